@@ -0,0 +1,222 @@
+// Package cascade reconstructs the influence trees that show how each Color spread through
+// a network, from the log of color changes recorded during a simulation run.
+package cascade
+
+import (
+	"sort"
+
+	"github.com/codeafix/orgnetsim/sim"
+)
+
+// Node is an adoption of a Color by an Agent in an influence tree. Iteration is the
+// iteration the Agent adopted the Color on, it is -1 for an Agent that held the Color
+// at the start of the run. CrossTeam is set if the Color was passed to the Agent across
+// one of the links between teams added by NetworkOptions.LinkTeams.
+type Node struct {
+	AgentID   string  `json:"agent"`
+	Iteration int     `json:"iteration"`
+	CrossTeam bool    `json:"crossTeam,omitempty"`
+	Children  []*Node `json:"children,omitempty"`
+}
+
+// Cascade is the tree of adoptions of a Color that can be traced back to a single seed.
+// A seed is an Agent that held the Color at the start of the run, that flipped to the
+// Color because of its contrariness, or that was set to the Color by an intervention or
+// converted by a broadcast. Size is the number of adoptions in the cascade not counting the
+// seed, Depth is the length of the longest chain of adoptions from the seed, and Breadth is
+// the largest number of adoptions at any one depth.
+type Cascade struct {
+	Color              sim.Color `json:"color"`
+	SeedID             string    `json:"seed"`
+	Evangelist         bool      `json:"evangelist,omitempty"`
+	Contrarian         bool      `json:"contrarian,omitempty"`
+	Intervened         bool      `json:"intervened,omitempty"`
+	Broadcasted        bool      `json:"broadcasted,omitempty"`
+	Iteration          int       `json:"iteration"`
+	Size               int       `json:"size"`
+	Depth              int       `json:"depth"`
+	Breadth            int       `json:"breadth"`
+	CrossTeamAdoptions int       `json:"crossTeamAdoptions"`
+	Root               *Node     `json:"root"`
+}
+
+// Tree holds every cascade of a single Color, sorted with the largest cascade first.
+// Adoptions is the number of times an Agent was influenced to adopt the Color, and
+// CrossTeamFraction is the fraction of those adoptions passed across links between teams.
+type Tree struct {
+	Color              sim.Color  `json:"color"`
+	Adoptions          int        `json:"adoptions"`
+	CrossTeamAdoptions int        `json:"crossTeamAdoptions"`
+	CrossTeamFraction  float64    `json:"crossTeamFraction"`
+	Cascades           []*Cascade `json:"cascades"`
+}
+
+// Analysis holds the influence tree for every Color other than Grey that was held or
+// adopted during the run, together with the totals across all the Colors
+type Analysis struct {
+	Adoptions          int     `json:"adoptions"`
+	CrossTeamAdoptions int     `json:"crossTeamAdoptions"`
+	CrossTeamFraction  float64 `json:"crossTeamFraction"`
+	Trees              []*Tree `json:"trees"`
+}
+
+// holding is the Color an Agent currently holds and the node it adopted it through
+type holding struct {
+	color sim.Color
+	node  *Node
+}
+
+// Analyse replays the color changes recorded during a run on the network rm, which must be
+// the network at the end of the run, and builds the influence tree for each Color. The
+// Color each Agent held at the start of the run is taken from the first change it made, or
+// from the network if it made no changes. The LinkedTeamList in the options identifies the
+// links between teams, and the EvangelistList and LoneEvangelist identify the evangelists.
+func Analyse(events []sim.ColorChange, rm sim.RelationshipMgr, o sim.NetworkOptions) *Analysis {
+	linked := toSet(o.LinkedTeamList)
+	evangelists := toSet(o.EvangelistList)
+	if len(o.LoneEvangelist) > 0 {
+		evangelists[o.LoneEvangelist[0]] = true
+	}
+
+	trees := map[sim.Color]*Tree{}
+	tree := func(c sim.Color) *Tree {
+		t, exists := trees[c]
+		if !exists {
+			t = &Tree{Color: c, Cascades: []*Cascade{}}
+			trees[c] = t
+		}
+		return t
+	}
+	seed := func(c sim.Color, id string, iteration int, reason sim.ChangeReason) *Node {
+		root := &Node{AgentID: id, Iteration: iteration}
+		t := tree(c)
+		t.Cascades = append(t.Cascades, &Cascade{
+			Color:       c,
+			SeedID:      id,
+			Evangelist:  evangelists[id],
+			Contrarian:  reason == sim.Contrarian,
+			Intervened:  reason == sim.Intervened,
+			Broadcasted: reason == sim.Broadcasted,
+			Iteration:   iteration,
+			Root:        root,
+		})
+		return root
+	}
+
+	//Work out the Color each Agent held at the start of the run
+	held := map[string]*holding{}
+	for _, e := range events {
+		if _, exists := held[e.AgentID]; !exists {
+			held[e.AgentID] = &holding{color: e.OldColor}
+		}
+	}
+	agents := rm.Agents()
+	ids := make([]string, 0, len(agents))
+	for _, a := range agents {
+		if _, exists := held[a.Identifier()]; !exists {
+			held[a.Identifier()] = &holding{color: a.GetColor()}
+		}
+		ids = append(ids, a.Identifier())
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		h := held[id]
+		if h.color != sim.Grey {
+			h.node = seed(h.color, id, -1, "")
+		}
+	}
+
+	for _, e := range events {
+		h := held[e.AgentID]
+		h.color = e.NewColor
+		h.node = nil
+		if e.NewColor == sim.Grey {
+			continue
+		}
+		//An Agent that was not given the Color by its sender starts a new cascade
+		if e.Reason == sim.Contrarian || e.Reason == sim.Intervened || e.Reason == sim.Broadcasted {
+			h.node = seed(e.NewColor, e.AgentID, e.Iteration, e.Reason)
+			continue
+		}
+		sender, exists := held[e.SenderID]
+		if !exists || sender.color != e.NewColor || sender.node == nil {
+			//The sender's Color is not known so treat the sender as a new seed
+			sender = &holding{color: e.NewColor, node: seed(e.NewColor, e.SenderID, e.Iteration, "")}
+			held[e.SenderID] = sender
+		}
+		h.node = &Node{
+			AgentID:   e.AgentID,
+			Iteration: e.Iteration,
+			CrossTeam: linked[e.AgentID] && linked[e.SenderID],
+		}
+		sender.node.Children = append(sender.node.Children, h.node)
+	}
+
+	a := &Analysis{Trees: []*Tree{}}
+	for _, t := range trees {
+		for _, c := range t.Cascades {
+			c.measure()
+			t.Adoptions += c.Size
+			t.CrossTeamAdoptions += c.CrossTeamAdoptions
+		}
+		sort.SliceStable(t.Cascades, func(i, j int) bool {
+			return t.Cascades[i].Size > t.Cascades[j].Size
+		})
+		t.CrossTeamFraction = fraction(t.CrossTeamAdoptions, t.Adoptions)
+		a.Adoptions += t.Adoptions
+		a.CrossTeamAdoptions += t.CrossTeamAdoptions
+		a.Trees = append(a.Trees, t)
+	}
+	sort.Slice(a.Trees, func(i, j int) bool {
+		return a.Trees[i].Color < a.Trees[j].Color
+	})
+	a.CrossTeamFraction = fraction(a.CrossTeamAdoptions, a.Adoptions)
+	return a
+}
+
+// Tree returns the influence tree for the passed Color, or nil if the Color was never held
+func (a *Analysis) Tree(c sim.Color) *Tree {
+	for _, t := range a.Trees {
+		if t.Color == c {
+			return t
+		}
+	}
+	return nil
+}
+
+// measure calculates the size, depth, breadth and number of cross team adoptions of the
+// cascade by walking the tree a level at a time
+func (c *Cascade) measure() {
+	c.Size, c.Depth, c.Breadth, c.CrossTeamAdoptions = 0, 0, 0, 0
+	level := c.Root.Children
+	for len(level) > 0 {
+		c.Depth++
+		c.Size += len(level)
+		if len(level) > c.Breadth {
+			c.Breadth = len(level)
+		}
+		next := []*Node{}
+		for _, n := range level {
+			if n.CrossTeam {
+				c.CrossTeamAdoptions++
+			}
+			next = append(next, n.Children...)
+		}
+		level = next
+	}
+}
+
+func toSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+func fraction(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
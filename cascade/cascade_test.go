@@ -0,0 +1,181 @@
+package cascade
+
+import (
+	"testing"
+
+	"github.com/codeafix/orgnetsim/sim"
+)
+
+func IsTrue(t *testing.T, condition bool, msg string) {
+	t.Helper()
+	if !condition {
+		t.Errorf(msg)
+	}
+}
+
+func AreEqual(t *testing.T, expected interface{}, actual interface{}, msg string) {
+	t.Helper()
+	if expected != actual {
+		t.Errorf("%s Expected = '%v' Actual = '%v'", msg, expected, actual)
+	}
+}
+
+func AssertSuccess(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func CreateNetwork(colors map[string]sim.Color) sim.RelationshipMgr {
+	rm := &sim.Network{}
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		rm.AddAgent(&sim.AgentState{ID: id, Color: colors[id]})
+	}
+	return rm
+}
+
+func CreateEvents() []sim.ColorChange {
+	return []sim.ColorChange{
+		{Iteration: 0, AgentID: "b", SenderID: "a", OldColor: sim.Grey, NewColor: sim.Blue, Reason: sim.Influenced},
+		{Iteration: 0, AgentID: "c", SenderID: "b", OldColor: sim.Grey, NewColor: sim.Blue, Reason: sim.Influenced},
+		{Iteration: 1, AgentID: "d", SenderID: "a", OldColor: sim.Grey, NewColor: sim.Blue, Reason: sim.Influenced},
+		{Iteration: 1, AgentID: "e", SenderID: "c", OldColor: sim.Grey, NewColor: sim.Red, Reason: sim.Contrarian},
+		{Iteration: 2, AgentID: "b", SenderID: "e", OldColor: sim.Blue, NewColor: sim.Red, Reason: sim.Influenced},
+	}
+}
+
+func TestAnalyseBuildsTreePerColor(t *testing.T) {
+	rm := CreateNetwork(map[string]sim.Color{"a": sim.Blue, "b": sim.Red, "c": sim.Blue, "d": sim.Blue, "e": sim.Red})
+	o := sim.NetworkOptions{
+		LinkedTeamList: []string{"a", "d"},
+		EvangelistList: []string{"a"},
+	}
+	a := Analyse(CreateEvents(), rm, o)
+	AreEqual(t, 2, len(a.Trees), "Wrong number of trees")
+	AreEqual(t, 4, a.Adoptions, "Wrong number of adoptions")
+	AreEqual(t, 1, a.CrossTeamAdoptions, "Wrong number of cross team adoptions")
+	AreEqual(t, 0.25, a.CrossTeamFraction, "Wrong cross team fraction")
+
+	blue := a.Tree(sim.Blue)
+	AreEqual(t, 1, len(blue.Cascades), "Wrong number of Blue cascades")
+	AreEqual(t, 3, blue.Adoptions, "Wrong number of Blue adoptions")
+	c := blue.Cascades[0]
+	AreEqual(t, "a", c.SeedID, "Wrong seed")
+	IsTrue(t, c.Evangelist, "Seed not identified as an evangelist")
+	AreEqual(t, -1, c.Iteration, "Initial seed should have iteration -1")
+	AreEqual(t, 3, c.Size, "Wrong cascade size")
+	AreEqual(t, 2, c.Depth, "Wrong cascade depth")
+	AreEqual(t, 2, c.Breadth, "Wrong cascade breadth")
+	AreEqual(t, 1, c.CrossTeamAdoptions, "Wrong number of cross team adoptions in cascade")
+	AreEqual(t, "b", c.Root.Children[0].AgentID, "Wrong first adoption")
+	AreEqual(t, "c", c.Root.Children[0].Children[0].AgentID, "Wrong second level adoption")
+	IsTrue(t, c.Root.Children[1].CrossTeam, "Adoption across linked teams not identified")
+
+	red := a.Tree(sim.Red)
+	AreEqual(t, 1, len(red.Cascades), "Wrong number of Red cascades")
+	c = red.Cascades[0]
+	AreEqual(t, "e", c.SeedID, "Wrong seed")
+	IsTrue(t, c.Contrarian, "Contrarian seed not identified")
+	AreEqual(t, 1, c.Iteration, "Wrong seed iteration")
+	AreEqual(t, 1, c.Size, "Wrong cascade size")
+	AreEqual(t, 1, c.Depth, "Wrong cascade depth")
+	AreEqual(t, 0.0, red.CrossTeamFraction, "Wrong cross team fraction")
+	IsTrue(t, a.Tree(sim.Green) == nil, "Tree returned for a Color that was never held")
+}
+
+func TestAnalyseWithNoEvents(t *testing.T) {
+	rm := CreateNetwork(map[string]sim.Color{"a": sim.Blue})
+	a := Analyse([]sim.ColorChange{}, rm, sim.NetworkOptions{})
+	AreEqual(t, 1, len(a.Trees), "Wrong number of trees")
+	AreEqual(t, 0, a.Adoptions, "Wrong number of adoptions")
+	AreEqual(t, 0.0, a.CrossTeamFraction, "Wrong cross team fraction")
+	AreEqual(t, 0, a.Tree(sim.Blue).Cascades[0].Size, "Wrong cascade size")
+}
+
+func TestAnalyseSortsCascadesBySize(t *testing.T) {
+	rm := CreateNetwork(map[string]sim.Color{"a": sim.Blue, "b": sim.Blue, "c": sim.Blue, "d": sim.Blue})
+	events := []sim.ColorChange{
+		{Iteration: 0, AgentID: "c", SenderID: "b", OldColor: sim.Grey, NewColor: sim.Blue, Reason: sim.Influenced},
+		{Iteration: 0, AgentID: "d", SenderID: "b", OldColor: sim.Grey, NewColor: sim.Blue, Reason: sim.Influenced},
+	}
+	a := Analyse(events, rm, sim.NetworkOptions{})
+	blue := a.Tree(sim.Blue)
+	AreEqual(t, 2, len(blue.Cascades), "Wrong number of cascades")
+	AreEqual(t, "b", blue.Cascades[0].SeedID, "Largest cascade not first")
+	AreEqual(t, 2, blue.Cascades[0].Size, "Wrong cascade size")
+}
+
+func TestAnalyseSeedsCascadeFromIntervention(t *testing.T) {
+	rm := CreateNetwork(map[string]sim.Color{})
+	events := []sim.ColorChange{
+		{Iteration: 1, AgentID: "a", OldColor: sim.Grey, NewColor: sim.Blue, Reason: sim.Intervened},
+		{Iteration: 1, AgentID: "b", OldColor: sim.Grey, NewColor: sim.Blue, Reason: sim.Intervened},
+		{Iteration: 2, AgentID: "c", SenderID: "a", OldColor: sim.Grey, NewColor: sim.Blue, Reason: sim.Influenced},
+		{Iteration: 3, AgentID: "d", SenderID: "c", OldColor: sim.Grey, NewColor: sim.Blue, Reason: sim.Influenced},
+	}
+	a := Analyse(events, rm, sim.NetworkOptions{})
+	blue := a.Tree(sim.Blue)
+	AreEqual(t, 2, len(blue.Cascades), "Each intervened agent should seed a cascade")
+	AreEqual(t, "a", blue.Cascades[0].SeedID, "Cascade not rooted at the intervened agent")
+	IsTrue(t, blue.Cascades[0].Intervened, "Cascade not marked as seeded by an intervention")
+	AreEqual(t, 1, blue.Cascades[0].Iteration, "Wrong seed iteration")
+	AreEqual(t, 2, blue.Cascades[0].Size, "Wrong cascade size")
+	AreEqual(t, "b", blue.Cascades[1].SeedID, "Cascade not rooted at the intervened agent")
+	AreEqual(t, 2, a.Adoptions, "Interventions should not be counted as adoptions")
+}
+
+func TestAnalyseSeedsCascadeFromBroadcast(t *testing.T) {
+	rm := CreateNetwork(map[string]sim.Color{})
+	events := []sim.ColorChange{
+		{Iteration: 0, AgentID: "a", OldColor: sim.Grey, NewColor: sim.Red, Reason: sim.Broadcasted},
+		{Iteration: 0, AgentID: "b", SenderID: "townhall", OldColor: sim.Grey, NewColor: sim.Red, Reason: sim.Broadcasted},
+		{Iteration: 1, AgentID: "c", SenderID: "a", OldColor: sim.Grey, NewColor: sim.Red, Reason: sim.Influenced},
+	}
+	a := Analyse(events, rm, sim.NetworkOptions{})
+	red := a.Tree(sim.Red)
+	AreEqual(t, 2, len(red.Cascades), "Each agent converted by a broadcast should seed a cascade")
+	AreEqual(t, "a", red.Cascades[0].SeedID, "Cascade of an unnamed broadcast not rooted at the converted agent")
+	IsTrue(t, red.Cascades[0].Broadcasted, "Cascade not marked as seeded by a broadcast")
+	AreEqual(t, 1, red.Cascades[0].Size, "Wrong cascade size")
+	AreEqual(t, "b", red.Cascades[1].SeedID, "Cascade of a named broadcast not rooted at the converted agent")
+	AreEqual(t, 1, a.Adoptions, "Broadcast conversions should not be counted as adoptions")
+}
+
+func TestAnalyseRunAccountsForEveryAdoption(t *testing.T) {
+	s := sim.HierarchySpec{
+		Levels:           3,
+		TeamSize:         4,
+		TeamLinkLevel:    2,
+		LinkTeamPeers:    true,
+		LinkTeams:        true,
+		InitColors:       []sim.Color{sim.Grey},
+		MaxColors:        4,
+		EvangelistAgents: true,
+		Seed:             5,
+	}
+	n, o, err := sim.GenerateHierarchy(s)
+	AssertSuccess(t, err)
+	l := sim.NewEventLog()
+	r := sim.NewSeededRunner(n, 50, 9)
+	r.SetEventLog(l)
+	r.Run()
+
+	adoptions := 0
+	for _, e := range l.Events {
+		if e.Reason == sim.Influenced && e.NewColor != sim.Grey {
+			adoptions++
+		}
+	}
+	a := Analyse(l.Events, n, *o)
+	IsTrue(t, adoptions > 0, "No adoptions in the run")
+	AreEqual(t, adoptions, a.Adoptions, "Not every adoption is in a cascade")
+	IsTrue(t, a.Tree(sim.Blue) != nil, "No Blue tree")
+	evangelistSeeds := 0
+	for _, c := range a.Tree(sim.Blue).Cascades {
+		if c.Evangelist && c.Iteration == -1 {
+			evangelistSeeds++
+		}
+	}
+	AreEqual(t, len(o.EvangelistList), evangelistSeeds, "Every evangelist should seed a Blue cascade")
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/codeafix/orgnetsim/sim"
+)
+
+//BatchOptions holds settings specified on the command line for the batch command
+type BatchOptions struct {
+	Batch sim.BatchSpec
+}
+
+//Batch provides the functionality for the orgnetsim batch command utility
+func Batch() {
+	success, bo := batchCommandLineOptions()
+	if !success {
+		return
+	}
+
+	infile := os.Args[2]
+	n, err := sim.NewNetwork(strings.Join(readFileIntoArray(infile), ""))
+	check(err)
+	source, err := sim.CopySource(n)
+	check(err)
+
+	//Stop starting new runs if the user presses Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results, err := sim.RunBatch(ctx, bo.Batch, source, func(completed int) {
+		fmt.Printf("Run %d of %d complete\n", completed, bo.Batch.Runs)
+	})
+	if err != nil {
+		fmt.Printf("Batch stopped: %s\n", err.Error())
+		return
+	}
+
+	prefix := infile
+	i := strings.LastIndex(infile, ".")
+	if i > 0 {
+		prefix = infile[:i]
+	}
+	prefix = fmt.Sprintf("%s-batch%d", prefix, bo.Batch.Seed)
+
+	data, err := json.Marshal(results)
+	check(err)
+	writeFile(prefix+".json", data)
+	var buffer bytes.Buffer
+	check(results.WriteCsv(&buffer))
+	writeFile(prefix+".csv", buffer.Bytes())
+	fmt.Printf("Seed %d, results written to %s.csv\n", bo.Batch.Seed, prefix)
+}
+
+func batchCommandLineOptions() (success bool, bo BatchOptions) {
+	bo = BatchOptions{
+		Batch: sim.BatchSpec{
+			Runs:       10,
+			Iterations: 100,
+			Parallel:   1,
+		},
+	}
+	success = true
+
+	if len(os.Args) < 3 || os.Args[2] == "-help" {
+		batchPrintUsage()
+		return false, bo
+	}
+
+	//List of unrecognised command switches
+	uc := []string{}
+
+	skipnext := false
+	for i, arg := range os.Args[3:len(os.Args)] {
+		if skipnext {
+			skipnext = false
+			continue
+		}
+		switch arg {
+		case "-n", "-i", "-seed", "-par":
+			if len(os.Args) < i+5 || strings.HasPrefix(os.Args[i+4], "-") {
+				fmt.Printf("<value> missing after %s option \n\n", arg)
+				success = false
+				break
+			}
+			skipnext = true
+			val, err := strconv.ParseInt(os.Args[i+4], 10, 64)
+			if err != nil {
+				fmt.Printf("Invalid integer '%s' for %s option\n", os.Args[i+4], arg)
+				success = false
+				break
+			}
+			switch arg {
+			case "-n":
+				bo.Batch.Runs = int(val)
+			case "-i":
+				bo.Batch.Iterations = int(val)
+			case "-seed":
+				bo.Batch.Seed = val
+			case "-par":
+				bo.Batch.Parallel = int(val)
+			}
+		case "-pc":
+			if len(os.Args) < i+5 || strings.HasPrefix(os.Args[i+4], "-") {
+				fmt.Printf("<percentiles> missing after -pc option \n\n")
+				success = false
+				break
+			}
+			skipnext = true
+			for _, p := range strings.Split(os.Args[i+4], ",") {
+				val, err := strconv.ParseFloat(p, 64)
+				if err != nil {
+					fmt.Printf("Invalid percentile '%s' for -pc option\n", p)
+					success = false
+					break
+				}
+				bo.Batch.Percentiles = append(bo.Batch.Percentiles, val)
+			}
+		default:
+			uc = append(uc, arg)
+		}
+	}
+	err := bo.Batch.Validate()
+	if err != nil {
+		fmt.Printf("%s\n", err.Error())
+		success = false
+	}
+	if bo.Batch.Seed == 0 {
+		//seed has not been set so default to time.Now
+		bo.Batch.Seed = sim.NewSeed()
+	}
+	if len(uc) > 0 {
+		fmt.Printf("Unrecognised options on command line: %s\n\n", strings.Join(uc, " "))
+		success = false
+	}
+	return success, bo
+}
+
+func batchPrintUsage() {
+	fmt.Println("Runs a simulation on a network saved in json format a number of times with different")
+	fmt.Println("seeds, and reports the mean, median and percentile bands of the number of agents with")
+	fmt.Println("each color and the number of conversations on every iteration.")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("      orgnetsim batch <network> [-n <runs>] [-i <iterations>] [-seed <seed>] [-par <parallel>] [-pc <percentiles>]")
+	fmt.Println("      orgnetsim batch -help")
+	fmt.Println()
+	fmt.Println("<network>")
+	fmt.Println("      is a json file containing the network to simulate. The statistics are saved to")
+	fmt.Println("      <network>-batch<seed>.json and <network>-batch<seed>.csv.")
+	fmt.Println("-n <runs>")
+	fmt.Println("      The number of runs. Default is 10.")
+	fmt.Println("-i <iterations>")
+	fmt.Println("      The number of iterations in each run. Default is 100.")
+	fmt.Println("-seed <seed>")
+	fmt.Println("      The seed the seeds of the runs are drawn from. A batch with the same seed on the")
+	fmt.Println("      same network produces identical results. The default is time.Now() in nanoseconds.")
+	fmt.Println("-par <parallel>")
+	fmt.Println("      The number of runs to perform at the same time. Default is 1.")
+	fmt.Println("-pc <percentiles>")
+	fmt.Println("      A comma separated list of the percentile bands to report. Default is 5,25,75,95.")
+	fmt.Println("-help")
+	fmt.Println("      Prints this message.")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codeafix/orgnetsim/sim"
+)
+
+func TestBatchReturnsFalseForHelp(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "batch", "-help"}
+	success, _ := batchCommandLineOptions()
+	IsFalse(t, success, "-help not returning false")
+}
+
+func TestBatchReturnsTrueWithDefaults(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "batch", "network.json"}
+	success, bo := batchCommandLineOptions()
+	IsTrue(t, success, "not returning true")
+	AreEqual(t, 10, bo.Batch.Runs, "Incorrect default runs")
+	AreEqual(t, 100, bo.Batch.Iterations, "Incorrect default iterations")
+	AreEqual(t, 1, bo.Batch.Parallel, "Incorrect default parallel")
+	NotEqual(t, int64(0), bo.Batch.Seed, "Seed not defaulted")
+}
+
+func TestBatchReturnsTrueGetsArgs(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "batch", "network.json", "-n", "20", "-i", "50", "-seed", "12345", "-par", "4", "-pc", "10,90"}
+	success, bo := batchCommandLineOptions()
+	IsTrue(t, success, "not returning true")
+	AreEqual(t, 20, bo.Batch.Runs, "Incorrect runs")
+	AreEqual(t, 50, bo.Batch.Iterations, "Incorrect iterations")
+	AreEqual(t, int64(12345), bo.Batch.Seed, "Incorrect seed")
+	AreEqual(t, 4, bo.Batch.Parallel, "Incorrect parallel")
+	AreEqual(t, 2, len(bo.Batch.Percentiles), "Incorrect number of percentiles")
+	AreEqual(t, 90.0, bo.Batch.Percentiles[1], "Incorrect percentile")
+}
+
+func TestBatchReturnsFalseWithInvalidPercentile(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "batch", "network.json", "-pc", "10,ninety"}
+	success, _ := batchCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestBatchReturnsFalseWithZeroRuns(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "batch", "network.json", "-n", "0"}
+	success, _ := batchCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestBatchReturnsFalseWithUnrecognisedOptions(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "batch", "network.json", "-x"}
+	success, _ := batchCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestBatchWritesResults(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	n, _, err := sim.GenerateHierarchy(sim.HierarchySpec{Levels: 2, TeamSize: 3, InitColors: []sim.Color{sim.Grey, sim.Blue}, MaxColors: 3, Seed: 2})
+	AssertSuccess(t, err)
+	dir := t.TempDir()
+	netfile := filepath.Join(dir, "network.json")
+	AssertSuccess(t, os.WriteFile(netfile, []byte(n.Serialise()), 0644))
+
+	os.Args = []string{"orgnetsim", "batch", netfile, "-n", "3", "-i", "5", "-seed", "7"}
+	main()
+	csv, err := os.ReadFile(filepath.Join(dir, "network-batch7.csv"))
+	AssertSuccess(t, err)
+	lines := strings.Split(strings.TrimSpace(string(csv)), "\n")
+	AreEqual(t, 21, len(lines), "Wrong number of rows, expected a header and 4 rows for each iteration")
+	_, err = os.Stat(filepath.Join(dir, "network-batch7.json"))
+	AssertSuccess(t, err)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/codeafix/orgnetsim/cascade"
+	"github.com/codeafix/orgnetsim/sim"
+)
+
+//CascadeOptions holds settings specified on the command line for the cascade command
+type CascadeOptions struct {
+	Network *sim.NetworkOptions
+	Top     int
+}
+
+//Cascade provides the functionality for the orgnetsim cascade command utility
+func Cascade() {
+	success, co := cascadeCommandLineOptions()
+	if !success {
+		return
+	}
+
+	n, err := sim.NewNetwork(strings.Join(readFileIntoArray(os.Args[2]), ""))
+	check(err)
+
+	eventsfile := os.Args[3]
+	events := []sim.ColorChange{}
+	err = json.Unmarshal([]byte(strings.Join(readFileIntoArray(eventsfile), "")), &events)
+	check(err)
+
+	a := cascade.Analyse(events, n, *co.Network)
+	printAnalysis(a, co.Top)
+
+	outfile := eventsfile
+	i := strings.LastIndex(eventsfile, ".")
+	if i > 0 {
+		outfile = eventsfile[:i]
+	}
+	outfile = outfile + "-cascades.json"
+	data, err := json.Marshal(a)
+	check(err)
+	writeFile(outfile, data)
+	fmt.Printf("Influence trees written to %s\n", outfile)
+}
+
+//printAnalysis prints a summary of the influence tree for each color, listing the largest cascades
+func printAnalysis(a *cascade.Analysis, top int) {
+	fmt.Printf("%d adoptions, %.1f%% through cross team links\n", a.Adoptions, a.CrossTeamFraction*100)
+	for _, t := range a.Trees {
+		fmt.Println()
+		fmt.Printf("%s: %d adoptions from %d seeds, %.1f%% through cross team links\n", t.Color.String(), t.Adoptions, len(t.Cascades), t.CrossTeamFraction*100)
+		fmt.Printf("    %-20s %9s %6s %6s %8s\n", "Seed", "Iteration", "Size", "Depth", "Breadth")
+		for i, c := range t.Cascades {
+			if i == top {
+				break
+			}
+			seed := c.SeedID
+			if c.Evangelist {
+				seed = seed + " (E)"
+			}
+			if c.Contrarian {
+				seed = seed + " (C)"
+			}
+			fmt.Printf("    %-20s %9d %6d %6d %8d\n", seed, c.Iteration, c.Size, c.Depth, c.Breadth)
+		}
+	}
+}
+
+func cascadeCommandLineOptions() (success bool, co CascadeOptions) {
+	of := newOptionsFile()
+	co = CascadeOptions{
+		Network: of.Network,
+		Top:     10,
+	}
+	success = true
+
+	if len(os.Args) < 4 || os.Args[2] == "-help" {
+		cascadePrintUsage()
+		return false, co
+	}
+
+	//List of unrecognised command switches
+	uc := []string{}
+	optFile, beFile, ltFile := "", "", ""
+
+	skipnext := false
+	for i, arg := range os.Args[4:len(os.Args)] {
+		if skipnext {
+			skipnext = false
+			continue
+		}
+		if arg == "-opt" || arg == "-be" || arg == "-lt" || arg == "-top" {
+			if len(os.Args) < i+6 || strings.HasPrefix(os.Args[i+5], "-") {
+				fmt.Printf("<value> missing after %s option \n\n", arg)
+				success = false
+				continue
+			}
+			skipnext = true
+		}
+		switch arg {
+		case "-opt":
+			optFile = os.Args[i+5]
+		case "-be":
+			beFile = os.Args[i+5]
+		case "-lt":
+			ltFile = os.Args[i+5]
+		case "-top":
+			top, err := strconv.Atoi(os.Args[i+5])
+			if err != nil {
+				fmt.Printf("Invalid integer '%s' for -top option\n", os.Args[i+5])
+				success = false
+				break
+			}
+			co.Top = top
+		default:
+			uc = append(uc, arg)
+		}
+	}
+	if len(uc) > 0 {
+		fmt.Printf("Unrecognised options on command line: %s\n\n", strings.Join(uc, " "))
+		success = false
+	}
+	if !success {
+		return success, co
+	}
+	//Lists specified on the command line override the lists in the options file
+	if optFile != "" {
+		err := json.Unmarshal([]byte(strings.Join(readFileIntoArray(optFile), "")), &of)
+		if err != nil {
+			fmt.Printf("Error in <optionsFile>: %s \n\n", err.Error())
+			return false, co
+		}
+		co.Network = of.Network
+	}
+	if beFile != "" {
+		co.Network.EvangelistList = readFileIntoArray(beFile)
+	}
+	if ltFile != "" {
+		co.Network.LinkedTeamList = readFileIntoArray(ltFile)
+	}
+	return success, co
+}
+
+func cascadePrintUsage() {
+	fmt.Println("Builds the influence tree for each color from the color changes recorded by the run")
+	fmt.Println("command with the -e option, and reports the size, depth and breadth of the cascade")
+	fmt.Println("started by each seed agent.")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("      orgnetsim cascade <network> <events> [-opt <optionsFile>] [-be <beListFile>] [-lt <ltListFile>] [-top <n>]")
+	fmt.Println("      orgnetsim cascade -help")
+	fmt.Println()
+	fmt.Println("<network>")
+	fmt.Println("      is the json file containing the network at the end of the run.")
+	fmt.Println("<events>")
+	fmt.Println("      is the json file containing the color changes recorded during the run. The full")
+	fmt.Println("      analysis is saved to <events>-cascades.json.")
+	fmt.Println("-opt <optionsFile>")
+	fmt.Println("      The options file used when the network was parsed, the evangelist and linked")
+	fmt.Println("      team lists are read from the network options in the file.")
+	fmt.Println("-be <beListFile>")
+	fmt.Println("      A file containing the list of evangelists, one identifier per line.")
+	fmt.Println("-lt <ltListFile>")
+	fmt.Println("      A file containing the list of individuals linked across teams, one identifier per")
+	fmt.Println("      line. Adoptions passed between individuals on this list are reported as reached")
+	fmt.Println("      through cross team links.")
+	fmt.Println("-top <n>")
+	fmt.Println("      The number of the largest cascades to print for each color. Default is 10.")
+	fmt.Println("-help")
+	fmt.Println("      Prints this message.")
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codeafix/orgnetsim/cascade"
+	"github.com/codeafix/orgnetsim/sim"
+)
+
+func TestCascadeReturnsFalseForHelp(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "cascade", "-help"}
+	success, co := cascadeCommandLineOptions()
+	IsFalse(t, success, "-help not returning false")
+	AreEqual(t, 10, co.Top, "Incorrect default top")
+}
+
+func TestCascadeReturnsFalseWithoutEvents(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "cascade", "network.json"}
+	success, _ := cascadeCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestCascadeReturnsTrueGetsArgs(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	dir := t.TempDir()
+	beFile := filepath.Join(dir, "be.txt")
+	ltFile := filepath.Join(dir, "lt.txt")
+	AssertSuccess(t, os.WriteFile(beFile, []byte("id_1\nid_2"), 0644))
+	AssertSuccess(t, os.WriteFile(ltFile, []byte("id_3"), 0644))
+	os.Args = []string{"orgnetsim", "cascade", "network.json", "events.json", "-be", beFile, "-lt", ltFile, "-top", "3"}
+	success, co := cascadeCommandLineOptions()
+	IsTrue(t, success, "not returning true")
+	AreEqual(t, 3, co.Top, "Incorrect top")
+	AreEqual(t, "id_1,id_2", strings.Join(co.Network.EvangelistList, ","), "Incorrect evangelist list")
+	AreEqual(t, "id_3", strings.Join(co.Network.LinkedTeamList, ","), "Incorrect linked team list")
+}
+
+func TestCascadeReturnsFalseWithInvalidTop(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "cascade", "network.json", "events.json", "-top", "many"}
+	success, _ := cascadeCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestCascadeReturnsFalseWithUnrecognisedOptions(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "cascade", "network.json", "events.json", "-x"}
+	success, _ := cascadeCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestRunWithEventsThenCascade(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	dir := t.TempDir()
+	n, o, err := sim.GenerateHierarchy(sim.HierarchySpec{Levels: 3, TeamSize: 4, TeamLinkLevel: 2, LinkTeams: true, InitColors: []sim.Color{sim.Grey}, MaxColors: 4, EvangelistAgents: true, Seed: 1})
+	AssertSuccess(t, err)
+	netFile := filepath.Join(dir, "net.json")
+	AssertSuccess(t, os.WriteFile(netFile, []byte(n.Serialise()), 0644))
+	ltFile := filepath.Join(dir, "lt.txt")
+	AssertSuccess(t, os.WriteFile(ltFile, []byte(strings.Join(o.LinkedTeamList, "\n")), 0644))
+
+	os.Args = []string{"orgnetsim", "run", netFile, "-i", "20", "-seed", "3", "-p", "0", "-e"}
+	Run()
+	prefix := filepath.Join(dir, "net-run3")
+	_, err = os.Stat(prefix + ".csv")
+	AssertSuccess(t, err)
+
+	os.Args = []string{"orgnetsim", "cascade", prefix + ".json", prefix + "-events.json", "-lt", ltFile}
+	Cascade()
+	data, err := os.ReadFile(prefix + "-events-cascades.json")
+	AssertSuccess(t, err)
+	a := cascade.Analysis{}
+	AssertSuccess(t, json.Unmarshal(data, &a))
+	IsTrue(t, len(a.Trees) > 0, fmt.Sprintf("No influence trees in %s", data))
+}
@@ -19,6 +19,16 @@ func main() {
 	switch os.Args[1] {
 	case "parse":
 		Parse()
+	case "run":
+		Run()
+	case "batch":
+		Batch()
+	case "cascade":
+		Cascade()
+	case "sweep":
+		Sweep()
+	case "sensitivity":
+		Sensitivity()
 	case "serve":
 		webfs, err := fs.Sub(efs, "web")
 		check(err)
@@ -45,6 +55,16 @@ func printUsage() {
 	fmt.Println("Commands:")
 	fmt.Println("    parse <orglist> [-help] [-awm] [-ltp] [-ic] [-be <beListFile>] [-lt <ltListFile>] [-mc <maxColors>]")
 	fmt.Println("        Reads in a csv or tsv and converts into an orgnetsim network saved in json format.")
+	fmt.Println("    run <network> [-help] [-i <iterations>] [-seed <seed>] [-sch <scheduler>] [-p <progress>] [-share <fraction>] [-stable <n>] [-entropy <threshold>] [-time <seconds>] [-e]")
+	fmt.Println("        Runs a simulation on a network saved in json format, reporting progress as it runs.")
+	fmt.Println("    batch <network> [-help] [-n <runs>] [-i <iterations>] [-seed <seed>] [-par <parallel>] [-pc <percentiles>]")
+	fmt.Println("        Runs a simulation many times with different seeds and reports percentile bands for each iteration.")
+	fmt.Println("    cascade <network> <events> [-help] [-opt <optionsFile>] [-be <beListFile>] [-lt <ltListFile>] [-top <n>]")
+	fmt.Println("        Analyses how each color spread through the network from the events recorded by run -e.")
+	fmt.Println("    sweep <experiment> [-help] [-n <network>] [-seed <seed>] [-par <parallel>]")
+	fmt.Println("        Runs every combination of a grid of parameters and writes a table with a row for each run.")
+	fmt.Println("    sensitivity <spec> [-help] [-n <network>] [-m <method>] [-seed <seed>] [-par <parallel>]")
+	fmt.Println("        Measures how strongly each factor drives the time to adoption and the final share of each color.")
 	fmt.Println("    serve <rootpath> [-help] [-p <port>]")
 	fmt.Println("        Starts an orgnetsim server that persists simulations in the folder specified by <rootpath>.")
 	fmt.Println("-help")
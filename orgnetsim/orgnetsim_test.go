@@ -25,3 +25,10 @@ func TestCommandLineServe(t *testing.T) {
 	os.Args = []string{"orgnetsim", "serve"}
 	main()
 }
+
+func TestCommandLineRun(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run"}
+	main()
+}
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
-	"math/rand"
 	"os"
 	"strconv"
 	"strings"
@@ -35,7 +34,7 @@ func Parse() {
 		of.Parse.Delimiter = "\t"
 	}
 
-	rand.Seed(int64(seed))
+	of.Network.Seed = seed
 
 	rm, err := of.Parse.ParseDelim(data)
 	check(err)
@@ -99,6 +98,9 @@ func parseCommandLineOptions() (success bool, of OptionsFile, opt string, seed i
 		case "-ltp":
 			of.Network.LinkTeamPeers = true
 			opt = opt + arg
+		case "-dir":
+			of.Parse.Directed = true
+			opt = opt + arg
 		case "-ic":
 			of.Network.InitColors = []sim.Color{sim.Grey, sim.Red}
 			opt = opt + arg
@@ -212,7 +214,7 @@ func parsePrintUsage() {
 	fmt.Println("      have to convert the file format.")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("      orgnetsim parse <orglist> [-opt <optionsFile>] [-awm] [-ltp] [-ic] [-be <beListFile>] [-lt <ltListFile>] [-seed <seed>] [-mc <maxColors>] [-seed <seed>]")
+	fmt.Println("      orgnetsim parse <orglist> [-opt <optionsFile>] [-awm] [-ltp] [-dir] [-ic] [-be <beListFile>] [-lt <ltListFile>] [-seed <seed>] [-mc <maxColors>] [-seed <seed>]")
 	fmt.Println("      orgnetsim parse -help")
 	fmt.Println()
 	fmt.Println("<orglist>")
@@ -227,6 +229,9 @@ func parsePrintUsage() {
 	fmt.Println("-ltp")
 	fmt.Println("      Add links that connect each member of a team with every other member of that team.")
 	fmt.Println("      Default is off.")
+	fmt.Println("-dir")
+	fmt.Println("      Make the link from each individual's parent to the individual directed, so that parents")
+	fmt.Println("      can talk to their children but children cannot talk to their parents. Default is off.")
 	fmt.Println("-ic")
 	fmt.Println("      Randomly assign a Color to the agents in the network either Grey or Red. Default all")
 	fmt.Println("      individuals are Grey.")
@@ -262,6 +267,7 @@ func parsePrintUsage() {
 	fmt.Println("          \"identifier\": 0,")
 	fmt.Println("          \"parent\": 1,")
 	fmt.Println("          \"delimiter\": \",\",")
+	fmt.Println("          \"directed\": false,")
 	fmt.Println("          \"regex\": {\"2\":\"\\\\S+\"}")
 	fmt.Println("        }")
 	fmt.Println("      }")
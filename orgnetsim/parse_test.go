@@ -54,11 +54,12 @@ func TestParseReturnsTrue(t *testing.T) {
 func TestParseReturnsTrueGetsArgs(t *testing.T) {
 	oldArgs := os.Args
 	defer func() { os.Args = oldArgs }()
-	os.Args = []string{"orgnetsim", "parse", "../sim/tst.csv", "-awm", "-ltp", "-ic", "-mc", "7", "-seed", "9"}
+	os.Args = []string{"orgnetsim", "parse", "../sim/tst.csv", "-awm", "-ltp", "-dir", "-ic", "-mc", "7", "-seed", "9"}
 	success, of, _, seed := parseCommandLineOptions()
 	IsTrue(t, success, "not returning true")
 	IsTrue(t, of.Network.AgentsWithMemory, "awm not true")
 	IsTrue(t, of.Network.LinkTeamPeers, "ltp not true")
+	IsTrue(t, of.Parse.Directed, "dir not true")
 	AreEqual(t, of.Network.MaxColors, 7, "wrong max colors")
 	var expectedSeed int64 = 9
 	AreEqual(t, seed, expectedSeed, "wrong seed")
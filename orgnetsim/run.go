@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/codeafix/orgnetsim/sim"
+)
+
+//RunOptions holds settings specified on the command line for the run command
+type RunOptions struct {
+	Iterations    int
+	Seed          int64
+	Scheduler     sim.SchedulerMode
+	Selection     sim.PartnerSelection
+	Dynamics      sim.LinkDynamics
+	Hierarchy     sim.HierarchyInfluence
+	Interventions sim.InterventionSchedule
+	Broadcasts    sim.Broadcasts
+	Progress      int
+	Events        bool
+	Stop          sim.StopConditions
+}
+
+//Run provides the functionality for the orgnetsim run command utility
+func Run() {
+	success, ro := runCommandLineOptions()
+	if !success {
+		return
+	}
+
+	infile := os.Args[2]
+	netjson := strings.Join(readFileIntoArray(infile), "")
+	n, err := sim.NewNetwork(netjson)
+	check(err)
+	err = ro.Interventions.Validate(n)
+	if err != nil {
+		fmt.Printf("Error in <interventionsFile>: %s\n", err.Error())
+		return
+	}
+	err = ro.Broadcasts.Validate(n)
+	if err != nil {
+		fmt.Printf("Error in <broadcastsFile>: %s\n", err.Error())
+		return
+	}
+
+	scheduler, err := sim.NewScheduler(ro.Scheduler)
+	check(err)
+	r := sim.NewSeededRunner(n, ro.Iterations, ro.Seed)
+	r.SetScheduler(scheduler)
+	r.SetPartnerSelection(ro.Selection)
+	r.SetLinkDynamics(ro.Dynamics)
+	r.SetHierarchyInfluence(ro.Hierarchy)
+	r.SetInterventions(ro.Interventions)
+	r.SetBroadcasts(ro.Broadcasts)
+	r.SetStopConditions(ro.Stop)
+	var log *sim.EventLog
+	if ro.Events {
+		log = sim.NewEventLog()
+		r.SetEventLog(log)
+	}
+
+	//Stop cleanly at the end of the current iteration if the user presses Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results, err := r.RunContext(ctx, sim.ObserverFunc(func(i int, colors []int, conversations int) {
+		if ro.Progress > 0 && (i+1)%ro.Progress == 0 {
+			fmt.Printf("Iteration %d of %d: colors %v conversations %d\n", i+1, ro.Iterations, colors, conversations)
+		}
+	}))
+	if err != nil {
+		fmt.Printf("Run stopped after %d iterations: %s\n", results.Iterations, err.Error())
+	}
+	for _, ai := range results.Interventions {
+		fmt.Printf("Applied %s intervention on iteration %d\n", ai.Intervention.Type, ai.Iteration+1)
+	}
+	if results.Stopped != nil {
+		fmt.Printf("Run stopped by the %s condition on iteration %d\n", results.Stopped.Condition, results.Stopped.Iteration+1)
+	}
+
+	prefix := infile
+	i := strings.LastIndex(infile, ".")
+	if i > 0 {
+		prefix = infile[:i]
+	}
+	prefix = fmt.Sprintf("%s-run%d", prefix, ro.Seed)
+
+	writeFile(prefix+".json", []byte(n.Serialise()))
+	writeFile(prefix+".csv", resultsCsv(results, n.MaxColors()))
+	if log != nil {
+		events, err := json.Marshal(log.Events)
+		check(err)
+		writeFile(prefix+"-events.json", events)
+	}
+	fmt.Printf("Seed %d, results written to %s.csv\n", ro.Seed, prefix)
+}
+
+//resultsCsv formats the results with a column for each Color and a column for the
+//number of conversations, and a row for each iteration. If the run had broadcasts there
+//are also columns for the number of peer and broadcast conversions.
+func resultsCsv(results sim.Results, maxColors int) []byte {
+	var buffer bytes.Buffer
+	for c := 0; c < maxColors; c++ {
+		buffer.WriteString(fmt.Sprintf("%s,", sim.Color(c).String()))
+	}
+	buffer.WriteString("Conversations")
+	if results.BroadcastConversions != nil {
+		buffer.WriteString(",Peer Conversions,Broadcast Conversions")
+	}
+	buffer.WriteString("\n")
+
+	for i := 0; i < results.Iterations; i++ {
+		for j := 0; j < maxColors; j++ {
+			buffer.WriteString(fmt.Sprintf("%d,", results.Colors[i][j]))
+		}
+		buffer.WriteString(fmt.Sprintf("%d", results.Conversations[i]))
+		if results.BroadcastConversions != nil {
+			buffer.WriteString(fmt.Sprintf(",%d,%d", results.Conversions[i], results.BroadcastConversions[i]))
+		}
+		buffer.WriteString("\n")
+	}
+	return buffer.Bytes()
+}
+
+func writeFile(filename string, data []byte) {
+	fo, err := os.Create(filename)
+	check(err)
+	defer fo.Close()
+	_, err = fo.Write(data)
+	check(err)
+}
+
+func runCommandLineOptions() (success bool, ro RunOptions) {
+	ro = RunOptions{
+		Iterations: 100,
+		Scheduler:  sim.Sequential,
+		Progress:   10,
+	}
+	success = true
+
+	if len(os.Args) < 3 || os.Args[2] == "-help" {
+		runPrintUsage()
+		return false, ro
+	}
+
+	//List of unrecognised command switches
+	uc := []string{}
+
+	skipnext := false
+	for i, arg := range os.Args[3:len(os.Args)] {
+		if skipnext {
+			skipnext = false
+			continue
+		}
+		switch arg {
+		case "-i", "-seed", "-p", "-stable":
+			if len(os.Args) < i+5 || strings.HasPrefix(os.Args[i+4], "-") {
+				fmt.Printf("<value> missing after %s option \n\n", arg)
+				success = false
+				break
+			}
+			skipnext = true
+			val, err := strconv.ParseInt(os.Args[i+4], 10, 64)
+			if err != nil {
+				fmt.Printf("Invalid integer '%s' for %s option\n", os.Args[i+4], arg)
+				success = false
+				break
+			}
+			switch arg {
+			case "-i":
+				ro.Iterations = int(val)
+			case "-seed":
+				ro.Seed = val
+			case "-p":
+				ro.Progress = int(val)
+			case "-stable":
+				ro.Stop.StableIterations = int(val)
+			}
+		case "-share", "-entropy", "-time", "-baseline", "-decay", "-prune", "-closure", "-up", "-down", "-peer":
+			if len(os.Args) < i+5 || strings.HasPrefix(os.Args[i+4], "-") {
+				fmt.Printf("<value> missing after %s option \n\n", arg)
+				success = false
+				break
+			}
+			skipnext = true
+			val, err := strconv.ParseFloat(os.Args[i+4], 64)
+			if err != nil {
+				fmt.Printf("Invalid number '%s' for %s option\n", os.Args[i+4], arg)
+				success = false
+				break
+			}
+			switch arg {
+			case "-share":
+				ro.Stop.ColorShare = val
+			case "-entropy":
+				ro.Stop.EntropyThreshold = val
+			case "-time":
+				ro.Stop.TimeLimit = val
+			case "-baseline":
+				ro.Selection.Baseline = val
+			case "-decay":
+				ro.Dynamics.Decay = val
+			case "-prune":
+				ro.Dynamics.Threshold = val
+			case "-closure":
+				ro.Dynamics.Closure = val
+			case "-up":
+				ro.Hierarchy.Upward = val
+			case "-down":
+				ro.Hierarchy.Downward = val
+			case "-peer":
+				ro.Hierarchy.Peer = val
+			}
+		case "-e":
+			ro.Events = true
+		case "-sch":
+			if len(os.Args) < i+5 || strings.HasPrefix(os.Args[i+4], "-") {
+				fmt.Printf("<scheduler> missing after -sch option \n\n")
+				success = false
+				break
+			}
+			skipnext = true
+			ro.Scheduler = sim.SchedulerMode(os.Args[i+4])
+			_, err := sim.NewScheduler(ro.Scheduler)
+			if err != nil {
+				fmt.Printf("%s\n", err.Error())
+				success = false
+				break
+			}
+		case "-sel":
+			if len(os.Args) < i+5 || strings.HasPrefix(os.Args[i+4], "-") {
+				fmt.Printf("<selection> missing after -sel option \n\n")
+				success = false
+				break
+			}
+			skipnext = true
+			ro.Selection.Mode = sim.SelectionMode(os.Args[i+4])
+		case "-bc":
+			if len(os.Args) < i+5 || strings.HasPrefix(os.Args[i+4], "-") {
+				fmt.Printf("<broadcastsFile> missing after -bc option \n\n")
+				success = false
+				break
+			}
+			skipnext = true
+			file := strings.Join(readFileIntoArray(os.Args[i+4]), "")
+			err := json.Unmarshal([]byte(file), &ro.Broadcasts)
+			if err != nil {
+				fmt.Printf("Error in <broadcastsFile>: %s \n\n", err.Error())
+				success = false
+				break
+			}
+		case "-int":
+			if len(os.Args) < i+5 || strings.HasPrefix(os.Args[i+4], "-") {
+				fmt.Printf("<interventionsFile> missing after -int option \n\n")
+				success = false
+				break
+			}
+			skipnext = true
+			file := strings.Join(readFileIntoArray(os.Args[i+4]), "")
+			err := json.Unmarshal([]byte(file), &ro.Interventions)
+			if err != nil {
+				fmt.Printf("Error in <interventionsFile>: %s \n\n", err.Error())
+				success = false
+				break
+			}
+		default:
+			uc = append(uc, arg)
+		}
+	}
+	if ro.Iterations <= 0 {
+		fmt.Printf("Iterations must be greater than zero\n")
+		success = false
+	}
+	err := ro.Stop.Validate()
+	if err != nil {
+		fmt.Printf("%s\n", err.Error())
+		success = false
+	}
+	err = ro.Selection.Validate()
+	if err == nil {
+		err = ro.Dynamics.Validate()
+	}
+	if err == nil {
+		err = ro.Hierarchy.Validate()
+	}
+	if err != nil {
+		fmt.Printf("%s\n", err.Error())
+		success = false
+	}
+	if ro.Seed == 0 {
+		//seed has not been set so default to time.Now
+		ro.Seed = sim.NewSeed()
+	}
+	if len(uc) > 0 {
+		fmt.Printf("Unrecognised options on command line: %s\n\n", strings.Join(uc, " "))
+		success = false
+	}
+	return success, ro
+}
+
+func runPrintUsage() {
+	fmt.Println("Runs a simulation on a network saved in json format and reports progress as it runs.")
+	fmt.Println("Pressing Ctrl+C stops the run at the end of the current iteration and saves the results")
+	fmt.Println("of the iterations completed so far.")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("      orgnetsim run <network> [-i <iterations>] [-seed <seed>] [-sch <scheduler>] [-sel <selection>] [-baseline <weight>] [-decay <fraction>] [-prune <strength>] [-closure <probability>] [-up <multiplier>] [-down <multiplier>] [-peer <multiplier>] [-int <interventionsFile>] [-bc <broadcastsFile>] [-p <progress>] [-share <fraction>] [-stable <n>] [-entropy <threshold>] [-time <seconds>] [-e]")
+	fmt.Println("      orgnetsim run -help")
+	fmt.Println()
+	fmt.Println("<network>")
+	fmt.Println("      is a json file containing the network to simulate, such as one created by the parse")
+	fmt.Println("      command. The network at the end of the run is saved to <network>-run<seed>.json and")
+	fmt.Println("      the results are saved to <network>-run<seed>.csv.")
+	fmt.Println("-i <iterations>")
+	fmt.Println("      The number of iterations to run. Default is 100.")
+	fmt.Println("-seed <seed>")
+	fmt.Println("      The seed for the run. Runs with the same seed on the same network using the")
+	fmt.Println("      sequential scheduler produce identical results. The default is time.Now() in")
+	fmt.Println("      nanoseconds.")
+	fmt.Println("-sch <scheduler>")
+	fmt.Println("      The scheduler to use, one of sequential, concurrent or pool. Default is sequential.")
+	fmt.Println("-sel <selection>")
+	fmt.Println("      How agents choose who to talk to, either uniform, where every linked agent is")
+	fmt.Println("      equally likely, or strength, where agents are chosen with a probability")
+	fmt.Println("      proportional to the strength of the link to them plus the baseline. Default is")
+	fmt.Println("      uniform.")
+	fmt.Println("-baseline <weight>")
+	fmt.Println("      The weight added to the strength of every link when the selection is strength.")
+	fmt.Println("      Default is 0.")
+	fmt.Println("-decay <fraction>")
+	fmt.Println("      The fraction of its strength every link loses at the end of each iteration.")
+	fmt.Println("-prune <strength>")
+	fmt.Println("      Deactivates a link when decay takes its strength from at least <strength> to")
+	fmt.Println("      below it.")
+	fmt.Println("-closure <probability>")
+	fmt.Println("      The probability that an agent forms a link to an agent linked to one of its own")
+	fmt.Println("      linked agents at the end of each iteration.")
+	fmt.Println("-up <multiplier>")
+	fmt.Println("      Multiplies the influence of an agent over its manager. Default is 1.")
+	fmt.Println("-down <multiplier>")
+	fmt.Println("      Multiplies the influence of a manager over its direct reports. Default is 1.")
+	fmt.Println("-peer <multiplier>")
+	fmt.Println("      Multiplies the influence in every conversation that is not along a reporting")
+	fmt.Println("      line. Default is 1.")
+	fmt.Println("-int <interventionsFile>")
+	fmt.Println("      A file containing a list of interventions to make to the network at given")
+	fmt.Println("      iterations of the run, for example:")
+	fmt.Println("      [")
+	fmt.Println("        {\"iteration\": 50, \"type\": \"color\", \"agents\": [\"id_1\",\"id_2\"], \"color\": 2},")
+	fmt.Println("        {\"iteration\": 100, \"type\": \"addLinks\", \"links\": [{\"source\":\"id_3\",\"target\":\"id_9\"}]}")
+	fmt.Println("      ]")
+	fmt.Println("      The type is one of color, traits, addLinks, removeLinks or evangelist.")
+	fmt.Println("-bc <broadcastsFile>")
+	fmt.Println("      A file containing a list of broadcasts, such as emails or town halls, that")
+	fmt.Println("      reach many agents at once, for example:")
+	fmt.Println("      [")
+	fmt.Println("        {\"name\": \"email\", \"color\": 1, \"frequency\": 10, \"influence\": 0.8},")
+	fmt.Println("        {\"name\": \"townhall\", \"color\": 1, \"frequency\": 50, \"influence\": 1.5,")
+	fmt.Println("         \"reach\": {\"department\": \"id_2\"}}")
+	fmt.Println("      ]")
+	fmt.Println("      Each broadcast is sent on the first iteration and then every <frequency>")
+	fmt.Println("      iterations, to everyone, to the agents in the department headed by an agent,")
+	fmt.Println("      or to a list of \"agents\". The conversions caused by broadcasts are written")
+	fmt.Println("      to the results separately from the peer conversions.")
+	fmt.Println("-p <progress>")
+	fmt.Println("      Report progress every <progress> iterations, 0 turns progress reporting off.")
+	fmt.Println("      Default is 10.")
+	fmt.Println("-share <fraction>")
+	fmt.Println("      Stops the run as soon as any color other than grey is held by at least")
+	fmt.Println("      <fraction> of the agents, a number between 0 and 1.")
+	fmt.Println("-stable <n>")
+	fmt.Println("      Stops the run when no agent has changed color for <n> iterations in a row.")
+	fmt.Println("-entropy <threshold>")
+	fmt.Println("      Stops the run when the entropy of the color distribution, in bits, falls below")
+	fmt.Println("      <threshold>.")
+	fmt.Println("-time <seconds>")
+	fmt.Println("      Stops the run when it has been running for <seconds>.")
+	fmt.Println("-e")
+	fmt.Println("      Records every change of color made by an agent and saves them to")
+	fmt.Println("      <network>-run<seed>-events.json, which can be analysed with the cascade command.")
+	fmt.Println("-help")
+	fmt.Println("      Prints this message.")
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codeafix/orgnetsim/sim"
+)
+
+func TestRunReturnsFalseForHelp(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "-help"}
+	success, ro := runCommandLineOptions()
+	IsFalse(t, success, "-help not returning false")
+	AreEqual(t, 100, ro.Iterations, "Incorrect default iterations")
+}
+
+func TestRunReturnsTrueWithDefaults(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json"}
+	success, ro := runCommandLineOptions()
+	IsTrue(t, success, "not returning true")
+	AreEqual(t, 100, ro.Iterations, "Incorrect default iterations")
+	AreEqual(t, sim.Sequential, ro.Scheduler, "Incorrect default scheduler")
+	AreEqual(t, 10, ro.Progress, "Incorrect default progress")
+	NotEqual(t, int64(0), ro.Seed, "Seed not defaulted")
+}
+
+func TestRunReturnsTrueGetsArgs(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-i", "50", "-seed", "12345", "-sch", "pool", "-p", "0"}
+	success, ro := runCommandLineOptions()
+	IsTrue(t, success, "not returning true")
+	AreEqual(t, 50, ro.Iterations, "Incorrect iterations")
+	AreEqual(t, int64(12345), ro.Seed, "Incorrect seed")
+	AreEqual(t, sim.WorkerPool, ro.Scheduler, "Incorrect scheduler")
+	AreEqual(t, 0, ro.Progress, "Incorrect progress")
+}
+
+func TestRunReturnsFalseWithMissingIterations(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-i"}
+	success, _ := runCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestRunReturnsFalseWithInvalidIterations(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-i", "lots"}
+	success, _ := runCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestRunReturnsFalseWithUnknownScheduler(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-sch", "random"}
+	success, _ := runCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestRunReturnsTrueGetsSelection(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-sel", "strength", "-baseline", "0.5"}
+	success, ro := runCommandLineOptions()
+	IsTrue(t, success, "not returning true")
+	AreEqual(t, sim.StrengthSelection, ro.Selection.Mode, "Wrong selection mode")
+	AreEqual(t, 0.5, ro.Selection.Baseline, "Wrong selection baseline")
+}
+
+func TestRunReturnsFalseWithUnknownSelection(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-sel", "loudest"}
+	success, _ := runCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestRunReturnsTrueGetsLinkDynamics(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-decay", "0.1", "-prune", "2", "-closure", "0.05"}
+	success, ro := runCommandLineOptions()
+	IsTrue(t, success, "not returning true")
+	AreEqual(t, sim.LinkDynamics{Decay: 0.1, Threshold: 2, Closure: 0.05}, ro.Dynamics, "Wrong link dynamics")
+}
+
+func TestRunReturnsFalseWithInvalidLinkDynamics(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-decay", "1.5"}
+	success, _ := runCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestRunReturnsTrueGetsHierarchyInfluence(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-up", "0.5", "-down", "2", "-peer", "1.5"}
+	success, ro := runCommandLineOptions()
+	IsTrue(t, success, "not returning true")
+	AreEqual(t, sim.HierarchyInfluence{Upward: 0.5, Downward: 2, Peer: 1.5}, ro.Hierarchy, "Wrong hierarchy influence")
+}
+
+func TestRunReturnsFalseWithMissingHierarchyMultiplier(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-down"}
+	success, _ := runCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestRunReturnsTrueGetsInterventions(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "interventions.json")
+	AssertSuccess(t, os.WriteFile(file, []byte(`[{"iteration":50,"type":"color","agents":["id_1"],"color":2}]`), 0644))
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-int", file}
+	success, ro := runCommandLineOptions()
+	IsTrue(t, success, "not returning true")
+	AreEqual(t, 1, len(ro.Interventions), "Interventions not read")
+	iv := ro.Interventions[0]
+	AreEqual(t, 50, iv.Iteration, "Wrong intervention iteration")
+	AreEqual(t, sim.ColorIntervention, iv.Type, "Wrong intervention type")
+	AreEqual(t, "id_1", iv.Agents[0], "Wrong intervention agent")
+	AreEqual(t, sim.Red, iv.Color, "Wrong intervention color")
+}
+
+func TestRunReturnsFalseWithMissingInterventionsFile(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-int"}
+	success, _ := runCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestRunReturnsTrueGetsBroadcasts(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "broadcasts.json")
+	AssertSuccess(t, os.WriteFile(file, []byte(`[{"name":"email","color":1,"frequency":10,"influence":0.8,"reach":{"department":"id_2"}}]`), 0644))
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-bc", file}
+	success, ro := runCommandLineOptions()
+	IsTrue(t, success, "not returning true")
+	AreEqual(t, 1, len(ro.Broadcasts), "Broadcasts not read")
+	b := ro.Broadcasts[0]
+	AreEqual(t, "email", b.Name, "Wrong broadcast name")
+	AreEqual(t, 10, b.Frequency, "Wrong broadcast frequency")
+	AreEqual(t, 0.8, b.Influence, "Wrong broadcast influence")
+	AreEqual(t, "id_2", b.Reach.Department, "Wrong broadcast reach")
+}
+
+func TestRunReturnsFalseWithMissingBroadcastsFile(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-bc"}
+	success, _ := runCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestResultsCsvReportsBroadcastConversions(t *testing.T) {
+	results := sim.Results{
+		Iterations:           1,
+		Colors:               [][]int{{1, 2}},
+		Conversations:        []int{3},
+		Conversions:          []int{1},
+		BroadcastConversions: []int{2},
+	}
+	AreEqual(t, "Grey,Blue,Conversations,Peer Conversions,Broadcast Conversions\n1,2,3,1,2\n", string(resultsCsv(results, 2)), "Wrong csv")
+}
+
+func TestRunReturnsFalseWithUnrecognisedOptions(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-x"}
+	success, _ := runCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestRunReturnsTrueGetsStopConditions(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-share", "0.8", "-stable", "20", "-entropy", "0.5", "-time", "30"}
+	success, ro := runCommandLineOptions()
+	IsTrue(t, success, "not returning true")
+	AreEqual(t, 0.8, ro.Stop.ColorShare, "Incorrect color share")
+	AreEqual(t, 20, ro.Stop.StableIterations, "Incorrect stable iterations")
+	AreEqual(t, 0.5, ro.Stop.EntropyThreshold, "Incorrect entropy threshold")
+	AreEqual(t, 30.0, ro.Stop.TimeLimit, "Incorrect time limit")
+}
+
+func TestRunReturnsFalseWithInvalidColorShare(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-share", "80"}
+	success, _ := runCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestRunReturnsFalseWithInvalidTimeLimit(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "run", "network.json", "-time", "soon"}
+	success, _ := runCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/codeafix/orgnetsim/sim"
+	"github.com/codeafix/orgnetsim/sweep"
+)
+
+//SensitivityOptions holds settings specified on the command line for the sensitivity command
+type SensitivityOptions struct {
+	NetworkFile string
+	Method      string
+	Seed        int64
+	Parallel    int
+}
+
+//Sensitivity provides the functionality for the orgnetsim sensitivity command utility
+func Sensitivity() {
+	success, so := sensitivityCommandLineOptions()
+	if !success {
+		return
+	}
+
+	infile := os.Args[2]
+	s := &sweep.SensitivitySpec{}
+	check(json.Unmarshal([]byte(strings.Join(readFileIntoArray(infile), "")), s))
+	if so.Method != "" {
+		s.Method = so.Method
+	}
+	if so.Seed != 0 {
+		s.Seed = so.Seed
+	}
+	if so.Parallel != 0 {
+		s.Parallel = so.Parallel
+	}
+	var base sim.RelationshipMgr
+	if so.NetworkFile != "" {
+		n, err := sim.NewNetwork(strings.Join(readFileIntoArray(so.NetworkFile), ""))
+		check(err)
+		base = n
+	}
+
+	//Stop starting new runs if the user presses Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results, err := sweep.RunSensitivity(ctx, s, base, func(completed, total int) {
+		fmt.Printf("Run %d of %d complete\n", completed, total)
+	})
+	if err != nil {
+		fmt.Printf("Sensitivity analysis stopped: %s\n", err.Error())
+		return
+	}
+
+	prefix := infile
+	i := strings.LastIndex(infile, ".")
+	if i > 0 {
+		prefix = infile[:i]
+	}
+	prefix = prefix + "-sensitivity"
+
+	data, err := json.Marshal(results)
+	check(err)
+	writeFile(prefix+".json", data)
+	var buffer bytes.Buffer
+	check(results.WriteCsv(&buffer))
+	writeFile(prefix+".csv", buffer.Bytes())
+	buffer.Reset()
+	check(results.Table.WriteCsv(&buffer))
+	writeFile(prefix+"-runs.csv", buffer.Bytes())
+
+	fmt.Printf("\n%-40s %12s %12s %12s %12s\n", "Factor", "Time swing", "Time corr", "Share swing", "Share corr")
+	for _, e := range results.Effects {
+		fmt.Printf("%-40s %12.3f %12.3f %12.3f %12.3f\n", e.Factor, e.HalfAdoption.Swing, e.HalfAdoption.Correlation, e.Adoption.Swing, e.Adoption.Correlation)
+	}
+	fmt.Printf("\nSeed %d, results written to %s.csv\n", s.Seed, prefix)
+}
+
+func sensitivityCommandLineOptions() (success bool, so SensitivityOptions) {
+	so = SensitivityOptions{}
+	success = true
+
+	if len(os.Args) < 3 || os.Args[2] == "-help" {
+		sensitivityPrintUsage()
+		return false, so
+	}
+
+	//List of unrecognised command switches
+	uc := []string{}
+
+	skipnext := false
+	for i, arg := range os.Args[3:len(os.Args)] {
+		if skipnext {
+			skipnext = false
+			continue
+		}
+		switch arg {
+		case "-n", "-m":
+			if len(os.Args) < i+5 || strings.HasPrefix(os.Args[i+4], "-") {
+				fmt.Printf("<value> missing after %s option \n\n", arg)
+				success = false
+				break
+			}
+			skipnext = true
+			if arg == "-n" {
+				so.NetworkFile = os.Args[i+4]
+				break
+			}
+			so.Method = os.Args[i+4]
+			if so.Method != sweep.OneAtATime && so.Method != sweep.LatinHypercube {
+				fmt.Printf("Invalid method '%s' for -m option\n", so.Method)
+				success = false
+			}
+		case "-seed", "-par":
+			if len(os.Args) < i+5 || strings.HasPrefix(os.Args[i+4], "-") {
+				fmt.Printf("<value> missing after %s option \n\n", arg)
+				success = false
+				break
+			}
+			skipnext = true
+			val, err := strconv.ParseInt(os.Args[i+4], 10, 64)
+			if err != nil || val < 0 {
+				fmt.Printf("Invalid integer '%s' for %s option\n", os.Args[i+4], arg)
+				success = false
+				break
+			}
+			if arg == "-seed" {
+				so.Seed = val
+			} else {
+				so.Parallel = int(val)
+			}
+		default:
+			uc = append(uc, arg)
+		}
+	}
+	if len(uc) > 0 {
+		fmt.Printf("Unrecognised options on command line: %s\n\n", strings.Join(uc, " "))
+		success = false
+	}
+	return success, so
+}
+
+func sensitivityPrintUsage() {
+	fmt.Println("Runs a sensitivity analysis, measuring how strongly each factor, such as the parameters")
+	fmt.Println("of the distributions that agent traits are drawn from, drives the number of iterations")
+	fmt.Println("before half the agents adopt a color and the final share of agents with a color.")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("      orgnetsim sensitivity <spec> [-n <network>] [-m <method>] [-seed <seed>] [-par <parallel>]")
+	fmt.Println("      orgnetsim sensitivity -help")
+	fmt.Println()
+	fmt.Println("<spec>")
+	fmt.Println("      is a json file describing the analysis, with the same fields as a sweep experiment")
+	fmt.Println("      but with factors in place of parameters, for example:")
+	fmt.Println("      {")
+	fmt.Println("        \"hierarchy\": {\"levels\": 4, \"teamSize\": 5, \"initColors\": [0, 1], \"maxColors\": 2},")
+	fmt.Println("        \"run\": {\"iterations\": 200},")
+	fmt.Println("        \"method\": \"lhs\",")
+	fmt.Println("        \"factors\": [")
+	fmt.Println("          {\"name\": \"hierarchy.traits.influence.mean\", \"low\": 0.5, \"high\": 1.5},")
+	fmt.Println("          {\"name\": \"hierarchy.traits.contrariness.sd\", \"low\": 0, \"high\": 0.3}")
+	fmt.Println("        ],")
+	fmt.Println("        \"samples\": 50,")
+	fmt.Println("        \"repeats\": 3")
+	fmt.Println("      }")
+	fmt.Println("      The effect of each factor is saved to <spec>-sensitivity.csv, the runs are saved to")
+	fmt.Println("      <spec>-sensitivity-runs.csv and both are saved to <spec>-sensitivity.json.")
+	fmt.Println("-n <network>")
+	fmt.Println("      A json file containing the network to start every run from when the spec has no")
+	fmt.Println("      hierarchy.")
+	fmt.Println("-m <method>")
+	fmt.Println("      Overrides the method in the spec. Either oat, which varies each factor in turn across")
+	fmt.Println("      a number of levels, or lhs, which varies all the factors together using a Latin")
+	fmt.Println("      hypercube of samples.")
+	fmt.Println("-seed <seed>")
+	fmt.Println("      Overrides the seed in the spec. The default is time.Now() in nanoseconds.")
+	fmt.Println("-par <parallel>")
+	fmt.Println("      Overrides the number of runs to perform at the same time. Default is 1.")
+	fmt.Println("-help")
+	fmt.Println("      Prints this message.")
+}
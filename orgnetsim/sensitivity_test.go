@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSensitivityReturnsFalseForHelp(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "sensitivity", "-help"}
+	success, _ := sensitivityCommandLineOptions()
+	IsFalse(t, success, "-help not returning false")
+}
+
+func TestSensitivityReturnsTrueGetsArgs(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "sensitivity", "spec.json", "-n", "network.json", "-m", "lhs", "-seed", "12345", "-par", "2"}
+	success, so := sensitivityCommandLineOptions()
+	IsTrue(t, success, "not returning true")
+	AreEqual(t, "network.json", so.NetworkFile, "Incorrect network file")
+	AreEqual(t, "lhs", so.Method, "Incorrect method")
+	AreEqual(t, int64(12345), so.Seed, "Incorrect seed")
+	AreEqual(t, 2, so.Parallel, "Incorrect parallel")
+}
+
+func TestSensitivityReturnsFalseWithInvalidMethod(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "sensitivity", "spec.json", "-m", "grid"}
+	success, _ := sensitivityCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestSensitivityWritesResults(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	dir := t.TempDir()
+	specfile := filepath.Join(dir, "spec.json")
+	spec := `{"hierarchy":{"levels":3,"teamSize":3,"initColors":[0,0,1],"maxColors":3},"run":{"iterations":10},"method":"oat","levels":3,"factors":[{"name":"hierarchy.traits.influence.mean","low":0.5,"high":1.5},{"name":"hierarchy.traits.contrariness.mean","low":0,"high":1}]}`
+	AssertSuccess(t, os.WriteFile(specfile, []byte(spec), 0644))
+
+	os.Args = []string{"orgnetsim", "sensitivity", specfile, "-seed", "7"}
+	main()
+	csv, err := os.ReadFile(filepath.Join(dir, "spec-sensitivity.csv"))
+	AssertSuccess(t, err)
+	lines := strings.Split(strings.TrimSpace(string(csv)), "\n")
+	AreEqual(t, 3, len(lines), "Wrong number of rows, expected a header and a row for each factor")
+	IsTrue(t, strings.HasPrefix(lines[2], "hierarchy.traits.contrariness.mean,0,1,0.7,"), "Wrong last row")
+	runs, err := os.ReadFile(filepath.Join(dir, "spec-sensitivity-runs.csv"))
+	AssertSuccess(t, err)
+	AreEqual(t, 7, len(strings.Split(strings.TrimSpace(string(runs)), "\n")), "Wrong number of runs")
+	_, err = os.Stat(filepath.Join(dir, "spec-sensitivity.json"))
+	AssertSuccess(t, err)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/codeafix/orgnetsim/sim"
+	"github.com/codeafix/orgnetsim/sweep"
+)
+
+//SweepOptions holds settings specified on the command line for the sweep command
+type SweepOptions struct {
+	NetworkFile string
+	Seed        int64
+	Parallel    int
+}
+
+//Sweep provides the functionality for the orgnetsim sweep command utility
+func Sweep() {
+	success, so := sweepCommandLineOptions()
+	if !success {
+		return
+	}
+
+	infile := os.Args[2]
+	e := &sweep.Experiment{}
+	check(json.Unmarshal([]byte(strings.Join(readFileIntoArray(infile), "")), e))
+	if so.Seed != 0 {
+		e.Seed = so.Seed
+	}
+	if so.Parallel != 0 {
+		e.Parallel = so.Parallel
+	}
+	var base sim.RelationshipMgr
+	if so.NetworkFile != "" {
+		n, err := sim.NewNetwork(strings.Join(readFileIntoArray(so.NetworkFile), ""))
+		check(err)
+		base = n
+	}
+
+	//Stop starting new runs if the user presses Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	table, err := sweep.Run(ctx, e, base, func(completed, total int) {
+		fmt.Printf("Run %d of %d complete\n", completed, total)
+	})
+	if err != nil {
+		fmt.Printf("Sweep stopped: %s\n", err.Error())
+		return
+	}
+
+	prefix := infile
+	i := strings.LastIndex(infile, ".")
+	if i > 0 {
+		prefix = infile[:i]
+	}
+	prefix = prefix + "-results"
+
+	data, err := json.Marshal(table)
+	check(err)
+	writeFile(prefix+".json", data)
+	var buffer bytes.Buffer
+	check(table.WriteCsv(&buffer))
+	writeFile(prefix+".csv", buffer.Bytes())
+	fmt.Printf("Seed %d, results written to %s.csv\n", e.Seed, prefix)
+}
+
+func sweepCommandLineOptions() (success bool, so SweepOptions) {
+	so = SweepOptions{}
+	success = true
+
+	if len(os.Args) < 3 || os.Args[2] == "-help" {
+		sweepPrintUsage()
+		return false, so
+	}
+
+	//List of unrecognised command switches
+	uc := []string{}
+
+	skipnext := false
+	for i, arg := range os.Args[3:len(os.Args)] {
+		if skipnext {
+			skipnext = false
+			continue
+		}
+		switch arg {
+		case "-n":
+			if len(os.Args) < i+5 || strings.HasPrefix(os.Args[i+4], "-") {
+				fmt.Printf("<network> missing after -n option \n\n")
+				success = false
+				break
+			}
+			skipnext = true
+			so.NetworkFile = os.Args[i+4]
+		case "-seed", "-par":
+			if len(os.Args) < i+5 || strings.HasPrefix(os.Args[i+4], "-") {
+				fmt.Printf("<value> missing after %s option \n\n", arg)
+				success = false
+				break
+			}
+			skipnext = true
+			val, err := strconv.ParseInt(os.Args[i+4], 10, 64)
+			if err != nil || val < 0 {
+				fmt.Printf("Invalid integer '%s' for %s option\n", os.Args[i+4], arg)
+				success = false
+				break
+			}
+			if arg == "-seed" {
+				so.Seed = val
+			} else {
+				so.Parallel = int(val)
+			}
+		default:
+			uc = append(uc, arg)
+		}
+	}
+	if len(uc) > 0 {
+		fmt.Printf("Unrecognised options on command line: %s\n\n", strings.Join(uc, " "))
+		success = false
+	}
+	return success, so
+}
+
+func sweepPrintUsage() {
+	fmt.Println("Runs a parameter sweep experiment, simulating every combination of the values of the")
+	fmt.Println("parameters in the experiment, and writes a table with a row for each run containing the")
+	fmt.Println("parameters, the final share of agents with each color and the number of iterations")
+	fmt.Println("before half of the agents had a color other than grey.")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("      orgnetsim sweep <experiment> [-n <network>] [-seed <seed>] [-par <parallel>]")
+	fmt.Println("      orgnetsim sweep -help")
+	fmt.Println()
+	fmt.Println("<experiment>")
+	fmt.Println("      is a json file describing the experiment, for example:")
+	fmt.Println("      {")
+	fmt.Println("        \"hierarchy\": {\"levels\": 4, \"teamSize\": 5, \"initColors\": [0, 1], \"maxColors\": 2},")
+	fmt.Println("        \"run\": {\"iterations\": 200, \"stop\": {\"colorShare\": 0.9}},")
+	fmt.Println("        \"parameters\": [")
+	fmt.Println("          {\"name\": \"hierarchy.teamSize\", \"values\": [3, 5, 7]},")
+	fmt.Println("          {\"name\": \"run.iterations\", \"from\": 100, \"to\": 300, \"step\": 100}")
+	fmt.Println("        ],")
+	fmt.Println("        \"repeats\": 5")
+	fmt.Println("      }")
+	fmt.Println("      Parameter names are the json names of the fields in the hierarchy, options or run")
+	fmt.Println("      sections. The results are saved to <experiment>-results.json and")
+	fmt.Println("      <experiment>-results.csv.")
+	fmt.Println("-n <network>")
+	fmt.Println("      A json file containing the network to start every run from when the experiment")
+	fmt.Println("      has no hierarchy.")
+	fmt.Println("-seed <seed>")
+	fmt.Println("      Overrides the seed in the experiment. A sweep with the same seed produces identical")
+	fmt.Println("      results. The default is time.Now() in nanoseconds.")
+	fmt.Println("-par <parallel>")
+	fmt.Println("      Overrides the number of runs to perform at the same time. Default is 1.")
+	fmt.Println("-help")
+	fmt.Println("      Prints this message.")
+}
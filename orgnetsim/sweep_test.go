@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codeafix/orgnetsim/sim"
+)
+
+func TestSweepReturnsFalseForHelp(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "sweep", "-help"}
+	success, _ := sweepCommandLineOptions()
+	IsFalse(t, success, "-help not returning false")
+}
+
+func TestSweepReturnsTrueGetsArgs(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "sweep", "experiment.json", "-n", "network.json", "-seed", "12345", "-par", "4"}
+	success, so := sweepCommandLineOptions()
+	IsTrue(t, success, "not returning true")
+	AreEqual(t, "network.json", so.NetworkFile, "Incorrect network file")
+	AreEqual(t, int64(12345), so.Seed, "Incorrect seed")
+	AreEqual(t, 4, so.Parallel, "Incorrect parallel")
+}
+
+func TestSweepReturnsFalseWithUnrecognisedOptions(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"orgnetsim", "sweep", "experiment.json", "-x"}
+	success, _ := sweepCommandLineOptions()
+	IsFalse(t, success, "not returning false")
+}
+
+func TestSweepWritesResults(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	n, _, err := sim.GenerateHierarchy(sim.HierarchySpec{Levels: 2, TeamSize: 3, InitColors: []sim.Color{sim.Grey, sim.Blue}, MaxColors: 3, Seed: 2})
+	AssertSuccess(t, err)
+	dir := t.TempDir()
+	netfile := filepath.Join(dir, "network.json")
+	AssertSuccess(t, os.WriteFile(netfile, []byte(n.Serialise()), 0644))
+	expfile := filepath.Join(dir, "experiment.json")
+	experiment := `{"run":{"iterations":5},"parameters":[{"name":"run.iterations","values":[3,5]}],"repeats":2}`
+	AssertSuccess(t, os.WriteFile(expfile, []byte(experiment), 0644))
+
+	os.Args = []string{"orgnetsim", "sweep", expfile, "-n", netfile, "-seed", "7"}
+	main()
+	csv, err := os.ReadFile(filepath.Join(dir, "experiment-results.csv"))
+	AssertSuccess(t, err)
+	lines := strings.Split(strings.TrimSpace(string(csv)), "\n")
+	AreEqual(t, 5, len(lines), "Wrong number of rows, expected a header and a row for each run")
+	IsTrue(t, strings.HasPrefix(lines[4], "3,1,"), "Wrong last row")
+	_, err = os.Stat(filepath.Join(dir, "experiment-results.json"))
+	AssertSuccess(t, err)
+}
@@ -2,7 +2,8 @@ package sim
 
 import "reflect"
 
-// An AgentState carries the state of a node in the network
+// An AgentState carries the state of a node in the network. Manager is the ID of the Agent it
+// reports to, if any, and Level is the number of managers above it in the reporting lines.
 type AgentState struct {
 	ID             string      `json:"id"`
 	Name           string      `json:"name"`
@@ -15,6 +16,8 @@ type AgentState struct {
 	Type           string      `json:"type"`
 	X              float64     `json:"fx,omitempty"`
 	Y              float64     `json:"fy,omitempty"`
+	Manager        string      `json:"manager,omitempty"`
+	Level          int         `json:"level,omitempty"`
 }
 
 // Agent is an interface that allows interaction with an Agent
@@ -74,26 +77,32 @@ func (a *AgentState) ReadMail(n RelationshipMgr) Color {
 	if received {
 		ra, isAgent := n.GetAgentByID(msg).(*AgentState)
 		if isAgent {
-			c, update := a.UpdateColor(n, ra)
+			c, reason, update := a.UpdateColor(n, ra)
 			if update {
+				oldColor := a.Color
 				a.SetColor(c)
+				n.EventLog().Record(a.ID, ra.ID, oldColor, a.Color, reason)
 			}
 		}
 	}
 	return a.Color
 }
 
-// UpdateColor looks at the properties of the passed agent and decides what the agent should update its color to
-func (a *AgentState) UpdateColor(n RelationshipMgr, ra *AgentState) (Color, bool) {
+// UpdateColor looks at the properties of the passed agent and decides what the agent should update its color to,
+// together with the reason for the update. The influence of the passed agent is scaled by the trust on the link
+// between them and by the multiplier for where they sit in the reporting lines relative to each other.
+func (a *AgentState) UpdateColor(n RelationshipMgr, ra *AgentState) (Color, ChangeReason, bool) {
 	n.IncrementLinkStrength(a.Identifier(), ra.Identifier())
-	if ra.Influence > a.Susceptability {
-		if a.Contrariness > ra.Influence {
-			altColor := RandomlySelectAlternateColor(a.Color, n.MaxColors())
-			return altColor, true
+	influence := ra.Influence * n.GetLink(a.Identifier(), ra.Identifier()).TrustWeight()
+	influence = influence * n.HierarchyInfluence().Multiplier(ra, a)
+	if influence > a.Susceptability {
+		if a.Contrariness > influence {
+			altColor := RandomlySelectAlternateColor(n.Rand(), a.Color, n.MaxColors())
+			return altColor, Contrarian, true
 		}
-		return ra.Color, true
+		return ra.Color, Influenced, true
 	}
-	return Grey, false
+	return Grey, "", false
 }
 
 // SetColor changes the color of the current Agent and counts the number of times the Agent changes color
@@ -1,6 +1,7 @@
 package sim
 
 import (
+	"math/rand"
 	"testing"
 )
 
@@ -8,12 +9,19 @@ type testNetwork struct {
 	relatedAgents []Agent
 	agentByID     map[string]Agent
 	LinkStrength  int
+	eventLog      *EventLog
+	link          *Link
+	hierarchy     HierarchyInfluence
 }
 
 func (tn *testNetwork) GetRelatedAgents(a Agent) []Agent {
 	return tn.relatedAgents
 }
 
+func (tn *testNetwork) GetInfluencingAgents(a Agent) []Agent {
+	return tn.relatedAgents
+}
+
 func (tn *testNetwork) GetAgentByID(id string) Agent {
 	return tn.agentByID[id]
 }
@@ -23,6 +31,10 @@ func (tn *testNetwork) IncrementLinkStrength(id1 string, id2 string) error {
 	return nil
 }
 
+func (tn *testNetwork) GetLink(id1 string, id2 string) *Link {
+	return tn.link
+}
+
 func (tn *testNetwork) MaxColors() int {
 	return 4
 }
@@ -34,6 +46,40 @@ func (tn *testNetwork) PopulateMaps() error {
 	return nil
 }
 
+func (tn *testNetwork) PopulateLinkMaps() error {
+	return nil
+}
+
+func (tn *testNetwork) Rand() *rand.Rand {
+	return NewRand(1)
+}
+
+func (tn *testNetwork) SetRand(r *rand.Rand) {
+}
+
+func (tn *testNetwork) EventLog() *EventLog {
+	return tn.eventLog
+}
+
+func (tn *testNetwork) SetEventLog(l *EventLog) {
+	tn.eventLog = l
+}
+
+func (tn *testNetwork) PartnerSelection() PartnerSelection {
+	return PartnerSelection{}
+}
+
+func (tn *testNetwork) SetPartnerSelection(ps PartnerSelection) {
+}
+
+func (tn *testNetwork) HierarchyInfluence() HierarchyInfluence {
+	return tn.hierarchy
+}
+
+func (tn *testNetwork) SetHierarchyInfluence(h HierarchyInfluence) {
+	tn.hierarchy = h
+}
+
 func (tn *testNetwork) Agents() []Agent {
 	return nil
 }
@@ -45,6 +91,9 @@ func (tn *testNetwork) Links() []*Link {
 func (tn *testNetwork) AddLink(a1 Agent, a2 Agent) {
 }
 
+func (tn *testNetwork) AddDirectedLink(a1 Agent, a2 Agent) {
+}
+
 func (tn *testNetwork) AddAgent(a Agent) {
 	tn.relatedAgents = append(tn.relatedAgents, a)
 	tn.agentByID[a.Identifier()] = a
@@ -139,6 +188,25 @@ func TestReadMailReceivesMsgLowerSusceptabilityChangesColor(t *testing.T) {
 	AreEqual(t, Blue, aut.Color, "Agent Color should change to Blue if Agent has lower susceptability")
 }
 
+func TestReadMailScalesInfluenceByLinkTrust(t *testing.T) {
+	tn := newTestNetwork()
+	trust := 0.5
+	tn.link = &Link{Trust: &trust}
+	aut := newAgent()
+	aut.ID = "id_aut"
+	aut.Susceptability = 0.4
+	aut.Color = Red
+	aut.PostMsg("id_1")
+	aut.ReadMail(tn)
+	AreEqual(t, Red, aut.Color, "Agent Color should not change if the sender is not trusted enough")
+
+	trust = 2
+	aut.Susceptability = 0.8
+	aut.PostMsg("id_1")
+	aut.ReadMail(tn)
+	AreEqual(t, Blue, aut.Color, "Agent Color should change if trust lifts the sender's influence")
+}
+
 func TestReadMailReceivesMsgLowerSusceptabilityHigherContrarinessRandomlyChangesColor(t *testing.T) {
 	tn := newTestNetwork()
 	aut := newAgent()
@@ -1,26 +1,42 @@
 package sim
 
-import "reflect"
+import (
+	"math/rand"
+	"reflect"
+)
 
-//An AgentWithMemory is a node in the network that has memory
+//An AgentWithMemory is a node in the network that has memory. PreviousColors is its long term
+//memory of the Colors it has held, and ShortMemory holds the Colors it has heard about once,
+//each recorded against the value of its Clock at the time. The Clock counts the iterations the
+//Agent has read its Mail on. A Color is forgotten from long term memory once LongMemorySpan
+//iterations have passed since it was left, and from short term memory once ShortMemorySpan
+//iterations have passed without hearing about it again. A span of zero means the Color is never
+//forgotten, and Grey is always remembered.
 type AgentWithMemory struct {
 	AgentState
-	PreviousColors map[Color]struct{} `json:"-"`
-	ShortMemory    map[Color]struct{} `json:"-"`
-	MaxColors      int                `json:"-"`
+	PreviousColors  map[Color]int `json:"previousColors"`
+	ShortMemory     map[Color]int `json:"shortMemory,omitempty"`
+	MaxColors       int           `json:"-"`
+	LongMemorySpan  int           `json:"longMemorySpan,omitempty"`
+	ShortMemorySpan int           `json:"shortMemorySpan,omitempty"`
+	Clock           int           `json:"clock,omitempty"`
 }
 
 /*ReadMail checks for any messages it received in its own Mail queue. If it receives
 one then it decides whether to update its color.
 */
 func (a *AgentWithMemory) ReadMail(n RelationshipMgr) Color {
+	a.Clock++
+	a.forget()
 	msg, received := a.ReceiveMsg()
 	if received {
 		ra, isAgentWithMem := n.GetAgentByID(msg).(*AgentWithMemory)
 		if isAgentWithMem {
-			c, update := a.UpdateColor(n, &ra.AgentState)
+			c, reason, update := a.UpdateColor(n, &ra.AgentState)
 			if update {
+				oldColor := a.Color
 				a.SetColor(c)
+				n.EventLog().Record(a.ID, ra.ID, oldColor, a.Color, reason)
 			}
 		}
 	}
@@ -42,21 +58,56 @@ func (a *AgentWithMemory) SetColor(color Color) {
 		_, rem := a.ShortMemory[color]
 		if rem {
 			a.ChangeCount++
-			a.PreviousColors[a.Color] = struct{}{}
+			a.PreviousColors[a.Color] = a.Clock
 			a.Color = color
-			a.ShortMemory = make(map[Color]struct{}, a.MaxColors)
+			a.ShortMemory = make(map[Color]int, a.MaxColors)
 		} else {
-			a.ShortMemory[color] = struct{}{}
+			a.ShortMemory[color] = a.Clock
 		}
 	}
 }
 
-//Initialise ensures the agent is correctly initialised
+//forget removes the Colors from long and short term memory that have been remembered for
+//longer than their memory span
+func (a *AgentWithMemory) forget() {
+	if a.LongMemorySpan > 0 {
+		for c, t := range a.PreviousColors {
+			if c != Grey && a.Clock-t > a.LongMemorySpan {
+				delete(a.PreviousColors, c)
+			}
+		}
+	}
+	if a.ShortMemorySpan > 0 {
+		for c, t := range a.ShortMemory {
+			if a.Clock-t > a.ShortMemorySpan {
+				delete(a.ShortMemory, c)
+			}
+		}
+	}
+}
+
+//SampleTraits sets the memory spans of the Agent from the Traits when it is generated
+func (a *AgentWithMemory) SampleTraits(r *rand.Rand, t Traits) {
+	a.LongMemorySpan = t.LongMemorySpan
+	a.ShortMemorySpan = t.ShortMemorySpan
+}
+
+//Initialise ensures the agent is correctly initialised. The memory the Agent already has, for
+//example from being unmarshalled from a saved step, is restored rather than reset so that a run
+//continues with the memory the Agent had when it was saved. Agents saved before their memory
+//was recorded start with an empty memory that only remembers Grey. MaxColors is always taken
+//from the network, so it follows any change to the Colors permitted on it.
 func (a *AgentWithMemory) Initialise(n RelationshipMgr) {
 	a.AgentState.Initialise(n)
 	a.MaxColors = n.MaxColors()
-	a.PreviousColors = make(map[Color]struct{}, a.MaxColors)
-	a.PreviousColors[Grey] = struct{}{}
-	a.ShortMemory = make(map[Color]struct{}, a.MaxColors)
+	if a.PreviousColors == nil {
+		a.PreviousColors = make(map[Color]int, a.MaxColors)
+	}
+	if _, rem := a.PreviousColors[Grey]; !rem {
+		a.PreviousColors[Grey] = a.Clock
+	}
+	if a.ShortMemory == nil {
+		a.ShortMemory = make(map[Color]int, a.MaxColors)
+	}
 	a.Type = reflect.TypeOf(a).Elem().Name()
 }
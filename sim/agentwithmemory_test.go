@@ -0,0 +1,119 @@
+package sim
+
+import (
+	"testing"
+)
+
+func newAgentWithMemory(tn *testNetwork) *AgentWithMemory {
+	a := &AgentWithMemory{}
+	a.ID = "id_aut"
+	a.Susceptability = 0.4
+	a.Color = Red
+	a.Initialise(tn)
+	tn.agentByID["id_1"] = &AgentWithMemory{AgentState: *tn.agentByID["id_1"].State()}
+	return a
+}
+
+func TestAgentWithMemoryNeverReturnsToRememberedColor(t *testing.T) {
+	tn := newTestNetwork()
+	aut := newAgentWithMemory(tn)
+	aut.PreviousColors[Blue] = 0
+	for i := 0; i < 5; i++ {
+		aut.PostMsg("id_1")
+		AreEqual(t, Red, aut.ReadMail(tn), "Agent should not return to a remembered color")
+	}
+}
+
+func TestAgentWithMemoryForgetsPreviousColors(t *testing.T) {
+	tn := newTestNetwork()
+	aut := newAgentWithMemory(tn)
+	aut.LongMemorySpan = 2
+	aut.PreviousColors[Blue] = 0
+	aut.PostMsg("id_1")
+	AreEqual(t, Red, aut.ReadMail(tn), "Agent should not return to a remembered color")
+	aut.ReadMail(tn)
+	aut.ReadMail(tn)
+	_, rem := aut.PreviousColors[Blue]
+	IsFalse(t, rem, "Color not forgotten after the long memory span")
+	_, rem = aut.PreviousColors[Grey]
+	IsTrue(t, rem, "Grey should never be forgotten")
+	aut.PostMsg("id_1")
+	AreEqual(t, Red, aut.ReadMail(tn), "Agent should only change after hearing about a color twice")
+	aut.PostMsg("id_1")
+	AreEqual(t, Blue, aut.ReadMail(tn), "Agent should return to a forgotten color")
+	AreEqual(t, 5, aut.PreviousColors[Red], "Color left not recorded against the clock")
+}
+
+func TestAgentWithMemoryShortMemoryFades(t *testing.T) {
+	tn := newTestNetwork()
+	aut := newAgentWithMemory(tn)
+	aut.ShortMemorySpan = 1
+	aut.PostMsg("id_1")
+	aut.ReadMail(tn)
+	AreEqual(t, 1, aut.ShortMemory[Blue], "Color heard not recorded in short term memory")
+	aut.ReadMail(tn)
+	aut.ReadMail(tn)
+	AreEqual(t, 0, len(aut.ShortMemory), "Short term memory should fade without reinforcement")
+	aut.PostMsg("id_1")
+	AreEqual(t, Red, aut.ReadMail(tn), "Agent should not change after its short term memory faded")
+}
+
+func TestGenerateAgentsWithMemorySpans(t *testing.T) {
+	traits := Traits{LongMemorySpan: 20, ShortMemorySpan: 5}
+	n, _, err := GenerateHierarchy(HierarchySpec{Levels: 2, TeamSize: 2, InitColors: []Color{Grey}, MaxColors: 2, AgentsWithMemory: true, Traits: &traits, Seed: 1})
+	AssertSuccess(t, err)
+	a := n.Agents()[0].(*AgentWithMemory)
+	AreEqual(t, 20, a.LongMemorySpan, "Long memory span not set from the traits")
+	AreEqual(t, 5, a.ShortMemorySpan, "Short memory span not set from the traits")
+	traits.ShortMemorySpan = -1
+	AreEqual(t, "memory spans cannot be negative", traits.Validate().Error(), "Wrong error")
+}
+
+func TestAgentWithMemoryRestoredFromSavedNetwork(t *testing.T) {
+	traits := Traits{LongMemorySpan: 30, ShortMemorySpan: 3}
+	n, _, err := GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 4, InitColors: []Color{Grey, Blue, Red, Green}, MaxColors: 4, AgentsWithMemory: true, Traits: &traits, Seed: 1})
+	AssertSuccess(t, err)
+	NewSeededRunner(n, 20, 1).Run()
+	saved, err := NewNetwork(n.Serialise())
+	AssertSuccess(t, err)
+	for i, a := range saved.Agents() {
+		ma, orig := a.(*AgentWithMemory), n.Agents()[i].(*AgentWithMemory)
+		AreEqual(t, len(orig.PreviousColors), len(ma.PreviousColors), "Long term memory not restored")
+		for c, ti := range orig.PreviousColors {
+			AreEqual(t, ti, ma.PreviousColors[c], "Long term memory not restored")
+		}
+		AreEqual(t, len(orig.ShortMemory), len(ma.ShortMemory), "Short term memory not restored")
+		AreEqual(t, orig.Clock, ma.Clock, "Clock not restored")
+	}
+	//Continuing the run from the saved network is the same as continuing the original once its
+	//maps are populated again, as they are when a run continues from the last step
+	AssertSuccess(t, n.PopulateMaps())
+	r1 := NewSeededRunner(n, 20, 2).Run()
+	r2 := NewSeededRunner(saved, 20, 2).Run()
+	for i := range r1.Colors {
+		for c := range r1.Colors[i] {
+			AreEqual(t, r1.Colors[i][c], r2.Colors[i][c], "Run from the saved network diverged")
+		}
+	}
+}
+
+func TestAgentWithMemoryFromOlderStepStartsWithGrey(t *testing.T) {
+	n, err := NewNetwork(`{"links":null,"nodes":[{"id":"id_1","color":1,"type":"AgentWithMemory"},{"id":"id_2","color":1,"type":"AgentWithMemory","previousColors":{}}],"maxColors":3}`)
+	AssertSuccess(t, err)
+	for _, a := range n.Agents() {
+		ma := a.(*AgentWithMemory)
+		AreEqual(t, 1, len(ma.PreviousColors), "Older agents should only remember Grey")
+		_, rem := ma.PreviousColors[Grey]
+		IsTrue(t, rem, "Grey not remembered")
+		AreEqual(t, 3, ma.MaxColors, "MaxColors not taken from the network")
+	}
+}
+
+func TestAgentWithMemoryFollowsNetworkMaxColors(t *testing.T) {
+	n, err := NewNetwork(`{"links":null,"nodes":[{"id":"id_1","color":1,"type":"AgentWithMemory","previousColors":{"0":0,"1":2},"clock":2}],"maxColors":3}`)
+	AssertSuccess(t, err)
+	n.SetMaxColors(6)
+	AssertSuccess(t, n.PopulateMaps())
+	AreEqual(t, 6, n.Agents()[0].(*AgentWithMemory).MaxColors, "MaxColors not updated from the network")
+	AreEqual(t, 2, n.Agents()[0].(*AgentWithMemory).PreviousColors[Blue], "Memory not kept when MaxColors changed")
+}
@@ -0,0 +1,252 @@
+package sim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+)
+
+// DefaultPercentiles are the percentile bands reported by a batch if none are specified
+var DefaultPercentiles = []float64{5, 25, 75, 95}
+
+// BatchSpec specifies a batch of runs of the same starting network, each with a different
+// seed. The seed for each run is drawn from a random source seeded with Seed, so a batch
+// with the same Seed always uses the same seeds. Parallel is the number of runs performed
+// at the same time, the default is one. Percentiles lists the percentile bands, between 0
+// and 100, reported for every iteration. StopConditions are not supported, every run in a
+// batch runs for the same number of iterations so that the runs can be aggregated.
+type BatchSpec struct {
+	Runs        int       `json:"runs"`
+	Iterations  int       `json:"iterations"`
+	Seed        int64     `json:"seed,omitempty"`
+	Parallel    int       `json:"parallel,omitempty"`
+	Percentiles []float64 `json:"percentiles,omitempty"`
+}
+
+// Summary holds the statistics of a single value across all the runs in a batch. The
+// Percentiles are in the same order as the Percentiles of the BatchResults.
+type Summary struct {
+	Mean        float64   `json:"mean"`
+	Median      float64   `json:"median"`
+	Min         float64   `json:"min"`
+	Max         float64   `json:"max"`
+	Percentiles []float64 `json:"percentiles"`
+}
+
+// BatchResults holds the statistics of a batch of runs for every iteration. Colors has an
+// entry for each iteration holding a Summary of the number of Agents with each Color, and
+// Conversations has a Summary of the number of conversations for each iteration. Seeds
+// holds the seed used for each run.
+type BatchResults struct {
+	Runs          int         `json:"runs"`
+	Iterations    int         `json:"iterations"`
+	Seeds         []int64     `json:"seeds"`
+	Percentiles   []float64   `json:"percentiles"`
+	Colors        [][]Summary `json:"colors"`
+	Conversations []Summary   `json:"conversations"`
+}
+
+// NetworkSource returns the network to simulate for a single run of a batch. The seed is
+// the seed of the run, which a source can use to generate a different network for each run.
+type NetworkSource func(seed int64) (RelationshipMgr, error)
+
+// CopySource returns a NetworkSource that gives every run its own copy of the passed network
+func CopySource(rm RelationshipMgr) (NetworkSource, error) {
+	b, err := json.Marshal(rm)
+	if err != nil {
+		return nil, err
+	}
+	return func(seed int64) (RelationshipMgr, error) {
+		return NewNetwork(string(b))
+	}, nil
+}
+
+// HierarchySource returns a NetworkSource that generates a new network from the passed
+// HierarchySpec for every run, using the seed of the run to generate the network
+func HierarchySource(s HierarchySpec) NetworkSource {
+	return func(seed int64) (RelationshipMgr, error) {
+		spec := s
+		spec.Seed = seed
+		n, _, err := GenerateHierarchy(spec)
+		return n, err
+	}
+}
+
+// Validate returns an error if the BatchSpec cannot be run
+func (bs BatchSpec) Validate() error {
+	if bs.Runs <= 0 || bs.Iterations <= 0 {
+		return errors.New("runs and iterations must be greater than zero")
+	}
+	if bs.Parallel < 0 {
+		return errors.New("parallel cannot be negative")
+	}
+	for _, p := range bs.Percentiles {
+		if p < 0 || p > 100 {
+			return fmt.Errorf("percentile %g must be between 0 and 100", p)
+		}
+	}
+	return nil
+}
+
+// RunBatch performs the runs specified in the BatchSpec on networks from the passed source
+// and aggregates their Results. Each run uses the Sequential scheduler so the batch is
+// reproducible whether or not the runs are performed in parallel. If done is not nil it is
+// called with the number of runs completed each time a run finishes. If the context is
+// cancelled no more runs are started and the error from the context is returned.
+func RunBatch(ctx context.Context, bs BatchSpec, source NetworkSource, done func(completed int)) (*BatchResults, error) {
+	err := bs.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if bs.Seed == 0 {
+		bs.Seed = NewSeed()
+	}
+	if len(bs.Percentiles) == 0 {
+		bs.Percentiles = DefaultPercentiles
+	}
+	parallel := bs.Parallel
+	if parallel <= 0 {
+		parallel = 1
+	}
+
+	r := NewRand(bs.Seed)
+	seeds := make([]int64, bs.Runs)
+	for i := range seeds {
+		seeds[i] = r.Int63()
+	}
+
+	runs := make([]Results, bs.Runs)
+	errs := make([]error, bs.Runs)
+	var lock sync.Mutex
+	completed := 0
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i := range seeds {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			n, err := source(seeds[i])
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			runs[i], errs[i] = NewSeededRunner(n, bs.Iterations, seeds[i]).RunContext(ctx, nil)
+			lock.Lock()
+			defer lock.Unlock()
+			completed++
+			if done != nil {
+				done(completed)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return aggregate(runs, seeds, bs.Iterations, bs.Percentiles), nil
+}
+
+// aggregate summarises the Color counts and conversations of every run for each iteration
+func aggregate(runs []Results, seeds []int64, iterations int, percentiles []float64) *BatchResults {
+	br := &BatchResults{
+		Runs:          len(runs),
+		Iterations:    iterations,
+		Seeds:         seeds,
+		Percentiles:   percentiles,
+		Colors:        make([][]Summary, iterations),
+		Conversations: make([]Summary, iterations),
+	}
+	values := make([]float64, len(runs))
+	for i := 0; i < iterations; i++ {
+		colors := len(runs[0].Colors[i])
+		br.Colors[i] = make([]Summary, colors)
+		for c := 0; c < colors; c++ {
+			for r, run := range runs {
+				values[r] = float64(run.Colors[i][c])
+			}
+			br.Colors[i][c] = summarise(values, percentiles)
+		}
+		for r, run := range runs {
+			values[r] = float64(run.Conversations[i])
+		}
+		br.Conversations[i] = summarise(values, percentiles)
+	}
+	return br
+}
+
+// summarise returns the Summary of the passed values, the values are sorted in place
+func summarise(values []float64, percentiles []float64) Summary {
+	sort.Float64s(values)
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	s := Summary{
+		Mean:        total / float64(len(values)),
+		Median:      Percentile(values, 50),
+		Min:         values[0],
+		Max:         values[len(values)-1],
+		Percentiles: make([]float64, len(percentiles)),
+	}
+	for i, p := range percentiles {
+		s.Percentiles[i] = Percentile(values, p)
+	}
+	return s
+}
+
+// Percentile returns the p-th percentile (between 0 and 100) of the passed values, which
+// must be sorted, interpolating linearly between the closest ranks
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// WriteCsv writes the BatchResults in csv format with a row for every iteration of each
+// Color and of the conversations, and a column for each statistic
+func (br *BatchResults) WriteCsv(w io.Writer) error {
+	var buffer bytes.Buffer
+	buffer.WriteString("Iteration,Series,Mean,Median,Min,Max")
+	for _, p := range br.Percentiles {
+		buffer.WriteString(fmt.Sprintf(",P%g", p))
+	}
+	buffer.WriteString("\n")
+	row := func(i int, series string, s Summary) {
+		buffer.WriteString(fmt.Sprintf("%d,%s,%g,%g,%g,%g", i, series, s.Mean, s.Median, s.Min, s.Max))
+		for _, v := range s.Percentiles {
+			buffer.WriteString(fmt.Sprintf(",%g", v))
+		}
+		buffer.WriteString("\n")
+	}
+	for i := 0; i < br.Iterations; i++ {
+		for c, s := range br.Colors[i] {
+			row(i, Color(c).String(), s)
+		}
+		row(i, "Conversations", br.Conversations[i])
+	}
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
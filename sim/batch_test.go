@@ -0,0 +1,129 @@
+package sim
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func CreateBatchSpec() HierarchySpec {
+	return HierarchySpec{Levels: 3, TeamSize: 4, TeamLinkLevel: 2, LinkTeamPeers: true, InitColors: []Color{Grey}, MaxColors: 4, EvangelistAgents: true}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5}
+	AreEqual(t, 1.0, Percentile(values, 0), "Wrong 0th percentile")
+	AreEqual(t, 3.0, Percentile(values, 50), "Wrong median")
+	AreEqual(t, 5.0, Percentile(values, 100), "Wrong 100th percentile")
+	AreEqual(t, 1.4, Percentile(values, 10), "Wrong interpolated percentile")
+	AreEqual(t, 0.0, Percentile([]float64{}, 50), "Wrong percentile for no values")
+}
+
+func TestSummarise(t *testing.T) {
+	s := summarise([]float64{4, 1, 3, 2}, []float64{25, 75})
+	AreEqual(t, 2.5, s.Mean, "Wrong mean")
+	AreEqual(t, 2.5, s.Median, "Wrong median")
+	AreEqual(t, 1.0, s.Min, "Wrong min")
+	AreEqual(t, 4.0, s.Max, "Wrong max")
+	AreEqual(t, 1.75, s.Percentiles[0], "Wrong 25th percentile")
+	AreEqual(t, 3.25, s.Percentiles[1], "Wrong 75th percentile")
+}
+
+func TestValidateBatchSpec(t *testing.T) {
+	AssertSuccess(t, BatchSpec{Runs: 2, Iterations: 5}.Validate())
+	IsFalse(t, BatchSpec{Runs: 0, Iterations: 5}.Validate() == nil, "Zero runs not rejected")
+	IsFalse(t, BatchSpec{Runs: 2, Iterations: 0}.Validate() == nil, "Zero iterations not rejected")
+	IsFalse(t, BatchSpec{Runs: 2, Iterations: 5, Parallel: -1}.Validate() == nil, "Negative parallel not rejected")
+	IsFalse(t, BatchSpec{Runs: 2, Iterations: 5, Percentiles: []float64{101}}.Validate() == nil, "Percentile above 100 not rejected")
+}
+
+func TestRunBatchAggregatesEveryRun(t *testing.T) {
+	completed := 0
+	br, err := RunBatch(context.Background(), BatchSpec{Runs: 5, Iterations: 20, Seed: 7}, HierarchySource(CreateBatchSpec()), func(c int) {
+		completed = c
+	})
+	AssertSuccess(t, err)
+	AreEqual(t, 5, completed, "Completed runs not reported")
+	AreEqual(t, 5, br.Runs, "Wrong number of runs")
+	AreEqual(t, 5, len(br.Seeds), "Wrong number of seeds")
+	AreEqual(t, 20, len(br.Colors), "Wrong number of iterations of Colors")
+	AreEqual(t, 20, len(br.Conversations), "Wrong number of iterations of conversations")
+	AreEqual(t, 4, len(br.Colors[0]), "Wrong number of Colors")
+	AreEqual(t, len(DefaultPercentiles), len(br.Conversations[0].Percentiles), "Default percentiles not used")
+	for i := 0; i < br.Iterations; i++ {
+		total := 0.0
+		for _, s := range br.Colors[i] {
+			IsTrue(t, s.Min <= s.Percentiles[0] && s.Percentiles[0] <= s.Median && s.Median <= s.Percentiles[3] && s.Percentiles[3] <= s.Max, "Percentile bands out of order")
+			total += s.Mean
+		}
+		AreEqual(t, 21.0, math.Round(total), "Mean Color counts do not add up to the number of Agents")
+	}
+}
+
+func TestRunBatchIsReproducibleInParallel(t *testing.T) {
+	n, _, err := GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 4, TeamLinkLevel: 2, LinkTeamPeers: true, InitColors: []Color{Grey, Red, Blue}, MaxColors: 4, Seed: 3})
+	AssertSuccess(t, err)
+	colors := make([]Color, len(n.Agents()))
+	for i, a := range n.Agents() {
+		colors[i] = a.GetColor()
+	}
+	source, err := CopySource(n)
+	AssertSuccess(t, err)
+	br1, err := RunBatch(context.Background(), BatchSpec{Runs: 6, Iterations: 15, Seed: 11}, source, nil)
+	AssertSuccess(t, err)
+	br2, err := RunBatch(context.Background(), BatchSpec{Runs: 6, Iterations: 15, Seed: 11, Parallel: 3}, source, nil)
+	AssertSuccess(t, err)
+	var b1, b2 bytes.Buffer
+	AssertSuccess(t, br1.WriteCsv(&b1))
+	AssertSuccess(t, br2.WriteCsv(&b2))
+	AreEqual(t, b1.String(), b2.String(), "Parallel batch not the same as sequential batch")
+	for i, a := range n.Agents() {
+		AreEqual(t, colors[i], a.GetColor(), "Network passed to CopySource was modified")
+	}
+}
+
+func TestRunBatchFromHierarchyIsReproducibleInParallel(t *testing.T) {
+	spec := CreateBatchSpec()
+	spec.InitColors = []Color{Grey, Red, Blue}
+	br1, err := RunBatch(context.Background(), BatchSpec{Runs: 6, Iterations: 15, Seed: 11}, HierarchySource(spec), nil)
+	AssertSuccess(t, err)
+	br2, err := RunBatch(context.Background(), BatchSpec{Runs: 6, Iterations: 15, Seed: 11, Parallel: 3}, HierarchySource(spec), nil)
+	AssertSuccess(t, err)
+	var b1, b2 bytes.Buffer
+	AssertSuccess(t, br1.WriteCsv(&b1))
+	AssertSuccess(t, br2.WriteCsv(&b2))
+	AreEqual(t, b1.String(), b2.String(), "Parallel batch of generated networks not the same as sequential batch")
+}
+
+func TestRunBatchReturnsSourceError(t *testing.T) {
+	source := func(seed int64) (RelationshipMgr, error) {
+		return nil, errors.New("no network")
+	}
+	_, err := RunBatch(context.Background(), BatchSpec{Runs: 2, Iterations: 5}, source, nil)
+	AreEqual(t, "no network", err.Error(), "Source error not returned")
+}
+
+func TestRunBatchStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := RunBatch(ctx, BatchSpec{Runs: 2, Iterations: 5}, HierarchySource(CreateBatchSpec()), nil)
+	AreEqual(t, context.Canceled, err, "Context error not returned")
+}
+
+func TestBatchResultsWriteCsv(t *testing.T) {
+	br := aggregate([]Results{
+		{Iterations: 1, Colors: [][]int{{3, 1}}, Conversations: []int{2}},
+		{Iterations: 1, Colors: [][]int{{1, 3}}, Conversations: []int{4}},
+	}, []int64{1, 2}, 1, []float64{50})
+	var b bytes.Buffer
+	AssertSuccess(t, br.WriteCsv(&b))
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	AreEqual(t, 4, len(lines), "Wrong number of lines")
+	AreEqual(t, "Iteration,Series,Mean,Median,Min,Max,P50", lines[0], "Wrong header")
+	AreEqual(t, "0,Grey,2,2,1,3,2", lines[1], "Wrong Grey row")
+	AreEqual(t, "0,Blue,2,2,1,3,2", lines[2], "Wrong Blue row")
+	AreEqual(t, "0,Conversations,3,3,2,4,3", lines[3], "Wrong conversations row")
+}
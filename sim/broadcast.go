@@ -0,0 +1,143 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Reach is the set of Agents a Broadcast is sent to. If Department is set the Broadcast reaches
+// the Agent with that ID and every Agent that reports to it, directly or through other managers.
+// If Agents is set it reaches the Agents listed. If neither is set it reaches everyone.
+type Reach struct {
+	Department string   `json:"department,omitempty"`
+	Agents     []string `json:"agents,omitempty"`
+}
+
+// Broadcast is a mass communication, such as an email, a town hall or an intranet post, that
+// reaches many Agents at once. It is sent at the start of the first iteration of the run and
+// then every Frequency iterations. Each Agent it reaches evaluates it with the same UpdateColor
+// logic used for Mail, as if it had been sent by an Agent with the Color and Influence of the
+// Broadcast, so its Influence is scaled by the Peer multiplier of any HierarchyInfluence. Name
+// identifies the Broadcast as the sender of the changes of Color it causes in the EventLog.
+type Broadcast struct {
+	Name      string  `json:"name,omitempty"`
+	Color     Color   `json:"color"`
+	Reach     Reach   `json:"reach"`
+	Frequency int     `json:"frequency"`
+	Influence float64 `json:"influence"`
+}
+
+// Broadcasts is a list of Broadcasts that are sent during a run
+type Broadcasts []Broadcast
+
+// colorUpdater is an Agent that decides whether to change its Color with UpdateColor
+type colorUpdater interface {
+	UpdateColor(n RelationshipMgr, ra *AgentState) (Color, ChangeReason, bool)
+	SetColor(color Color)
+}
+
+// Validate returns an error if any Broadcast has a Frequency less than 1, a negative Influence,
+// a Color that is not permitted on the network, or a Reach that refers to an Agent that is not
+// on the network
+func (bs Broadcasts) Validate(n RelationshipMgr) error {
+	for _, b := range bs {
+		if b.Frequency < 1 {
+			return errors.New("broadcast frequency must be at least 1")
+		}
+		if b.Influence < 0 {
+			return errors.New("broadcast influence cannot be negative")
+		}
+		if int(b.Color) < 0 || int(b.Color) >= n.MaxColors() {
+			return fmt.Errorf("broadcast color %d is not permitted on the network", b.Color)
+		}
+		ids := b.Reach.Agents
+		if b.Reach.Department != "" {
+			ids = append([]string{b.Reach.Department}, ids...)
+		}
+		for _, id := range ids {
+			if n.GetAgentByID(id) == nil {
+				return fmt.Errorf("unrecognised Agent Id '%s' in broadcast reach", id)
+			}
+		}
+	}
+	return nil
+}
+
+// send sends every Broadcast that is due at the passed iteration, counted from the start of the
+// run, and returns the number of Agents that changed Color because of them
+func (bs Broadcasts) send(n RelationshipMgr, iteration int) int {
+	conversions := 0
+	for _, b := range bs {
+		if b.Frequency > 0 && iteration%b.Frequency == 0 {
+			conversions += b.send(n)
+		}
+	}
+	return conversions
+}
+
+// send delivers the Broadcast to every Agent it reaches and returns the number of them that
+// changed Color. Opinionated Agents only move their Opinion in conversations with other
+// Opinionated Agents, so they are not influenced by Broadcasts.
+func (b Broadcast) send(n RelationshipMgr) int {
+	sender := &AgentState{ID: b.Name, Color: b.Color, Influence: b.Influence}
+	conversions := 0
+	for _, a := range b.Reach.agents(n) {
+		if _, isOpinionated := a.(Opinionated); isOpinionated {
+			continue
+		}
+		cu, isUpdater := a.(colorUpdater)
+		if !isUpdater {
+			continue
+		}
+		c, _, update := cu.UpdateColor(n, sender)
+		if !update {
+			continue
+		}
+		oldColor := a.GetColor()
+		if mi, isMultiIdea := a.(*MultiIdeaAgent); isMultiIdea {
+			if c == Grey {
+				continue
+			}
+			mi.Adopt(c)
+		} else {
+			cu.SetColor(c)
+		}
+		if a.GetColor() != oldColor {
+			n.EventLog().Record(a.Identifier(), b.Name, oldColor, a.GetColor(), Broadcasted)
+			conversions++
+		}
+	}
+	return conversions
+}
+
+// agents returns the Agents on the network in the Reach
+func (r Reach) agents(n RelationshipMgr) []Agent {
+	agents := n.Agents()
+	if r.Department == "" && len(r.Agents) == 0 {
+		return agents
+	}
+	listed := make(map[string]bool, len(r.Agents))
+	for _, id := range r.Agents {
+		listed[id] = true
+	}
+	ret := []Agent{}
+	for _, a := range agents {
+		if listed[a.Identifier()] || (r.Department != "" && inDepartment(n, a, r.Department, len(agents))) {
+			ret = append(ret, a)
+		}
+	}
+	return ret
+}
+
+// inDepartment returns true if the Agent is the head of the department or reports to it through
+// the chain of managers above it. A loop in the reporting lines is only followed until all of the
+// count Agents on the network have been visited.
+func inDepartment(n RelationshipMgr, a Agent, head string, count int) bool {
+	for steps := 0; a != nil && steps <= count; steps++ {
+		if a.Identifier() == head {
+			return true
+		}
+		a = n.GetAgentByID(a.State().Manager)
+	}
+	return false
+}
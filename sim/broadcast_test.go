@@ -0,0 +1,67 @@
+package sim
+
+import (
+	"testing"
+)
+
+const broadcastNetwork = `{"maxColors":3,"nodes":[` +
+	`{"id":"a","susceptability":0.5},{"id":"b","susceptability":0.5,"manager":"a"},` +
+	`{"id":"c","susceptability":0.5,"manager":"b"},{"id":"d","susceptability":0.5}],` +
+	`"links":[{"source":"a","target":"b"},{"source":"b","target":"c"}]}`
+
+func TestBroadcastsValidate(t *testing.T) {
+	n, err := NewNetwork(broadcastNetwork)
+	AssertSuccess(t, err)
+	AssertSuccess(t, Broadcasts{}.Validate(n))
+	AssertSuccess(t, Broadcasts{{Color: Blue, Frequency: 5, Influence: 1, Reach: Reach{Department: "b", Agents: []string{"d"}}}}.Validate(n))
+	AreEqual(t, "broadcast frequency must be at least 1", Broadcasts{{Color: Blue}}.Validate(n).Error(), "Wrong error")
+	AreEqual(t, "broadcast influence cannot be negative", Broadcasts{{Frequency: 1, Influence: -1}}.Validate(n).Error(), "Wrong error")
+	AreEqual(t, "broadcast color 4 is not permitted on the network", Broadcasts{{Frequency: 1, Color: Color(4)}}.Validate(n).Error(), "Wrong error")
+	AreEqual(t, "unrecognised Agent Id 'x' in broadcast reach", Broadcasts{{Frequency: 1, Reach: Reach{Department: "x"}}}.Validate(n).Error(), "Wrong error")
+	AreEqual(t, "unrecognised Agent Id 'y' in broadcast reach", Broadcasts{{Frequency: 1, Reach: Reach{Agents: []string{"a", "y"}}}}.Validate(n).Error(), "Wrong error")
+}
+
+func TestBroadcastReachesDepartmentOrListedAgents(t *testing.T) {
+	n, err := NewNetwork(broadcastNetwork)
+	AssertSuccess(t, err)
+	AreEqual(t, 4, len(Reach{}.agents(n)), "An empty reach should reach everyone")
+	dept := Reach{Department: "b"}.agents(n)
+	AreEqual(t, 2, len(dept), "Wrong number of agents in the department")
+	AreEqual(t, "b", dept[0].Identifier(), "Department should include its head")
+	AreEqual(t, "c", dept[1].Identifier(), "Department should include indirect reports")
+	both := Reach{Department: "b", Agents: []string{"d"}}.agents(n)
+	AreEqual(t, 3, len(both), "Listed agents not added to the department")
+}
+
+func TestBroadcastSentAtFrequencyConvertsAgents(t *testing.T) {
+	n, err := NewNetwork(broadcastNetwork)
+	AssertSuccess(t, err)
+	log := NewEventLog()
+	n.SetEventLog(log)
+	bs := Broadcasts{{Name: "townhall", Color: Red, Frequency: 3, Influence: 1, Reach: Reach{Agents: []string{"c", "d"}}}}
+	AreEqual(t, 0, bs.send(n, 2), "Broadcast sent when it was not due")
+	AreEqual(t, Grey, n.GetAgentByID("c").GetColor(), "Broadcast sent when it was not due")
+	AreEqual(t, 2, bs.send(n, 3), "Wrong number of broadcast conversions")
+	AreEqual(t, Red, n.GetAgentByID("d").GetColor(), "Agent not converted by the broadcast")
+	AreEqual(t, Grey, n.GetAgentByID("a").GetColor(), "Agent out of reach converted by the broadcast")
+	AreEqual(t, ColorChange{0, "c", "townhall", Grey, Red, Broadcasted}, log.Events[0], "Wrong change logged")
+	AreEqual(t, 0, bs.send(n, 6), "Agents already holding the color counted again")
+
+	weak := Broadcasts{{Color: Blue, Frequency: 1, Influence: 0.25}}
+	AreEqual(t, 0, weak.send(n, 0), "Agents should not be converted by a broadcast with less influence than their susceptability")
+}
+
+func TestRunnerReportsBroadcastAndPeerConversionsSeparately(t *testing.T) {
+	n, err := NewNetwork(`{"maxColors":3,"nodes":[{"id":"a","susceptability":5,"influence":1},{"id":"b","susceptability":0.5}],"links":[{"source":"a","target":"b"}]}`)
+	AssertSuccess(t, err)
+	r := NewSeededRunner(n, 2, 1)
+	r.SetBroadcasts(Broadcasts{{Color: Blue, Frequency: 10, Influence: 10, Reach: Reach{Agents: []string{"a"}}}})
+	results := r.Run()
+	AreEqual(t, 1, results.BroadcastConversions[0], "Broadcast conversion not reported")
+	AreEqual(t, 0, results.BroadcastConversions[1], "Broadcast sent when it was not due")
+	AreEqual(t, 1, results.Conversions[0]+results.Conversions[1], "Peer conversions should only include the agent converted by its peer")
+	AreEqual(t, 2, results.Colors[1][Blue], "Color did not spread from the agent reached by the broadcast")
+
+	results = NewSeededRunner(n, 2, 1).Run()
+	IsTrue(t, results.Conversions == nil, "Conversions recorded without broadcasts")
+}
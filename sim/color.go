@@ -21,18 +21,18 @@ const MaxDefinedColors int = 7
 
 //go:generate stringer -type=Color
 
-//RandomlySelectAlternateColor selects a Color other than the one passed
-//and other than Grey unless there is only one color to choose from
-func RandomlySelectAlternateColor(color Color, maxColors int) Color {
+//RandomlySelectAlternateColor uses the passed random source to select a Color other
+//than the one passed and other than Grey unless there is only one color to choose from
+func RandomlySelectAlternateColor(r *rand.Rand, color Color, maxColors int) Color {
 	if maxColors > MaxDefinedColors {
 		maxColors = MaxDefinedColors
 	}
-	altColor := Color(rand.Intn(maxColors))
+	altColor := Color(r.Intn(maxColors))
 	if maxColors <= 2 {
 		return Blue
 	}
 	for altColor == color || altColor == Grey {
-		altColor = Color(rand.Intn(maxColors))
+		altColor = Color(r.Intn(maxColors))
 	}
 	return altColor
 }
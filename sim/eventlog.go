@@ -0,0 +1,73 @@
+package sim
+
+// ChangeReason describes why an Agent changed its Color
+type ChangeReason string
+
+// The list of reasons an Agent can change its Color
+const (
+	//Influenced means the Agent adopted the Color of the Agent that sent it a Mail
+	Influenced ChangeReason = "influenced"
+	//Contrarian means the Agent was influenced by the sender but its Contrariness made it
+	//change to a randomly selected alternate Color instead
+	Contrarian ChangeReason = "contrarian"
+	//ThresholdReached means the Agent adopted the Color of the Agent that sent it a Mail because
+	//enough of its neighbours hold that Color
+	ThresholdReached ChangeReason = "threshold"
+	//OpinionShift means the Agent's opinion moved towards that of the Agent that sent it a Mail
+	//far enough to change its Color
+	OpinionShift ChangeReason = "opinion"
+	//Intervened means the Color of the Agent was set by an Intervention during the run
+	Intervened ChangeReason = "intervention"
+	//Broadcasted means the Agent was influenced by a Broadcast
+	Broadcasted ChangeReason = "broadcast"
+)
+
+// ColorChange records a single change of Color by an Agent during a run. Iteration is the
+// index of the iteration in the run, and SenderID is the Agent whose Mail caused the change.
+type ColorChange struct {
+	Iteration int          `json:"iteration"`
+	AgentID   string       `json:"agent"`
+	SenderID  string       `json:"sender"`
+	OldColor  Color        `json:"oldColor"`
+	NewColor  Color        `json:"newColor"`
+	Reason    ChangeReason `json:"reason"`
+}
+
+// EventLog records every change of Color made by the Agents on a network during a run. It
+// is optional, a Runner only records changes if an EventLog has been set on it. Changes are
+// recorded while the Agents read their Mail, which happens on a single goroutine, so the
+// EventLog does not need to be safe for concurrent use.
+type EventLog struct {
+	Events    []ColorChange `json:"events"`
+	iteration int
+}
+
+// NewEventLog returns a new empty EventLog
+func NewEventLog() *EventLog {
+	return &EventLog{
+		Events: []ColorChange{},
+	}
+}
+
+// Record adds a change of Color made during the current iteration to the log. It does
+// nothing if the log is nil, or if the Color has not changed.
+func (l *EventLog) Record(agentID string, senderID string, oldColor Color, newColor Color, reason ChangeReason) {
+	if l == nil || oldColor == newColor {
+		return
+	}
+	l.Events = append(l.Events, ColorChange{
+		Iteration: l.iteration,
+		AgentID:   agentID,
+		SenderID:  senderID,
+		OldColor:  oldColor,
+		NewColor:  newColor,
+		Reason:    reason,
+	})
+}
+
+// SetIteration sets the index of the iteration that subsequent changes are recorded against
+func (l *EventLog) SetIteration(i int) {
+	if l != nil {
+		l.iteration = i
+	}
+}
@@ -0,0 +1,87 @@
+package sim
+
+import (
+	"testing"
+)
+
+func TestRecordOnNilEventLogDoesNothing(t *testing.T) {
+	var l *EventLog
+	l.SetIteration(3)
+	l.Record("id_1", "id_2", Grey, Red, Influenced)
+}
+
+func TestRecordIgnoresUnchangedColor(t *testing.T) {
+	l := NewEventLog()
+	l.Record("id_1", "id_2", Red, Red, Influenced)
+	AreEqual(t, 0, len(l.Events), "Unchanged Color recorded")
+}
+
+func TestRecordAddsChangeAtCurrentIteration(t *testing.T) {
+	l := NewEventLog()
+	l.SetIteration(7)
+	l.Record("id_1", "id_2", Grey, Red, Influenced)
+	AreEqual(t, 1, len(l.Events), "Change not recorded")
+	AreEqual(t, ColorChange{7, "id_1", "id_2", Grey, Red, Influenced}, l.Events[0], "Wrong change recorded")
+}
+
+func TestReadMailRecordsInfluencedChange(t *testing.T) {
+	tn := newTestNetwork()
+	tn.SetEventLog(NewEventLog())
+	aut := newAgent()
+	aut.ID = "id_aut"
+	aut.Susceptability = 0.4
+	aut.Color = Red
+	aut.PostMsg("id_1")
+	aut.ReadMail(tn)
+	AreEqual(t, 1, len(tn.EventLog().Events), "Change not recorded")
+	AreEqual(t, ColorChange{0, "id_aut", "id_1", Red, Blue, Influenced}, tn.EventLog().Events[0], "Wrong change recorded")
+}
+
+func TestReadMailRecordsContrarianChange(t *testing.T) {
+	tn := newTestNetwork()
+	tn.SetEventLog(NewEventLog())
+	aut := newAgent()
+	aut.ID = "id_aut"
+	aut.Susceptability = 0.4
+	aut.Contrariness = 0.6
+	aut.Color = Red
+	aut.PostMsg("id_1")
+	aut.ReadMail(tn)
+	AreEqual(t, 1, len(tn.EventLog().Events), "Change not recorded")
+	AreEqual(t, Contrarian, tn.EventLog().Events[0].Reason, "Contrarian flip not recorded")
+	AreEqual(t, aut.Color, tn.EventLog().Events[0].NewColor, "Wrong new Color recorded")
+}
+
+func TestReadMailDoesNotRecordWhenColorUnchanged(t *testing.T) {
+	tn := newTestNetwork()
+	tn.SetEventLog(NewEventLog())
+	aut := newAgent()
+	aut.ID = "id_aut"
+	aut.Susceptability = 1
+	aut.Color = Red
+	aut.PostMsg("id_1")
+	aut.ReadMail(tn)
+	AreEqual(t, 0, len(tn.EventLog().Events), "Change recorded when Color not changed")
+}
+
+func TestRunRecordsEveryColorChange(t *testing.T) {
+	n, _, err := GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 3, InitColors: []Color{Grey, Red, Blue}, MaxColors: 4, AgentsWithMemory: true, Seed: 3})
+	AssertSuccess(t, err)
+	runner := NewSeededRunner(n, 30, 5)
+	l := NewEventLog()
+	runner.SetEventLog(l)
+	runner.Run()
+
+	changes := 0
+	for _, a := range n.Agents() {
+		changes += a.State().ChangeCount
+	}
+	IsTrue(t, changes > 0, "No changes made during the run")
+	AreEqual(t, changes, len(l.Events), "Not every change recorded")
+	last := 0
+	for _, e := range l.Events {
+		IsTrue(t, e.Iteration >= last && e.Iteration < 30, "Changes not recorded in iteration order")
+		last = e.Iteration
+		NotEqual(t, e.OldColor, e.NewColor, "Change recorded when Color not changed")
+	}
+}
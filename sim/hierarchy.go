@@ -0,0 +1,60 @@
+package sim
+
+import (
+	"errors"
+)
+
+// HierarchyInfluence sets multipliers for the Influence of an Agent depending on where it sits
+// in the reporting lines relative to the Agent it talks to. Downward scales the Influence of a
+// manager over its direct reports, Upward scales the Influence of a report over its manager,
+// and Peer scales the Influence in every other conversation. A multiplier that is not set
+// leaves the Influence unchanged, so a top-down rollout can be compared with grassroots spread
+// by raising Downward or Upward.
+type HierarchyInfluence struct {
+	Upward   float64 `json:"upward,omitempty"`
+	Downward float64 `json:"downward,omitempty"`
+	Peer     float64 `json:"peer,omitempty"`
+}
+
+// Active returns true if any of the multipliers are set
+func (h HierarchyInfluence) Active() bool {
+	return h.Upward != 0 || h.Downward != 0 || h.Peer != 0
+}
+
+// Validate returns an error if any of the multipliers are negative
+func (h HierarchyInfluence) Validate() error {
+	if h.Upward < 0 || h.Downward < 0 || h.Peer < 0 {
+		return errors.New("hierarchy influence multipliers cannot be negative")
+	}
+	return nil
+}
+
+// Multiplier returns the multiplier for the Influence of the sender over the receiver
+func (h HierarchyInfluence) Multiplier(sender *AgentState, receiver *AgentState) float64 {
+	m := h.Peer
+	switch {
+	case receiver.Manager != "" && receiver.Manager == sender.ID:
+		m = h.Downward
+	case sender.Manager != "" && sender.Manager == receiver.ID:
+		m = h.Upward
+	}
+	if m == 0 {
+		return 1
+	}
+	return m
+}
+
+// populateLevels sets the Level of every Agent on the network to the number of managers above
+// it in the reporting lines. Agents with no manager on the network are at level 0, and a loop in
+// the reporting lines is only followed until every Agent has been visited.
+func (n *Network) populateLevels() {
+	for _, a := range n.Nodes {
+		level := 0
+		m := n.AgentsByID[a.State().Manager]
+		for m != nil && level < len(n.Nodes) {
+			level++
+			m = n.AgentsByID[m.State().Manager]
+		}
+		a.State().Level = level
+	}
+}
@@ -0,0 +1,98 @@
+package sim
+
+import (
+	"testing"
+)
+
+func TestHierarchyInfluenceValidate(t *testing.T) {
+	AssertSuccess(t, HierarchyInfluence{}.Validate())
+	AssertSuccess(t, HierarchyInfluence{Upward: 0.5, Downward: 2, Peer: 1}.Validate())
+	AreEqual(t, "hierarchy influence multipliers cannot be negative", HierarchyInfluence{Peer: -1}.Validate().Error(), "Wrong error")
+	IsFalse(t, HierarchyInfluence{}.Active(), "No multipliers should not be active")
+}
+
+func TestHierarchyInfluenceMultiplier(t *testing.T) {
+	manager := &AgentState{ID: "boss"}
+	report := &AgentState{ID: "report", Manager: "boss"}
+	peer := &AgentState{ID: "peer", Manager: "boss"}
+	h := HierarchyInfluence{Upward: 0.5, Downward: 2, Peer: 3}
+	AreEqual(t, 2.0, h.Multiplier(manager, report), "Wrong multiplier for a manager talking to a report")
+	AreEqual(t, 0.5, h.Multiplier(report, manager), "Wrong multiplier for a report talking to its manager")
+	AreEqual(t, 3.0, h.Multiplier(report, peer), "Wrong multiplier for peers")
+	AreEqual(t, 1.0, HierarchyInfluence{Peer: 3}.Multiplier(manager, report), "A multiplier that is not set should not change influence")
+}
+
+func TestReadMailScalesInfluenceByHierarchy(t *testing.T) {
+	tn := newTestNetwork()
+	tn.SetHierarchyInfluence(HierarchyInfluence{Downward: 2, Peer: 0.5})
+	aut := newAgent()
+	aut.ID = "id_aut"
+	aut.Susceptability = 0.4
+	aut.Color = Red
+	aut.PostMsg("id_1")
+	aut.ReadMail(tn)
+	AreEqual(t, Red, aut.Color, "Agent Color should not change when the peer multiplier lowers influence")
+
+	aut.Manager = "id_1"
+	aut.Susceptability = 0.8
+	aut.PostMsg("id_1")
+	aut.ReadMail(tn)
+	AreEqual(t, Blue, aut.Color, "Agent Color should change when the downward multiplier raises its manager's influence")
+}
+
+func TestGenerateHierarchySetsManagersAndLevels(t *testing.T) {
+	n, o, err := GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 2, LinkTeamPeers: true, InitColors: []Color{Grey}, MaxColors: 4, Seed: 1})
+	AssertSuccess(t, err)
+	root := n.GetAgentByID("id_1").State()
+	AreEqual(t, "", root.Manager, "Root should not have a manager")
+	AreEqual(t, 0, root.Level, "Wrong level for root")
+	child := n.GetAgentByID("id_2").State()
+	AreEqual(t, "id_1", child.Manager, "Wrong manager")
+	AreEqual(t, 1, child.Level, "Wrong level")
+	grandchild := n.GetAgentByID("id_3").State()
+	AreEqual(t, "id_2", grandchild.Manager, "Wrong manager")
+	AreEqual(t, 2, grandchild.Level, "Wrong level")
+
+	o.LinkTeamPeers = false
+	clone, err := o.CloneModify(n)
+	AssertSuccess(t, err)
+	AreEqual(t, 2, clone.GetAgentByID("id_3").State().Level, "Reporting lines not kept when cloning")
+}
+
+func TestParseDelimSetsManagers(t *testing.T) {
+	data := []string{
+		"Id,Parent",
+		"ceo,",
+		"cto,ceo",
+		"dev,cto",
+	}
+	po := ParseOptions{
+		Delimiter:  ",",
+		Identifier: 0,
+		Parent:     1,
+	}
+	rm, err := po.ParseDelim(data)
+	AssertSuccess(t, err)
+	AreEqual(t, "cto", rm.GetAgentByID("dev").State().Manager, "Wrong manager")
+	AreEqual(t, 2, rm.GetAgentByID("dev").State().Level, "Wrong level")
+	AreEqual(t, 0, rm.GetAgentByID("ceo").State().Level, "Wrong level")
+
+	n, err := NewNetwork(`{"nodes":[{"id":"a","manager":"b"},{"id":"b","manager":"a"}],"links":[]}`)
+	AssertSuccess(t, err)
+	AreEqual(t, 2, n.GetAgentByID("a").State().Level, "Loop in reporting lines not stopped")
+}
+
+func TestRunnerRecordsHierarchyInfluence(t *testing.T) {
+	s := HierarchySpec{Levels: 3, TeamSize: 3, LinkTeamPeers: true, InitColors: []Color{Grey, Blue}, MaxColors: 2, Seed: 4}
+	h := HierarchyInfluence{Downward: 3}
+	n, _, _ := GenerateHierarchy(s)
+	r := NewSeededRunner(n, 10, 7)
+	r.SetHierarchyInfluence(h)
+	results := r.Run()
+	AreEqual(t, h, *results.Hierarchy, "Hierarchy influence not recorded in the results")
+	AreEqual(t, h, n.HierarchyInfluence(), "Hierarchy influence not set on the network")
+
+	n2, _, _ := GenerateHierarchy(s)
+	results = NewSeededRunner(n2, 10, 7).Run()
+	IsTrue(t, results.Hierarchy == nil, "Hierarchy influence recorded when not set")
+}
@@ -0,0 +1,180 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+)
+
+// InterventionType is the name of a change that can be made to the network during a run
+type InterventionType string
+
+// The list of supported intervention types
+const (
+	ColorIntervention       InterventionType = "color"
+	TraitIntervention       InterventionType = "traits"
+	AddLinksIntervention    InterventionType = "addLinks"
+	RemoveLinksIntervention InterventionType = "removeLinks"
+	EvangelistIntervention  InterventionType = "evangelist"
+)
+
+// Intervention is a change made to the network at the start of an iteration of a run, before
+// any Mail is sent. Iteration counts from the start of the run, so a run split into several
+// calls to RunContext on the same Runner carries on counting from where the last call ended.
+// A color intervention sets the listed Agents to the Color. A traits intervention sets the
+// Susceptability, Influence and Contrariness that are given on the listed Agents. An addLinks
+// intervention adds the Links, and a Link that is already between the same Agents takes the
+// direction and Trust of the new one and is reactivated if it is inactive. A removeLinks
+// intervention deactivates any Link between the Agents of each of the Links. An
+// evangelist intervention sets the listed Agents to the Color, or to Blue if no Color is given,
+// and raises their Susceptability so that they cannot be influenced, as AddEvangelists does.
+type Intervention struct {
+	Iteration      int              `json:"iteration"`
+	Type           InterventionType `json:"type"`
+	Agents         []string         `json:"agents,omitempty"`
+	Color          Color            `json:"color,omitempty"`
+	Susceptability *float64         `json:"susceptability,omitempty"`
+	Influence      *float64         `json:"influence,omitempty"`
+	Contrariness   *float64         `json:"contrariness,omitempty"`
+	Links          []Link           `json:"links,omitempty"`
+}
+
+// InterventionSchedule is a list of Interventions that are applied during a run
+type InterventionSchedule []Intervention
+
+// AppliedIntervention records an Intervention applied during a run. Iteration is the index
+// of the iteration in the Results it was applied at the start of.
+type AppliedIntervention struct {
+	Iteration    int          `json:"iteration"`
+	Intervention Intervention `json:"intervention"`
+}
+
+// Validate returns an error if any Intervention in the schedule has a negative Iteration or an
+// unrecognised Type, refers to an Agent that is not on the network, or uses a Color that is not
+// permitted on the network
+func (s InterventionSchedule) Validate(n RelationshipMgr) error {
+	for _, iv := range s {
+		if iv.Iteration < 0 {
+			return errors.New("intervention iteration cannot be negative")
+		}
+		switch iv.Type {
+		case ColorIntervention, TraitIntervention, AddLinksIntervention, RemoveLinksIntervention, EvangelistIntervention:
+		default:
+			return fmt.Errorf("unrecognised intervention type '%s'", iv.Type)
+		}
+		if int(iv.Color) < 0 || int(iv.Color) >= n.MaxColors() {
+			return fmt.Errorf("intervention color %d is not permitted on the network", iv.Color)
+		}
+		ids := append([]string{}, iv.Agents...)
+		for _, l := range iv.Links {
+			ids = append(ids, l.Agent1ID, l.Agent2ID)
+		}
+		for _, id := range ids {
+			if n.GetAgentByID(id) == nil {
+				return fmt.Errorf("unrecognised Agent Id '%s' in intervention", id)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply makes the change described by the Intervention to the network. Changes of Color are
+// recorded in the network's EventLog with the Intervened reason. The Color of an OpinionAgent is
+// always derived from its Opinion, so its Opinion is moved into the band for the new Color. A
+// MultiIdeaAgent adopts the new Color as an Idea, or drops all of its Ideas if it is Grey.
+// Returns an error if an Agent the Intervention refers to is not on the network.
+func (iv Intervention) Apply(n RelationshipMgr) error {
+	agents := make([]*AgentState, 0, len(iv.Agents))
+	for _, id := range iv.Agents {
+		a := n.GetAgentByID(id)
+		if a == nil {
+			return fmt.Errorf("unrecognised Agent Id '%s' in intervention", id)
+		}
+		agents = append(agents, a.State())
+	}
+	switch iv.Type {
+	case ColorIntervention, EvangelistIntervention:
+		c := iv.Color
+		if iv.Type == EvangelistIntervention && c == Grey {
+			c = Blue
+		}
+		for _, a := range agents {
+			old := a.Color
+			switch agent := n.GetAgentByID(a.ID).(type) {
+			case *OpinionAgent:
+				agent.MoveToBand(c)
+			case *MultiIdeaAgent:
+				if c == Grey {
+					agent.Ideas = []Color{}
+					agent.SetColor(Grey)
+				} else {
+					agent.Adopt(c)
+				}
+			default:
+				a.SetColor(c)
+			}
+			n.EventLog().Record(a.ID, "", old, a.Color, Intervened)
+			if iv.Type == EvangelistIntervention {
+				a.Susceptability = 5.0
+			}
+		}
+	case TraitIntervention:
+		for _, a := range agents {
+			if iv.Susceptability != nil {
+				a.Susceptability = *iv.Susceptability
+			}
+			if iv.Influence != nil {
+				a.Influence = *iv.Influence
+			}
+			if iv.Contrariness != nil {
+				a.Contrariness = *iv.Contrariness
+			}
+		}
+	case AddLinksIntervention, RemoveLinksIntervention:
+		return iv.applyLinks(n)
+	}
+	return nil
+}
+
+// applyLinks adds or deactivates the Links of the Intervention and populates the maps of the
+// Links on the network again
+func (iv Intervention) applyLinks(n RelationshipMgr) error {
+	existing := make(map[[2]string]*Link, len(n.Links()))
+	for _, l := range n.Links() {
+		existing[linkKey(l.Agent1ID, l.Agent2ID)] = l
+	}
+	for _, l := range iv.Links {
+		a1 := n.GetAgentByID(l.Agent1ID)
+		a2 := n.GetAgentByID(l.Agent2ID)
+		if a1 == nil || a2 == nil {
+			return fmt.Errorf("unrecognised Agent Id '%s' or '%s' in intervention", l.Agent1ID, l.Agent2ID)
+		}
+		link, exists := existing[linkKey(l.Agent1ID, l.Agent2ID)]
+		if iv.Type == RemoveLinksIntervention {
+			if exists {
+				link.Inactive = true
+			}
+			continue
+		}
+		if !exists {
+			n.AddLink(a1, a2)
+			link = n.Links()[len(n.Links())-1]
+			existing[linkKey(l.Agent1ID, l.Agent2ID)] = link
+		}
+		link.Agent1ID, link.Agent2ID = l.Agent1ID, l.Agent2ID
+		link.Inactive = false
+		link.Directed = l.Directed
+		link.Trust = l.Trust
+	}
+	return n.PopulateLinkMaps()
+}
+
+// at returns the Interventions in the schedule that are applied at the passed iteration
+func (s InterventionSchedule) at(iteration int) []Intervention {
+	var ret []Intervention
+	for _, iv := range s {
+		if iv.Iteration == iteration {
+			ret = append(ret, iv)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,139 @@
+package sim
+
+import (
+	"fmt"
+	"testing"
+)
+
+const interventionNetwork = `{"maxColors":3,"nodes":[{"id":"a","susceptability":5},{"id":"b","susceptability":5},{"id":"c","susceptability":5}],"links":[{"source":"a","target":"b"}]}`
+
+func TestInterventionScheduleValidate(t *testing.T) {
+	n, err := NewNetwork(interventionNetwork)
+	AssertSuccess(t, err)
+	AssertSuccess(t, InterventionSchedule{}.Validate(n))
+	AssertSuccess(t, InterventionSchedule{{Iteration: 2, Type: ColorIntervention, Agents: []string{"a"}, Color: Red}}.Validate(n))
+	AreEqual(t, "intervention iteration cannot be negative", InterventionSchedule{{Iteration: -1, Type: ColorIntervention}}.Validate(n).Error(), "Wrong error")
+	AreEqual(t, "unrecognised intervention type 'paint'", InterventionSchedule{{Type: "paint"}}.Validate(n).Error(), "Wrong error")
+	AreEqual(t, "intervention color 4 is not permitted on the network", InterventionSchedule{{Type: ColorIntervention, Color: Color(4)}}.Validate(n).Error(), "Wrong error")
+	AreEqual(t, "unrecognised Agent Id 'x' in intervention", InterventionSchedule{{Type: TraitIntervention, Agents: []string{"x"}}}.Validate(n).Error(), "Wrong error")
+	AreEqual(t, "unrecognised Agent Id 'x' in intervention", InterventionSchedule{{Type: AddLinksIntervention, Links: []Link{{Agent1ID: "a", Agent2ID: "x"}}}}.Validate(n).Error(), "Wrong error")
+}
+
+func TestRunnerAppliesColorInterventionAtIteration(t *testing.T) {
+	n, err := NewNetwork(interventionNetwork)
+	AssertSuccess(t, err)
+	log := NewEventLog()
+	iv := Intervention{Iteration: 2, Type: ColorIntervention, Agents: []string{"a", "c"}, Color: Red}
+	r := NewSeededRunner(n, 5, 1)
+	r.SetEventLog(log)
+	r.SetInterventions(InterventionSchedule{iv})
+	results := r.Run()
+	AreEqual(t, 0, results.Colors[1][Red], "Intervention applied too early")
+	AreEqual(t, 2, results.Colors[2][Red], "Intervention not applied at its iteration")
+	AreEqual(t, 2, results.Colors[4][Red], "Intervention did not last")
+	AreEqual(t, 1, len(results.Interventions), "Applied intervention not recorded")
+	AreEqual(t, 2, results.Interventions[0].Iteration, "Wrong iteration recorded")
+	AreEqual(t, ColorIntervention, results.Interventions[0].Intervention.Type, "Wrong intervention recorded")
+	AreEqual(t, 2, len(log.Events), "Changes of color not logged")
+	AreEqual(t, ColorChange{2, "a", "", Grey, Red, Intervened}, log.Events[0], "Wrong change logged")
+}
+
+func TestRunnerCountsInterventionIterationsAcrossRuns(t *testing.T) {
+	n, err := NewNetwork(interventionNetwork)
+	AssertSuccess(t, err)
+	r := NewSeededRunner(n, 3, 1)
+	r.SetInterventions(InterventionSchedule{{Iteration: 4, Type: ColorIntervention, Agents: []string{"b"}, Color: Blue}})
+	results := r.Run()
+	AreEqual(t, 0, len(results.Interventions), "Intervention applied in the wrong run")
+	results = r.Run()
+	AreEqual(t, 1, len(results.Interventions), "Intervention not applied in the second run")
+	AreEqual(t, 1, results.Interventions[0].Iteration, "Iteration should be the index in the second run's results")
+	AreEqual(t, 0, results.Colors[0][Blue], "Intervention applied too early")
+	AreEqual(t, 1, results.Colors[1][Blue], "Intervention not applied")
+}
+
+func TestTraitAndEvangelistInterventions(t *testing.T) {
+	n, err := NewNetwork(interventionNetwork)
+	AssertSuccess(t, err)
+	s, i := 0.25, 2.0
+	AssertSuccess(t, Intervention{Type: TraitIntervention, Agents: []string{"b"}, Susceptability: &s, Influence: &i}.Apply(n))
+	b := n.GetAgentByID("b").State()
+	AreEqual(t, 0.25, b.Susceptability, "Susceptability not set")
+	AreEqual(t, 2.0, b.Influence, "Influence not set")
+	AreEqual(t, 0.0, b.Contrariness, "Contrariness should not change when not given")
+
+	AssertSuccess(t, Intervention{Type: EvangelistIntervention, Agents: []string{"b"}}.Apply(n))
+	AreEqual(t, Blue, b.Color, "Evangelist should default to Blue")
+	AreEqual(t, 5.0, b.Susceptability, "Evangelist should not be susceptable")
+}
+
+func TestLinkInterventionsAddAndRemoveLinks(t *testing.T) {
+	n, err := NewNetwork(interventionNetwork)
+	AssertSuccess(t, err)
+	trust := 0.5
+	AssertSuccess(t, Intervention{Type: AddLinksIntervention, Links: []Link{{Agent1ID: "c", Agent2ID: "a", Trust: &trust, Directed: true}}}.Apply(n))
+	AreEqual(t, 2, len(n.Links()), "Link not added")
+	AreEqual(t, 1, len(n.GetRelatedAgents(n.GetAgentByID("c"))), "Added link not used")
+	AreEqual(t, 1, len(n.GetRelatedAgents(n.GetAgentByID("a"))), "Added link should be directed from c to a")
+	AreEqual(t, 0.5, n.GetLink("c", "a").TrustWeight(), "Trust not set on the added link")
+
+	AssertSuccess(t, Intervention{Type: RemoveLinksIntervention, Links: []Link{{Agent1ID: "b", Agent2ID: "a"}}}.Apply(n))
+	IsTrue(t, n.Links()[0].Inactive, "Link not deactivated")
+	AreEqual(t, 0, len(n.GetRelatedAgents(n.GetAgentByID("b"))), "Removed link still used")
+
+	AssertSuccess(t, Intervention{Type: AddLinksIntervention, Links: []Link{{Agent1ID: "a", Agent2ID: "b"}}}.Apply(n))
+	AreEqual(t, 2, len(n.Links()), "Existing link should be reused")
+	IsFalse(t, n.Links()[0].Inactive, "Link not reactivated")
+}
+
+func TestColorInterventionOnOpinionAgentSurvivesLinkIntervention(t *testing.T) {
+	n, err := NewNetwork(`{"maxColors":4,"nodes":[` +
+		`{"id":"a","type":"OpinionAgent","opinion":0.1},{"id":"b","type":"OpinionAgent","opinion":0.1},{"id":"c","type":"OpinionAgent","opinion":0.9}],` +
+		`"links":[{"source":"a","target":"b"}]}`)
+	AssertSuccess(t, err)
+	log := NewEventLog()
+	r := NewSeededRunner(n, 4, 1)
+	r.SetEventLog(log)
+	r.SetInterventions(InterventionSchedule{
+		{Iteration: 1, Type: ColorIntervention, Agents: []string{"a"}, Color: Red},
+		{Iteration: 2, Type: AddLinksIntervention, Links: []Link{{Agent1ID: "b", Agent2ID: "c"}}},
+	})
+	results := r.Run()
+	a := n.GetAgentByID("a").(*OpinionAgent)
+	AreEqual(t, Red, a.Color, "Color intervention undone by the link intervention")
+	AreEqual(t, 0.625, a.Opinion, "Opinion not moved into the band for the new Color")
+	AreEqual(t, 1, results.Colors[3][Red], "Color intervention not kept to the end of the run")
+	AreEqual(t, 1, len(log.Events), "Unexpected changes of Color logged")
+	AreEqual(t, Intervened, log.Events[0].Reason, "Wrong reason logged")
+}
+
+func TestColorInterventionOnMultiIdeaAgentAdoptsIdea(t *testing.T) {
+	n, err := NewNetwork(`{"maxColors":4,"nodes":[` +
+		`{"id":"a","type":"MultiIdeaAgent","color":2,"influence":1,"susceptability":5,"exclusive":[[1,2]]},{"id":"b","type":"MultiIdeaAgent","susceptability":0.5}],` +
+		`"links":[{"source":"a","target":"b"}]}`)
+	AssertSuccess(t, err)
+	r := NewSeededRunner(n, 3, 1)
+	r.SetInterventions(InterventionSchedule{{Iteration: 0, Type: ColorIntervention, Agents: []string{"a"}, Color: Blue}})
+	results := r.Run()
+	a := n.GetAgentByID("a").(*MultiIdeaAgent)
+	AreEqual(t, Blue, a.Color, "Color not set by the intervention")
+	AreEqual(t, "[Blue]", fmt.Sprint(a.Ideas), "Color not adopted as an Idea in place of the exclusive Idea")
+	AreEqual(t, 2, results.Colors[2][Blue], "Idea adopted by the intervention not spread")
+	AreEqual(t, 2, results.Ideas[2][Blue], "Ideas not counted for the Idea adopted by the intervention")
+
+	AssertSuccess(t, Intervention{Type: ColorIntervention, Agents: []string{"a"}, Color: Grey}.Apply(n))
+	AreEqual(t, Grey, a.Color, "Color not reset by the intervention")
+	AreEqual(t, 0, len(a.Ideas), "Ideas not dropped by a Grey intervention")
+}
+
+func TestOpinionAgentMoveToBand(t *testing.T) {
+	a := newOpinionAgent("id_aut", 0.1)
+	a.MoveToBand(Blue)
+	AreEqual(t, 0.375, a.Opinion, "Opinion not moved to the middle of the band")
+	AreEqual(t, Blue, a.Color, "Color not set from the Opinion")
+	a.Bands = []float64{0.2, 0.6}
+	a.MoveToBand(Red)
+	AreEqual(t, 0.8, a.Opinion, "Opinion not moved to the middle of the last band")
+	a.MoveToBand(Green)
+	AreEqual(t, Red, a.Color, "A Color above the last band should move into the last band")
+}
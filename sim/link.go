@@ -1,9 +1,23 @@
 package sim
 
-//A Link between Agents in the Network
+//A Link between Agents in the Network. Strength counts the conversations held over the Link,
+//less any decay. An Inactive Link is kept in the Network but Agents do not talk over it. A
+//Directed Link only carries Mail from the source Agent to the target Agent. Trust is an optional
+//weight that scales the Influence of an Agent over the Agent it talks to over the Link.
 type Link struct {
-	Agent1ID string  `json:"source"`
-	Agent2ID string  `json:"target"`
-	Strength int     `json:"strength,omitempty"`
-	Length   float64 `json:"length,omitempty"`
+	Agent1ID string   `json:"source"`
+	Agent2ID string   `json:"target"`
+	Strength float64  `json:"strength,omitempty"`
+	Length   float64  `json:"length,omitempty"`
+	Inactive bool     `json:"inactive,omitempty"`
+	Directed bool     `json:"directed,omitempty"`
+	Trust    *float64 `json:"trust,omitempty"`
+}
+
+//TrustWeight returns the Trust of the Link, or 1 if the Link has no Trust or is nil
+func (l *Link) TrustWeight() float64 {
+	if l == nil || l.Trust == nil {
+		return 1
+	}
+	return *l.Trust
 }
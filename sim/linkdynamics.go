@@ -0,0 +1,111 @@
+package sim
+
+import (
+	"errors"
+)
+
+// LinkDynamics makes the network co-evolve with the conversations held on it. At the end of
+// every iteration the Strength of every active Link is reduced by the fraction Decay. A Link
+// whose Strength falls from at or above the Threshold to below it is deactivated, so the
+// Agents it joins no longer talk to each other through it. Links that have never reached the
+// Threshold are left active. With probability Closure each Agent then forms a new Link to an
+// Agent that one of its related Agents is linked to but it is not (triadic closure). New Links
+// start with the Strength of a single conversation, and a deactivated Link between the two
+// Agents is reactivated rather than duplicated.
+type LinkDynamics struct {
+	Decay     float64 `json:"decay,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+	Closure   float64 `json:"closure,omitempty"`
+}
+
+// Active returns true if the LinkDynamics change the network
+func (d LinkDynamics) Active() bool {
+	return d.Decay > 0 || d.Closure > 0
+}
+
+// Validate returns an error if the Decay or Closure are not between 0 and 1, or the Threshold
+// is negative
+func (d LinkDynamics) Validate() error {
+	if d.Decay < 0 || d.Decay > 1 {
+		return errors.New("link decay must be between 0 and 1")
+	}
+	if d.Threshold < 0 {
+		return errors.New("link threshold cannot be negative")
+	}
+	if d.Closure < 0 || d.Closure > 1 {
+		return errors.New("link closure must be between 0 and 1")
+	}
+	return nil
+}
+
+// Apply decays, deactivates and forms the Links on the network for one iteration. If the
+// structure of the network changes the maps of its Links are populated again.
+func (d LinkDynamics) Apply(n RelationshipMgr) error {
+	changed := false
+	for _, l := range n.Links() {
+		if l.Inactive {
+			continue
+		}
+		before := l.Strength
+		l.Strength = before * (1 - d.Decay)
+		if d.Threshold > 0 && before >= d.Threshold && l.Strength < d.Threshold {
+			l.Inactive = true
+			changed = true
+		}
+	}
+	if d.Closure > 0 && d.close(n) {
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return n.PopulateLinkMaps()
+}
+
+// close gives each Agent the chance to form a Link to one of the Agents related to one of its
+// related Agents, returns true if any Links were formed
+func (d LinkDynamics) close(n RelationshipMgr) bool {
+	r := n.Rand()
+	links := make(map[[2]string]*Link, len(n.Links()))
+	for _, l := range n.Links() {
+		links[linkKey(l.Agent1ID, l.Agent2ID)] = l
+	}
+	formed := false
+	for _, a := range n.Agents() {
+		if r.Float64() >= d.Closure {
+			continue
+		}
+		related := n.GetRelatedAgents(a)
+		if len(related) == 0 {
+			continue
+		}
+		for _, c := range n.GetRelatedAgents(related[0]) {
+			if c.Identifier() == a.Identifier() {
+				continue
+			}
+			key := linkKey(a.Identifier(), c.Identifier())
+			l, exists := links[key]
+			if exists && !l.Inactive {
+				continue
+			}
+			if !exists {
+				n.AddLink(a, c)
+				l = n.Links()[len(n.Links())-1]
+				links[key] = l
+			}
+			l.Inactive = false
+			l.Strength = 1
+			formed = true
+			break
+		}
+	}
+	return formed
+}
+
+// linkKey returns a key identifying the Link between two Agents whichever way round they are
+func linkKey(id1 string, id2 string) [2]string {
+	if id1 > id2 {
+		return [2]string{id2, id1}
+	}
+	return [2]string{id1, id2}
+}
@@ -0,0 +1,85 @@
+package sim
+
+import (
+	"testing"
+)
+
+func TestLinkDynamicsValidate(t *testing.T) {
+	AssertSuccess(t, LinkDynamics{}.Validate())
+	AssertSuccess(t, LinkDynamics{Decay: 0.1, Threshold: 2, Closure: 0.05}.Validate())
+	AreEqual(t, "link decay must be between 0 and 1", LinkDynamics{Decay: 1.5}.Validate().Error(), "Wrong error")
+	AreEqual(t, "link threshold cannot be negative", LinkDynamics{Threshold: -1}.Validate().Error(), "Wrong error")
+	AreEqual(t, "link closure must be between 0 and 1", LinkDynamics{Closure: -0.1}.Validate().Error(), "Wrong error")
+	IsFalse(t, LinkDynamics{Threshold: 2}.Active(), "A threshold alone does not change the network")
+}
+
+func TestLinkDecayDeactivatesWeakenedLinks(t *testing.T) {
+	n, err := NewNetwork(`{"nodes":[{"id":"a"},{"id":"b"},{"id":"c"}],"links":[{"source":"a","target":"b","strength":4},{"source":"a","target":"c","strength":2.5}]}`)
+	AssertSuccess(t, err)
+	d := LinkDynamics{Decay: 0.25, Threshold: 2}
+	AssertSuccess(t, d.Apply(n))
+	AreEqual(t, 3.0, n.Links()[0].Strength, "Strength not decayed")
+	IsFalse(t, n.Links()[0].Inactive, "Link above the threshold deactivated")
+	IsTrue(t, n.Links()[1].Inactive, "Link that fell below the threshold not deactivated")
+	related := n.GetRelatedAgents(n.GetAgentByID("a"))
+	AreEqual(t, 1, len(related), "Inactive link still used")
+	AreEqual(t, "b", related[0].Identifier(), "Wrong related agent")
+
+	AssertSuccess(t, d.Apply(n))
+	AreEqual(t, 2.5*0.75, n.Links()[1].Strength, "Inactive link should not decay")
+
+	unused, err := NewNetwork(`{"nodes":[{"id":"a"},{"id":"b"}],"links":[{"source":"a","target":"b"}]}`)
+	AssertSuccess(t, err)
+	AssertSuccess(t, d.Apply(unused))
+	IsFalse(t, unused.Links()[0].Inactive, "Link that never reached the threshold deactivated")
+}
+
+func TestLinkClosureFormsAndReactivatesLinks(t *testing.T) {
+	n, err := NewNetwork(`{"nodes":[{"id":"a"},{"id":"b"},{"id":"c"}],"links":[{"source":"a","target":"b"},{"source":"b","target":"c"}]}`)
+	AssertSuccess(t, err)
+	n.SetRand(NewRand(3))
+	d := LinkDynamics{Closure: 1}
+	AssertSuccess(t, d.Apply(n))
+	AreEqual(t, 3, len(n.Links()), "Closure did not form a link")
+	l := n.Links()[2]
+	AreEqual(t, [2]string{"a", "c"}, linkKey(l.Agent1ID, l.Agent2ID), "Wrong link formed")
+	AreEqual(t, 1.0, l.Strength, "New link has the wrong strength")
+	AreEqual(t, 2, len(n.GetRelatedAgents(n.GetAgentByID("c"))), "New link not used")
+
+	AssertSuccess(t, d.Apply(n))
+	AreEqual(t, 3, len(n.Links()), "Closure should not duplicate links")
+
+	l.Inactive = true
+	AssertSuccess(t, n.PopulateMaps())
+	AssertSuccess(t, d.Apply(n))
+	AreEqual(t, 3, len(n.Links()), "Closure should reactivate an inactive link")
+	IsFalse(t, l.Inactive, "Inactive link not reactivated")
+}
+
+func TestLinkDynamicsDoNotReinitialiseAgents(t *testing.T) {
+	n, err := NewNetwork(`{"nodes":[{"id":"a"},{"id":"b"},{"id":"c"}],"links":[{"source":"a","target":"b","strength":4},{"source":"a","target":"c","strength":2.5}]}`)
+	AssertSuccess(t, err)
+	b := n.GetAgentByID("b").State()
+	mail := b.Mail
+	mail <- "message"
+	AssertSuccess(t, LinkDynamics{Decay: 0.25, Threshold: 2}.Apply(n))
+	IsTrue(t, n.Links()[1].Inactive, "Link that fell below the threshold not deactivated")
+	IsTrue(t, mail == b.Mail, "Mail channel reallocated when the links changed")
+	AreEqual(t, 1, len(b.Mail), "Mail waiting for the Agent lost when the links changed")
+}
+
+func TestRunnerRecordsActiveLinks(t *testing.T) {
+	s := HierarchySpec{Levels: 3, TeamSize: 3, LinkTeamPeers: true, InitColors: []Color{Grey, Blue}, MaxColors: 2, Seed: 4}
+	n, _, _ := GenerateHierarchy(s)
+	links := len(n.Links())
+	r := NewSeededRunner(n, 20, 7)
+	r.SetLinkDynamics(LinkDynamics{Decay: 0.2, Threshold: 1.5})
+	results := r.Run()
+	AreEqual(t, 20, len(results.Links), "Active links not recorded each iteration")
+	IsTrue(t, results.Links[19] < links, "No links were pruned")
+	AreEqual(t, links, len(n.Links()), "Pruned links should be kept on the network")
+
+	n2, _, _ := GenerateHierarchy(s)
+	results = NewSeededRunner(n2, 20, 7).Run()
+	IsTrue(t, results.Links == nil, "Active links recorded without link dynamics")
+}
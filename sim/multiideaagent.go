@@ -0,0 +1,159 @@
+package sim
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+)
+
+// IdeaHolder is implemented by Agents that can hold several ideas, each represented by a
+// Color, at the same time
+type IdeaHolder interface {
+	HeldIdeas() []Color
+}
+
+// A MultiIdeaAgent holds a set of Ideas at once, for example several compatible practices.
+// When it is influenced by another Agent it adopts one of the Ideas held by that Agent,
+// deciding whether it is influenced in the same way as a plain Agent, and keeps the Ideas it
+// already holds unless they are exclusive with the new one. Each group in Exclusive is a set
+// of Ideas that are exclusive with each other, Ideas that are not in a group together are
+// compatible. The Color of the Agent is the Idea it adopted most recently, or Grey if it holds
+// no Ideas.
+type MultiIdeaAgent struct {
+	AgentState
+	Ideas     []Color   `json:"ideas"`
+	Exclusive [][]Color `json:"exclusive,omitempty"`
+}
+
+func init() {
+	RegisterAgentType("MultiIdeaAgent", func() Agent { return &MultiIdeaAgent{} })
+}
+
+// Initialise ensures the agent is correctly initialised. An Agent that has a Color other than
+// Grey always holds it as an Idea.
+func (a *MultiIdeaAgent) Initialise(n RelationshipMgr) {
+	a.AgentState.Initialise(n)
+	a.Type = reflect.TypeOf(a).Elem().Name()
+	if a.Ideas == nil {
+		a.Ideas = []Color{}
+	}
+	if a.Color != Grey && !a.Holds(a.Color) {
+		a.Ideas = append(a.Ideas, a.Color)
+		sortColors(a.Ideas)
+	}
+}
+
+// SampleTraits sets the groups of exclusive Ideas from the Traits when the Agent is generated
+func (a *MultiIdeaAgent) SampleTraits(r *rand.Rand, t Traits) {
+	a.Exclusive = t.ExclusiveIdeas
+}
+
+// State returns the struct containing the state of this Agent
+func (a *MultiIdeaAgent) State() *AgentState {
+	return &a.AgentState
+}
+
+// HeldIdeas returns the Ideas held by the Agent
+func (a *MultiIdeaAgent) HeldIdeas() []Color {
+	return a.Ideas
+}
+
+// Holds returns true if the Agent holds the passed Idea
+func (a *MultiIdeaAgent) Holds(idea Color) bool {
+	for _, c := range a.Ideas {
+		if c == idea {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadMail checks for any messages it received in its own Mail queue. If it receives one then
+// it decides whether to adopt one of the Ideas held by the sender.
+func (a *MultiIdeaAgent) ReadMail(n RelationshipMgr) Color {
+	msg, received := a.ReceiveMsg()
+	if received {
+		ra := n.GetAgentByID(msg)
+		if ra != nil {
+			sender := *ra.State()
+			sender.Color = talkAbout(n.Rand(), ra)
+			c, reason, update := a.UpdateColor(n, &sender)
+			if update && c != Grey {
+				oldColor := a.Color
+				a.Adopt(c)
+				n.EventLog().Record(a.ID, ra.Identifier(), oldColor, a.Color, reason)
+			}
+		}
+	}
+	return a.Color
+}
+
+// Adopt adds the passed Idea to those held by the Agent and makes it the Agent's Color,
+// dropping any Ideas that are exclusive with it
+func (a *MultiIdeaAgent) Adopt(idea Color) {
+	ideas := []Color{idea}
+	for _, c := range a.Ideas {
+		if c != idea && !a.exclusive(c, idea) {
+			ideas = append(ideas, c)
+		}
+	}
+	sortColors(ideas)
+	a.Ideas = ideas
+	a.SetColor(idea)
+}
+
+// exclusive returns true if the two Ideas are in a group of exclusive Ideas together
+func (a *MultiIdeaAgent) exclusive(c1 Color, c2 Color) bool {
+	for _, group := range a.Exclusive {
+		has1, has2 := false, false
+		for _, c := range group {
+			has1 = has1 || c == c1
+			has2 = has2 || c == c2
+		}
+		if has1 && has2 {
+			return true
+		}
+	}
+	return false
+}
+
+// talkAbout returns the Idea that an Agent talks about in a conversation, which is one of its
+// Ideas chosen at random if it is an IdeaHolder, otherwise its Color
+func talkAbout(r *rand.Rand, a Agent) Color {
+	ih, isIdeaHolder := a.(IdeaHolder)
+	if !isIdeaHolder {
+		return a.GetColor()
+	}
+	ideas := ih.HeldIdeas()
+	if len(ideas) == 0 {
+		return Grey
+	}
+	return ideas[r.Intn(len(ideas))]
+}
+
+// ideaCounts returns the number of Agents holding each Idea. Agents that are not IdeaHolders
+// count as holding their Color, and Grey counts the Agents holding no Ideas.
+func ideaCounts(agents []Agent, maxColors int) []int {
+	counts := make([]int, maxColors)
+	for _, a := range agents {
+		ih, isIdeaHolder := a.(IdeaHolder)
+		if !isIdeaHolder {
+			counts[a.GetColor()]++
+			continue
+		}
+		ideas := ih.HeldIdeas()
+		if len(ideas) == 0 {
+			counts[Grey]++
+		}
+		for _, c := range ideas {
+			if int(c) < maxColors {
+				counts[c]++
+			}
+		}
+	}
+	return counts
+}
+
+func sortColors(colors []Color) {
+	sort.Slice(colors, func(i, j int) bool { return colors[i] < colors[j] })
+}
@@ -0,0 +1,85 @@
+package sim
+
+import (
+	"testing"
+)
+
+func newMultiIdeaAgent(ideas ...Color) *MultiIdeaAgent {
+	a := &MultiIdeaAgent{Ideas: ideas}
+	a.ID = "id_aut"
+	a.Mail = make(chan string, 1)
+	a.Susceptability = 0.4
+	if len(ideas) > 0 {
+		a.Color = ideas[len(ideas)-1]
+	}
+	return a
+}
+
+func TestMultiIdeaAgentAddsCompatibleIdea(t *testing.T) {
+	tn := newTestNetwork()
+	tn.SetEventLog(NewEventLog())
+	aut := newMultiIdeaAgent(Red)
+	aut.PostMsg("id_1")
+	AreEqual(t, Blue, aut.ReadMail(tn), "Color should be the idea adopted most recently")
+	AreEqual(t, 2, len(aut.Ideas), "Compatible idea not added")
+	IsTrue(t, aut.Holds(Blue) && aut.Holds(Red), "Both ideas should be held")
+	AreEqual(t, 1, tn.LinkStrength, "LinkStrength not incremented as part of reading a Mail")
+	AreEqual(t, Influenced, tn.EventLog().Events[0].Reason, "Wrong reason recorded")
+}
+
+func TestMultiIdeaAgentDropsExclusiveIdea(t *testing.T) {
+	tn := newTestNetwork()
+	aut := newMultiIdeaAgent(Red, Green)
+	aut.Exclusive = [][]Color{{Blue, Red}}
+	aut.PostMsg("id_1")
+	aut.ReadMail(tn)
+	AreEqual(t, 2, len(aut.Ideas), "Exclusive idea not dropped")
+	IsTrue(t, aut.Holds(Blue) && aut.Holds(Green), "Compatible idea should be kept")
+	IsFalse(t, aut.Holds(Red), "Exclusive idea should be dropped")
+}
+
+func TestMultiIdeaAgentIgnoresLowInfluence(t *testing.T) {
+	tn := newTestNetwork()
+	aut := newMultiIdeaAgent(Red)
+	aut.Susceptability = 1
+	aut.PostMsg("id_1")
+	AreEqual(t, Red, aut.ReadMail(tn), "Color should not change")
+	AreEqual(t, 1, len(aut.Ideas), "Idea should not be adopted")
+}
+
+func TestMultiIdeaAgentsRecordIdeas(t *testing.T) {
+	traits := Traits{ExclusiveIdeas: [][]Color{{Blue, Red}}}
+	n, _, err := GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 4, InitColors: []Color{Grey, Blue, Red, Green}, MaxColors: 4, AgentType: "MultiIdeaAgent", Traits: &traits, Seed: 1})
+	AssertSuccess(t, err)
+	for _, a := range n.Agents() {
+		ma := a.(*MultiIdeaAgent)
+		AreEqual(t, a.GetColor() != Grey, ma.Holds(a.GetColor()), "Initial color not held as an idea")
+		AreEqual(t, 1, len(ma.Exclusive), "Exclusive ideas not set from the traits")
+	}
+	initial := InitialResults(n)
+	AreEqual(t, 1, len(initial.Ideas), "Initial ideas not recorded")
+	AreEqual(t, initial.Colors[0][Blue], initial.Ideas[0][Blue], "Each agent should start with one idea")
+
+	results := NewSeededRunner(n, 20, 1).Run()
+	AreEqual(t, 20, len(results.Ideas), "Ideas not recorded for every iteration")
+	total := 0
+	for _, c := range results.Ideas[19] {
+		total += c
+	}
+	IsTrue(t, total > len(n.Agents()), "Agents should hold several ideas")
+	for _, a := range n.Agents() {
+		ma := a.(*MultiIdeaAgent)
+		IsFalse(t, ma.Holds(Blue) && ma.Holds(Red), "Exclusive ideas held together")
+	}
+
+	n2, err := NewNetwork(n.Serialise())
+	AssertSuccess(t, err)
+	for i, a := range n2.Agents() {
+		AreEqual(t, len(n.Agents()[i].(*MultiIdeaAgent).Ideas), len(a.(*MultiIdeaAgent).Ideas), "Ideas not unmarshalled")
+	}
+}
+
+func TestExclusiveIdeasValidate(t *testing.T) {
+	traits := Traits{ExclusiveIdeas: [][]Color{{Blue}}}
+	AreEqual(t, "exclusiveIdeas: each group must have at least two ideas", traits.Validate().Error(), "Wrong error")
+}
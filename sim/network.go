@@ -15,6 +15,13 @@ type Network struct {
 	AgentsByID    map[string]Agent                `json:"-"`
 	AgentLinkMap  map[string]map[string]AgentLink `json:"-"`
 	MaxColorCount int                             `json:"maxColors"`
+	rand          *rand.Rand
+	relatedAgents map[string][]Agent
+	shuffled      map[string][]Agent
+	influencers   map[string][]Agent
+	eventLog      *EventLog
+	selection     PartnerSelection
+	hierarchy     HierarchyInfluence
 }
 
 //AgentLink holds both the Link and the Agent in the AgentLinkMap
@@ -26,15 +33,27 @@ type AgentLink struct {
 //RelationshipMgr is an interface for the Network
 type RelationshipMgr interface {
 	GetRelatedAgents(a Agent) []Agent
+	GetInfluencingAgents(a Agent) []Agent
 	GetAgentByID(id string) Agent
 	IncrementLinkStrength(id1 string, id2 string) error
+	GetLink(id1 string, id2 string) *Link
 	AddAgent(a Agent)
 	AddLink(a1 Agent, a2 Agent)
+	AddDirectedLink(a1 Agent, a2 Agent)
 	Agents() []Agent
 	Links() []*Link
 	MaxColors() int
 	SetMaxColors(c int)
 	PopulateMaps() error
+	PopulateLinkMaps() error
+	Rand() *rand.Rand
+	SetRand(r *rand.Rand)
+	EventLog() *EventLog
+	SetEventLog(l *EventLog)
+	PartnerSelection() PartnerSelection
+	SetPartnerSelection(ps PartnerSelection)
+	HierarchyInfluence() HierarchyInfluence
+	SetHierarchyInfluence(h HierarchyInfluence)
 }
 
 //MaxColors returns the maximum number of color states that the agents are permitted on this network
@@ -47,6 +66,52 @@ func (n *Network) SetMaxColors(c int) {
 	n.MaxColorCount = c
 }
 
+//Rand returns the random source used for all random behaviour on this network. If no
+//source has been set a new one seeded from the current time is created
+func (n *Network) Rand() *rand.Rand {
+	if n.rand == nil {
+		n.rand = NewRand(NewSeed())
+	}
+	return n.rand
+}
+
+//SetRand sets the random source used for all random behaviour on this network
+func (n *Network) SetRand(r *rand.Rand) {
+	n.rand = r
+}
+
+//EventLog returns the log that changes of Color are recorded in, returns nil if changes
+//are not being recorded
+func (n *Network) EventLog() *EventLog {
+	return n.eventLog
+}
+
+//SetEventLog sets the log that changes of Color are recorded in, set it to nil to stop
+//recording changes
+func (n *Network) SetEventLog(l *EventLog) {
+	n.eventLog = l
+}
+
+//PartnerSelection returns how Agents choose the related Agent to send their Mail to
+func (n *Network) PartnerSelection() PartnerSelection {
+	return n.selection
+}
+
+//SetPartnerSelection sets how Agents choose the related Agent to send their Mail to
+func (n *Network) SetPartnerSelection(ps PartnerSelection) {
+	n.selection = ps
+}
+
+//HierarchyInfluence returns the multipliers for Influence along and across the reporting lines
+func (n *Network) HierarchyInfluence() HierarchyInfluence {
+	return n.hierarchy
+}
+
+//SetHierarchyInfluence sets the multipliers for Influence along and across the reporting lines
+func (n *Network) SetHierarchyInfluence(h HierarchyInfluence) {
+	n.hierarchy = h
+}
+
 //Agents returns a list of the Agents Communicating on the Network
 func (n *Network) Agents() []Agent {
 	return n.Nodes
@@ -71,13 +136,20 @@ func (n *Network) AddLink(a1 Agent, a2 Agent) {
 	n.Edges = append(n.Edges, &l)
 }
 
+//AddDirectedLink adds a new Link that only carries Mail from the first passed agent to the second
+func (n *Network) AddDirectedLink(a1 Agent, a2 Agent) {
+	n.AddLink(a1, a2)
+	n.Edges[len(n.Edges)-1].Directed = true
+}
+
 //Serialise returns a json representation of the Network
 func (n *Network) Serialise() string {
 	jsonBody, _ := json.Marshal(n)
 	return string(jsonBody)
 }
 
-//UnmarshalJSON implements unmarshalling of Agents of different types
+//UnmarshalJSON implements unmarshalling of Agents of different types. Each Agent is created
+//from the factory registered for its type, and an error is returned if the type is unknown.
 func (n *Network) UnmarshalJSON(b []byte) error {
 	var network map[string]json.RawMessage
 	err := json.Unmarshal(b, &network)
@@ -93,44 +165,61 @@ func (n *Network) UnmarshalJSON(b []byte) error {
 	n.Nodes = make([]Agent, len(nodes))
 
 	for i, raw := range nodes {
-		var fm map[string]interface{}
+		var fm struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		}
 		json.Unmarshal(raw, &fm)
-		switch fm["type"] {
-		case "Agent":
-			a := AgentState{}
-			json.Unmarshal(raw, &a)
-			n.Nodes[i] = &a
-		case "AgentWithMemory":
-			a := AgentWithMemory{}
-			json.Unmarshal(raw, &a)
-			n.Nodes[i] = &a
-		default:
-			a := AgentState{}
-			json.Unmarshal(raw, &a)
-			n.Nodes[i] = &a
+		//Agents saved without a type are plain Agents
+		if fm.Type == "" {
+			fm.Type = "Agent"
 		}
+		a, err := NewAgentOfType(fm.Type)
+		if err != nil {
+			return fmt.Errorf("%w for agent '%s'", err, fm.ID)
+		}
+		json.Unmarshal(raw, a)
+		n.Nodes[i] = a
 	}
 	return nil
 }
 
-// NewNetwork creates a new Network structure from the passed json string
+// NewNetwork creates a new Network structure from the passed json string. An empty Network
+// is returned for an empty string, but an error is returned if an Agent type is unknown.
 func NewNetwork(jsonBody string) (*Network, error) {
 	n := Network{}
-	json.Unmarshal([]byte(jsonBody), &n)
-	err := n.PopulateMaps()
+	err := json.Unmarshal([]byte(jsonBody), &n)
+	if errors.Is(err, ErrUnknownAgentType) {
+		return nil, err
+	}
+	err = n.PopulateMaps()
 	return &n, err
 }
 
-// PopulateMaps creates the map lookups from the Links and Nodes arrays
+// PopulateMaps creates the map lookups from the Links and Nodes arrays and initialises each
+// Agent. The Level of each Agent is set from its Manager.
 func (n *Network) PopulateMaps() error {
-	err := ""
 	n.AgentsByID = make(map[string]Agent, len(n.Nodes))
 	for _, agent := range n.Nodes {
 		n.AgentsByID[agent.Identifier()] = agent
 		agent.Initialise(n)
 	}
+	err := n.PopulateLinkMaps()
+	n.populateLevels()
+	return err
+}
+
+// PopulateLinkMaps creates the map lookups from the Links array only, leaving the Agents and
+// their Levels as they are. It is used when Links are added or deactivated during a run. Each
+// Agent's entry in the AgentLinkMap holds the Agents it can send Mail to, so a Directed Link is
+// only added to the entry of its source Agent.
+func (n *Network) PopulateLinkMaps() error {
+	err := ""
 	n.AgentLinkMap = make(map[string]map[string]AgentLink, len(n.Nodes))
 	for _, link := range n.Edges {
+		if link.Inactive {
+			continue
+		}
 		agent1, exists := n.AgentsByID[link.Agent1ID]
 		if !exists {
 			err = fmt.Sprintf("%sAgent1ID '%s' not found in list of Agents\n", err, link.Agent1ID)
@@ -141,12 +230,19 @@ func (n *Network) PopulateMaps() error {
 			err = fmt.Sprintf("%sAgent2ID '%s' not found in list of Agents\n", err, link.Agent2ID)
 			continue
 		}
+		if link.TrustWeight() < 0 {
+			err = fmt.Sprintf("%sLink '%s' '%s' has a negative trust\n", err, link.Agent1ID, link.Agent2ID)
+			continue
+		}
 		agent1Map, exists := n.AgentLinkMap[link.Agent1ID]
 		if !exists {
 			agent1Map = map[string]AgentLink{}
 			n.AgentLinkMap[link.Agent1ID] = agent1Map
 		}
 		agent1Map[agent2.Identifier()] = AgentLink{agent2, link}
+		if link.Directed {
+			continue
+		}
 		agent2Map, exists := n.AgentLinkMap[link.Agent2ID]
 		if !exists {
 			agent2Map = map[string]AgentLink{}
@@ -154,49 +250,97 @@ func (n *Network) PopulateMaps() error {
 		}
 		agent2Map[agent1.Identifier()] = AgentLink{agent1, link}
 	}
+	n.populateRelatedAgents()
 	if len(err) == 0 {
 		return nil
 	}
 	return errors.New(err)
 }
 
-//GetRelatedAgents returns a slice of Agents adjacent in the Network to the passed Agent
-//The returned slice of Agents is always deliberately shuffled into random order
-func (n *Network) GetRelatedAgents(a Agent) []Agent {
-	lnkdagents := n.AgentLinkMap[a.Identifier()]
-	acnt := len(n.AgentLinkMap[a.Identifier()])
-	keys := make([]float64, acnt)
-	raMap := make(map[float64]Agent, acnt)
-	i := 0
-	for _, agentLink := range lnkdagents {
-		keys[i] = rand.Float64()
-		raMap[keys[i]] = agentLink.Agent
-		i = i + 1
+// populateRelatedAgents creates a slice of related Agents, and a slice of the Agents that can
+// send it Mail, for each Agent from the AgentLinkMap. The Agents in each slice are sorted by
+// Identifier so that the order they are shuffled into by GetRelatedAgents only depends on the
+// random source and not on the iteration order of the map. A second slice of related Agents
+// is allocated for GetRelatedAgents to shuffle.
+func (n *Network) populateRelatedAgents() {
+	n.relatedAgents = make(map[string][]Agent, len(n.AgentLinkMap))
+	n.shuffled = make(map[string][]Agent, len(n.AgentLinkMap))
+	n.influencers = make(map[string][]Agent, len(n.AgentLinkMap))
+	for id, lnkdagents := range n.AgentLinkMap {
+		r := make([]Agent, 0, len(lnkdagents))
+		for _, agentLink := range lnkdagents {
+			r = append(r, agentLink.Agent)
+			target := agentLink.Agent.Identifier()
+			n.influencers[target] = append(n.influencers[target], n.AgentsByID[id])
+		}
+		sortAgents(r)
+		n.relatedAgents[id] = r
+		n.shuffled[id] = make([]Agent, len(r))
 	}
-	sort.Float64s(keys)
-	r := make([]Agent, acnt)
-	for i, key := range keys {
-		r[i] = raMap[key]
+	for _, r := range n.influencers {
+		sortAgents(r)
 	}
+}
+
+// sortAgents sorts the slice of Agents by Identifier
+func sortAgents(r []Agent) {
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Identifier() < r[j].Identifier()
+	})
+}
+
+//GetRelatedAgents returns a slice of Agents adjacent in the Network to the passed Agent that
+//it can send Mail to.
+//The returned slice of Agents is always deliberately shuffled into random order using the
+//Network's random source, weighted by the Strength of the Links if the PartnerSelection
+//mode is StrengthSelection. The Agents are shuffled from the order they are sorted in, so
+//the order returned only depends on the random source and not on earlier calls, which lets a
+//run be reproduced from a saved Network and a seed. To avoid allocating on every call the
+//slice is owned by the Network and is overwritten on the next call for the same Agent, so
+//callers must not modify it or keep hold of it.
+func (n *Network) GetRelatedAgents(a Agent) []Agent {
+	r := n.shuffled[a.Identifier()]
+	copy(r, n.relatedAgents[a.Identifier()])
+	if n.selection.Mode == StrengthSelection {
+		n.strengthShuffle(a, r)
+		return r
+	}
+	shuffleAgents(n.Rand(), r)
 	return r
 }
 
+// GetInfluencingAgents returns the Agents adjacent in the Network to the passed Agent that can
+// send Mail to it, which are all of its related Agents unless some of its Links are Directed.
+// The slice is owned by the Network, so callers must not modify it or keep hold of it.
+func (n *Network) GetInfluencingAgents(a Agent) []Agent {
+	return n.influencers[a.Identifier()]
+}
+
 // GetAgentByID returns a reference to the Agent with the given ID or nil if it doesn't exist
 func (n *Network) GetAgentByID(id string) Agent {
 	return n.AgentsByID[id]
 }
 
 // IncrementLinkStrength updates the strength field of the link connecting Agents id1 and id2.
-// Returns an error if no link is found
+// The link may be Directed either way round. Returns an error if no link is found
 func (n *Network) IncrementLinkStrength(id1 string, id2 string) error {
-	lnkdMap, exists := n.AgentLinkMap[id1]
-	if !exists {
+	link := n.GetLink(id1, id2)
+	if link == nil {
 		return fmt.Errorf("invalid Link id1=%s id2=%s", id1, id2)
 	}
-	agentLink, exists := lnkdMap[id2]
+	link.Strength++
+	return nil
+}
+
+// GetLink returns the active link connecting Agents id1 and id2, which may be Directed either
+// way round, or nil if there isn't one
+func (n *Network) GetLink(id1 string, id2 string) *Link {
+	agentLink, exists := n.AgentLinkMap[id1][id2]
+	if !exists {
+		agentLink, exists = n.AgentLinkMap[id2][id1]
+	}
 	if !exists {
-		return fmt.Errorf("invalid Link id1=%s id2=%s", id1, id2)
+		return nil
 	}
-	agentLink.Link.Strength++
-	return nil
+	return agentLink.Link
 }
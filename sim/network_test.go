@@ -7,9 +7,9 @@ import (
 )
 
 func TestSerialisationOfAgentWithMemory(t *testing.T) {
-	sJSON := `{"links":null,"nodes":[{"id":"id_1","name":"name_1","color":1,"susceptability":0.2,"influence":0.3,"contrariness":0.4,"change":5,"type":"AgentWithMemory","fx":0.1,"fy":0.2}],"maxColors":0}`
+	sJSON := `{"links":null,"nodes":[{"id":"id_1","name":"name_1","color":1,"susceptability":0.2,"influence":0.3,"contrariness":0.4,"change":5,"type":"AgentWithMemory","fx":0.1,"fy":0.2,"previousColors":{"0":0}}],"maxColors":0}`
 	n := Network{}
-	a := AgentWithMemory{AgentState{"id_1", "name_1", 1, 0.2, 0.3, 0.4, nil, 5, "", 0.1, 0.2}, nil, nil, 0}
+	a := AgentWithMemory{AgentState{"id_1", "name_1", 1, 0.2, 0.3, 0.4, nil, 5, "", 0.1, 0.2, "", 0}, nil, nil, 0, 0, 0, 0}
 	a.Initialise(&n)
 	n.Nodes = append(n.Nodes, &a)
 	serJSON := n.Serialise()
@@ -17,7 +17,7 @@ func TestSerialisationOfAgentWithMemory(t *testing.T) {
 }
 
 func TestDeserialisationOfAgentWithMemory(t *testing.T) {
-	sJSON := `{"links":null,"nodes":[{"id":"id_1","name":"name_1","color":1,"susceptability":0.2,"influence":0.3,"contrariness":0.4,"change":5,"type":"AgentWithMemory","fx":8,"fy":9}],"maxColors":0}`
+	sJSON := `{"links":null,"nodes":[{"id":"id_1","name":"name_1","color":1,"susceptability":0.2,"influence":0.3,"contrariness":0.4,"change":5,"type":"AgentWithMemory","fx":8,"fy":9,"previousColors":{"0":0,"2":3},"shortMemory":{"3":7},"longMemorySpan":10,"shortMemorySpan":5,"clock":8}],"maxColors":0}`
 	n, err := NewNetwork(sJSON)
 	AssertSuccess(t, err)
 	serJSON := n.Serialise()
@@ -27,7 +27,7 @@ func TestDeserialisationOfAgentWithMemory(t *testing.T) {
 func TestJsonSerialisationAgent(t *testing.T) {
 	sJSON := `{"links":null,"nodes":[{"id":"id_1","name":"name_1","color":1,"susceptability":0.2,"influence":0.3,"contrariness":0.4,"change":5,"type":"Agent","fx":1.2,"fy":3.4}],"maxColors":0}`
 	n := Network{}
-	n.Nodes = append(n.Nodes, &AgentState{"id_1", "name_1", 1, 0.2, 0.3, 0.4, make(chan string), 5, "Agent", 1.2, 3.4})
+	n.Nodes = append(n.Nodes, &AgentState{"id_1", "name_1", 1, 0.2, 0.3, 0.4, make(chan string), 5, "Agent", 1.2, 3.4, "", 0})
 	serJSON := n.Serialise()
 	AreEqual(t, sJSON, serJSON, "Serialised json is not identical to original json")
 }
@@ -35,7 +35,7 @@ func TestJsonSerialisationAgent(t *testing.T) {
 func TestJsonSerialisationLink(t *testing.T) {
 	sJSON := `{"links":[{"source":"id_1","target":"id_2","strength":4,"length":3}],"nodes":null,"maxColors":0}`
 	n := Network{}
-	n.Edges = append(n.Edges, &Link{"id_1", "id_2", 4, 3.0})
+	n.Edges = append(n.Edges, &Link{"id_1", "id_2", 4, 3.0, false, false, nil})
 	serJSON := n.Serialise()
 	AreEqual(t, sJSON, serJSON, "Serialised json is not identical to original json")
 }
@@ -173,10 +173,49 @@ func TestGetRelatedAgentsReturnsCorrectList(t *testing.T) {
 	}
 }
 
+func TestGetRelatedAgentsOnlyFollowsDirectedLinksFromSource(t *testing.T) {
+	sJSON := `{"nodes":[{"id":"id_1"},{"id":"id_2"},{"id":"id_3"}],"links":[{"source":"id_1","target":"id_2","directed":true},{"source":"id_2","target":"id_3"}]}`
+	n, err := NewNetwork(sJSON)
+	AssertSuccess(t, err)
+	relatedAgents := n.GetRelatedAgents(n.AgentsByID["id_1"])
+	AreEqual(t, 1, len(relatedAgents), "Incorrect number of related Agents to Agent id_1")
+	AreEqual(t, "id_2", relatedAgents[0].Identifier(), "Source of a directed link cannot send to the target")
+	relatedAgents = n.GetRelatedAgents(n.AgentsByID["id_2"])
+	AreEqual(t, 1, len(relatedAgents), "Incorrect number of related Agents to Agent id_2")
+	AreEqual(t, "id_3", relatedAgents[0].Identifier(), "Target of a directed link can send to the source")
+
+	err = n.IncrementLinkStrength("id_2", "id_1")
+	AssertSuccess(t, err)
+	AreEqual(t, 1.0, n.Links()[0].Strength, "Directed link strength not updated by the target")
+	IsTrue(t, strings.Contains(n.Serialise(), `"directed":true`), "Directed flag not serialised")
+}
+
+func TestGetRelatedAgentsOrderOnlyDependsOnRandomSource(t *testing.T) {
+	sJSON := `{"nodes":[{"id":"id_1"},{"id":"id_2"},{"id":"id_3"},{"id":"id_4"},{"id":"id_5"}],"links":[{"source":"id_1","target":"id_2"},{"source":"id_1","target":"id_3"},{"source":"id_4","target":"id_1"},{"source":"id_5","target":"id_1"}]}`
+	n1, err := NewNetwork(sJSON)
+	AssertSuccess(t, err)
+	n2, err := NewNetwork(sJSON)
+	AssertSuccess(t, err)
+	n1.SetRand(NewRand(1))
+	for i := 0; i < 5; i++ {
+		n1.GetRelatedAgents(n1.AgentsByID["id_1"])
+	}
+	n1.SetRand(NewRand(2))
+	n2.SetRand(NewRand(2))
+	for i := 0; i < 5; i++ {
+		r1 := n1.GetRelatedAgents(n1.AgentsByID["id_1"])
+		r2 := n2.GetRelatedAgents(n2.AgentsByID["id_1"])
+		for j := range r1 {
+			AreEqual(t, r2[j].Identifier(), r1[j].Identifier(), "Order of related Agents depends on earlier calls")
+		}
+	}
+}
+
 func TestGetRelatedAgentsReturnDistributedResults(t *testing.T) {
 	sJSON := `{"nodes":[{"id":"id_1"},{"id":"id_2"},{"id":"id_3"},{"id":"id_4"},{"id":"id_5"}],"links":[{"source":"id_1","target":"id_2"},{"source":"id_1","target":"id_3"},{"source":"id_4","target":"id_1"},{"source":"id_5","target":"id_1"}]}`
 	n, err := NewNetwork(sJSON)
 	AssertSuccess(t, err)
+	n.SetRand(NewRand(1))
 	agentCounts := make(map[string]int, 4)
 	iterations := 2000
 	for i := 0; i < iterations; i++ {
@@ -213,12 +252,43 @@ func TestUpdateLinkStrength(t *testing.T) {
 	AssertSuccess(t, err)
 	err = n.IncrementLinkStrength("id_5", "id_1")
 	AssertSuccess(t, err)
-	AreEqual(t, 1, n.AgentLinkMap["id_5"]["id_1"].Link.Strength, "Link strength not updated")
-	AreEqual(t, 1, n.AgentLinkMap["id_1"]["id_5"].Link.Strength, "Link strength not updated")
+	AreEqual(t, 1.0, n.AgentLinkMap["id_5"]["id_1"].Link.Strength, "Link strength not updated")
+	AreEqual(t, 1.0, n.AgentLinkMap["id_1"]["id_5"].Link.Strength, "Link strength not updated")
 	err = n.IncrementLinkStrength("id_1", "id_5")
 	AssertSuccess(t, err)
-	AreEqual(t, 2, n.AgentLinkMap["id_5"]["id_1"].Link.Strength, "Link strength not updated")
-	AreEqual(t, 2, n.AgentLinkMap["id_1"]["id_5"].Link.Strength, "Link strength not updated")
+	AreEqual(t, 2.0, n.AgentLinkMap["id_5"]["id_1"].Link.Strength, "Link strength not updated")
+	AreEqual(t, 2.0, n.AgentLinkMap["id_1"]["id_5"].Link.Strength, "Link strength not updated")
+}
+
+func TestGetLinkReturnsLinkWithTrust(t *testing.T) {
+	sJSON := `{"nodes":[{"id":"id_1"},{"id":"id_2"},{"id":"id_3"}],"links":[{"source":"id_1","target":"id_2","trust":0.25},{"source":"id_2","target":"id_3"}]}`
+	n, err := NewNetwork(sJSON)
+	AssertSuccess(t, err)
+	AreEqual(t, 0.25, n.GetLink("id_2", "id_1").TrustWeight(), "Wrong trust on link")
+	AreEqual(t, 1.0, n.GetLink("id_2", "id_3").TrustWeight(), "Link with no trust should have a weight of 1")
+	AreEqual(t, (*Link)(nil), n.GetLink("id_1", "id_3"), "Expected nil for agents that are not linked")
+	IsTrue(t, strings.Contains(n.Serialise(), `"trust":0.25`), "Trust not serialised")
+
+	_, err = NewNetwork(`{"nodes":[{"id":"id_1"},{"id":"id_2"}],"links":[{"source":"id_1","target":"id_2","trust":-1}]}`)
+	NotEqual(t, nil, err, "Negative trust not reported")
+}
+
+func TestCloneModifyKeepsLinkTrust(t *testing.T) {
+	sJSON := `{"nodes":[{"id":"id_1"},{"id":"id_2"},{"id":"id_3"}],"links":[{"source":"id_1","target":"id_2","trust":0.25},{"source":"id_2","target":"id_3","inactive":true}]}`
+	n, err := NewNetwork(sJSON)
+	AssertSuccess(t, err)
+	o := &NetworkOptions{InitColors: []Color{Grey}, MaxColors: 2, Seed: 1}
+	clone, err := o.CloneModify(n)
+	AssertSuccess(t, err)
+	AreEqual(t, 0.25, clone.GetLink("id_1", "id_2").TrustWeight(), "Trust not kept when cloning")
+	IsTrue(t, clone.Links()[1].Inactive, "Inactive flag not kept when cloning")
+	AreEqual(t, (*Link)(nil), clone.GetLink("id_2", "id_3"), "Inactive link should not be used by the clone")
+	*clone.Links()[0].Trust = 0.5
+	AreEqual(t, 0.25, n.GetLink("id_1", "id_2").TrustWeight(), "Trust shared between the clone and the original")
+
+	clone, err = NewNetwork(clone.(*Network).Serialise())
+	AssertSuccess(t, err)
+	AreEqual(t, 0.5, clone.GetLink("id_1", "id_2").TrustWeight(), "Trust of the clone not serialised")
 }
 
 func TestUpdateLinkStrengthReportsErrorIfIdDoesntExist(t *testing.T) {
@@ -19,24 +19,49 @@ type HierarchySpec struct {
 	EvangelistAgents bool    `json:"evangelistAgents"`
 	LoneEvangelist   bool    `json:"loneEvangelist"`
 	AgentsWithMemory bool    `json:"agentsWithMemory"`
+	Seed             int64   `json:"seed,omitempty"`
+	Traits           *Traits `json:"traits,omitempty"`
+	AgentType        string  `json:"agentType,omitempty"`
+	DirectedLinks    bool    `json:"directedLinks,omitempty"`
 }
 
-// GenerateHierarchy generates a hierarchical network
+// GenerateHierarchy generates a hierarchical network. If a Seed is specified in the
+// HierarchySpec the same network will be generated every time, otherwise the network is
+// generated from a seed derived from the current time. The traits of the Agents are drawn
+// from the Traits in the HierarchySpec, or from DefaultTraits if there are none. The Agents
+// are of the registered AgentType if one is set, otherwise they are Agents with or without
+// memory as set by AgentsWithMemory. If DirectedLinks is set the Links from each parent to its
+// children are Directed, so that managers can talk to their reports but not the other way round.
+// Each Agent other than the root has its parent as its Manager.
 func GenerateHierarchy(s HierarchySpec) (*Network, *NetworkOptions, error) {
+	err := s.validate()
+	if err != nil {
+		return nil, nil, err
+	}
+	seed := s.Seed
+	if seed == 0 {
+		seed = NewSeed()
+	}
+	r := NewRand(seed)
 	n := new(Network)
+	n.SetRand(r)
 	n.MaxColorCount = s.MaxColors
 	nodeCount := new(int)
 	*nodeCount = 1
 	a_id, a_name := generateIDAndName(nodeCount)
-	a := GenerateRandomAgent(a_id, a_name, s.InitColors, s.AgentsWithMemory)
+	a, err := GenerateRandomAgentOfType(r, a_id, a_name, s.InitColors, agentTypeName(s.AgentType, s.AgentsWithMemory), s.Traits)
+	if err != nil {
+		return nil, nil, err
+	}
 	n.AddAgent(a)
 
 	leafTeamCount := int(math.Pow(float64(s.TeamSize), float64(s.TeamLinkLevel-1)))
 	leafTeams := make([][]Agent, 0, leafTeamCount)
 
 	generateChildren(n, a, &leafTeams, nodeCount, 0, s)
-	err := n.PopulateMaps()
+	err = n.PopulateMaps()
 	o := CreateNetworkOptions(s)
+	o.SetRand(r)
 
 	if err != nil {
 		return n, o, err
@@ -71,6 +96,35 @@ func GenerateHierarchy(s HierarchySpec) (*Network, *NetworkOptions, error) {
 	return n, o, err
 }
 
+// validate checks that the Traits can be sampled, and that the teams at the TeamLinkLevel
+// exist and are large enough for the agents picked from them to link teams and to act as
+// evangelists
+func (s HierarchySpec) validate() error {
+	err := s.Traits.Validate()
+	if err != nil {
+		return err
+	}
+	if !s.LinkTeams && !s.EvangelistAgents && !s.LoneEvangelist {
+		return nil
+	}
+	if int(math.Pow(float64(s.TeamSize), float64(s.TeamLinkLevel-1))) == 0 {
+		return nil
+	}
+	if s.TeamLinkLevel >= s.Levels {
+		return fmt.Errorf("teamLinkLevel %d must be less than levels %d", s.TeamLinkLevel, s.Levels)
+	}
+	if s.TeamSize < 1 {
+		return fmt.Errorf("teamSize must be at least 1 to link teams")
+	}
+	if s.EvangelistAgents && s.TeamSize < 4 {
+		return fmt.Errorf("teamSize must be at least 4 for evangelistAgents")
+	}
+	if s.LoneEvangelist && s.TeamSize < 3 {
+		return fmt.Errorf("teamSize must be at least 3 for loneEvangelist")
+	}
+	return nil
+}
+
 func generateChildren(n *Network, parent Agent, leafTeams *[][]Agent, nodeCount *int, level int, s HierarchySpec) {
 	level++
 	if level >= s.Levels {
@@ -80,10 +134,16 @@ func generateChildren(n *Network, parent Agent, leafTeams *[][]Agent, nodeCount
 	peers := make([]Agent, s.TeamSize)
 	for i := 0; i < s.TeamSize; i++ {
 		id, name := generateIDAndName(nodeCount)
-		a := GenerateRandomAgent(id, name, s.InitColors, s.AgentsWithMemory)
+		//The agent type has already been checked when the root agent was generated
+		a, _ := GenerateRandomAgentOfType(n.Rand(), id, name, s.InitColors, agentTypeName(s.AgentType, s.AgentsWithMemory), s.Traits)
+		a.State().Manager = parent.Identifier()
 		peers[i] = a
 		n.AddAgent(a)
-		n.AddLink(parent, a)
+		if s.DirectedLinks {
+			n.AddDirectedLink(parent, a)
+		} else {
+			n.AddLink(parent, a)
+		}
 		generateChildren(n, a, leafTeams, nodeCount, level, s)
 	}
 
@@ -99,32 +159,42 @@ func generateIDAndName(agentCount *int) (string, string) {
 	return id, name
 }
 
-// GenerateRandomAgent creates an Agent with random properties
-func GenerateRandomAgent(id string, name string, initColors []Color, withMemory bool) Agent {
+// GenerateRandomAgent creates an Agent with random properties drawn from the passed random source
+// using the DefaultTraits
+func GenerateRandomAgent(r *rand.Rand, id string, name string, initColors []Color, withMemory bool) Agent {
+	//The built in agent types are always registered
+	a, _ := GenerateRandomAgentOfType(r, id, name, initColors, agentTypeName("", withMemory), nil)
+	return a
+}
+
+// GenerateRandomAgentOfType creates an Agent of the named registered type with properties
+// drawn from the distributions in the passed Traits using the passed random source. If traits
+// is nil, or a trait has no distribution, the DefaultTraits are used. Returns an error if the
+// type has not been registered.
+func GenerateRandomAgentOfType(r *rand.Rand, id string, name string, initColors []Color, agentType string, traits *Traits) (Agent, error) {
+	a, err := NewAgentOfType(agentType)
+	if err != nil {
+		return nil, err
+	}
+	t := traits.withDefaults()
 	as := AgentState{
 		ID:             id,
 		Name:           name,
 		Color:          Grey,
-		Influence:      rand.NormFloat64()*0.25 + 1,
-		Susceptability: rand.NormFloat64()*0.25 + 1,
-		Contrariness:   rand.NormFloat64()*0.15 + 0.7,
+		Influence:      t.Influence.Sample(r),
+		Susceptability: t.Susceptability.Sample(r),
+		Contrariness:   t.Contrariness.Sample(r),
 		Mail:           nil,
 		ChangeCount:    0,
-		Type:           "Agent",
+		Type:           agentType,
 	}
 	if len(initColors) > 0 {
-		as.Color = initColors[rand.Intn(len(initColors))]
-	}
-	if withMemory {
-		a := AgentWithMemory{
-			AgentState:     as,
-			PreviousColors: nil,
-			ShortMemory:    nil,
-			MaxColors:      0,
-		}
-		a.Type = "AgentWithMemory"
-		return &a
+		as.Color = initColors[r.Intn(len(initColors))]
 	}
-
-	return &as
+	*a.State() = as
+	ts, hasTraits := a.(TraitSampler)
+	if hasTraits {
+		ts.SampleTraits(r, t)
+	}
+	return a, nil
 }
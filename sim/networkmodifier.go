@@ -1,6 +1,9 @@
 package sim
 
-import "fmt"
+import (
+	"fmt"
+	"math/rand"
+)
 
 // NetworkOptions contains information about how the network is set up for the simulation
 type NetworkOptions struct {
@@ -11,6 +14,10 @@ type NetworkOptions struct {
 	InitColors       []Color  `json:"initColors"`
 	MaxColors        int      `json:"maxColors"`
 	AgentsWithMemory bool     `json:"agentsWithMemory"`
+	Seed             int64    `json:"seed,omitempty"`
+	Traits           *Traits  `json:"traits,omitempty"`
+	AgentType        string   `json:"agentType,omitempty"`
+	rand             *rand.Rand
 }
 
 // CreateNetworkOptions creates a new network modifier from the passed HierarchySpec
@@ -20,9 +27,30 @@ func CreateNetworkOptions(s HierarchySpec) *NetworkOptions {
 		InitColors:       s.InitColors,
 		MaxColors:        s.MaxColors,
 		AgentsWithMemory: s.AgentsWithMemory,
+		Seed:             s.Seed,
+		Traits:           s.Traits,
+		AgentType:        s.AgentType,
 	}
 }
 
+// Rand returns the random source used when generating new Agents. If no source has been
+// set one is created from the Seed, or from the current time if no Seed is specified.
+func (o *NetworkOptions) Rand() *rand.Rand {
+	if o.rand == nil {
+		seed := o.Seed
+		if seed == 0 {
+			seed = NewSeed()
+		}
+		o.rand = NewRand(seed)
+	}
+	return o.rand
+}
+
+// SetRand sets the random source used when generating new Agents
+func (o *NetworkOptions) SetRand(r *rand.Rand) {
+	o.rand = r
+}
+
 // AddTeamPeerLinks links all Agents related to the same parent node to each other.
 // Turns a strictly hierarchical network in to a more realistic communication network.
 func (o *NetworkOptions) AddTeamPeerLinks(rm RelationshipMgr) error {
@@ -100,7 +128,10 @@ func (o *NetworkOptions) AddLoneEvangelist(rm RelationshipMgr) error {
 		agent := rm.GetAgentByID(o.LoneEvangelist[0])
 		if agent == nil {
 			a_name := fmt.Sprintf("LoneEvangelist %s", o.LoneEvangelist[0])
-			agent = GenerateRandomAgent(o.LoneEvangelist[0], a_name, o.InitColors, o.AgentsWithMemory)
+			agent, err = GenerateRandomAgentOfType(o.Rand(), o.LoneEvangelist[0], a_name, o.InitColors, agentTypeName(o.AgentType, o.AgentsWithMemory), o.Traits)
+			if err != nil {
+				return err
+			}
 			rm.AddAgent(agent)
 			rm.(*Network).PopulateMaps()
 		}
@@ -132,8 +163,15 @@ func addLink(rm RelationshipMgr, id1 string, id2 string) error {
 
 // CloneModify clones the agents and links in the passed RelationshipMgr into a new
 // RelationshipMgr changing the Agent type and initial colors of all Agents on the Network,
-// then it modifies the links as specified in the passed Options struct.
+// then it modifies the links as specified in the passed Options struct. The traits of the new
+// Agents are drawn from the Traits in the Options, or from DefaultTraits if there are none.
+// The new Agents are of the registered AgentType if one is set, otherwise they are Agents
+// with or without memory as set by AgentsWithMemory.
 func (o *NetworkOptions) CloneModify(rm RelationshipMgr) (RelationshipMgr, error) {
+	err := o.Traits.Validate()
+	if err != nil {
+		return nil, err
+	}
 	ret, err := o.cloneNetwork(rm)
 	if err != nil {
 		return ret, err
@@ -172,11 +210,16 @@ func (o *NetworkOptions) ModifyNetwork(rm RelationshipMgr) error {
 }
 
 // cloneNetwork creates a new network and creates copies of the nodes and links in it from the passed network
-// The new Agents will be generated according to the settings in the passed Options struct
+// The new Agents will be generated according to the settings in the passed Options struct. Each new
+// link keeps the direction, trust and whether it is inactive from the link it is copied from.
 func (o *NetworkOptions) cloneNetwork(rm RelationshipMgr) (*Network, error) {
 	ret := &Network{}
 	for _, agent := range rm.Agents() {
-		clone := GenerateRandomAgent(agent.Identifier(), agent.AgentName(), o.InitColors, o.AgentsWithMemory)
+		clone, err := GenerateRandomAgentOfType(o.Rand(), agent.Identifier(), agent.AgentName(), o.InitColors, agentTypeName(o.AgentType, o.AgentsWithMemory), o.Traits)
+		if err != nil {
+			return nil, err
+		}
+		clone.State().Manager = agent.State().Manager
 		ret.AddAgent(clone)
 	}
 	ret.PopulateMaps()
@@ -185,6 +228,13 @@ func (o *NetworkOptions) cloneNetwork(rm RelationshipMgr) (*Network, error) {
 		if err != nil {
 			return nil, err
 		}
+		clone := ret.Edges[len(ret.Edges)-1]
+		clone.Directed = link.Directed
+		clone.Inactive = link.Inactive
+		if link.Trust != nil {
+			trust := *link.Trust
+			clone.Trust = &trust
+		}
 	}
 	ret.PopulateMaps()
 	return ret, nil
@@ -21,12 +21,17 @@ import (
 // If no regex is supplied for the parent and identifier columns then a default is applied
 // which will strip leading and trailing whitespace.
 // Delimiter is the delimiter to use when slicing rows into columns
+// Directed makes each parsed Link Directed from the Agent in the Parent column to the Agent
+// in the Identifier column
+// Weight provides the index of the column to use as the Trust of each Link parsed by ParseEdges
 type ParseOptions struct {
 	Identifier int               `json:"identifier"`
 	Parent     int               `json:"parent"`
 	Name       int               `json:"name"`
 	Regex      map[string]string `json:"regex"`
 	Delimiter  string            `json:"delimiter"`
+	Directed   bool              `json:"directed,omitempty"`
+	Weight     int               `json:"weight,omitempty"`
 }
 
 // IdentifierRegex returns the Regexp that must be applied to the Identifier column
@@ -72,7 +77,7 @@ func whitespace() *regexp.Regexp {
 // ParseDelim takes a hierarchy expressed in a comma separated file and generates a Network out of it
 // po are the ParseOptions that control how the parse will operate.
 // returns a RelationshipMgr containing all the Agents with links to parent Agents as described in the input
-// data. If the same Id is listed in multiple rows as specified after any regular expressions is applied the
+// data, with each parent set as the Manager of its children. If the same Id is listed in multiple rows as specified after any regular expressions is applied the
 // first row is used and subsequent rows are ignored.
 func (po *ParseOptions) ParseDelim(data []string) (RelationshipMgr, error) {
 	ws := whitespace()
@@ -121,7 +126,7 @@ func (po *ParseOptions) ParseDelim(data []string) (RelationshipMgr, error) {
 			nre, _ := po.GetColRegex(po.Name)
 			name = nre.ReplaceAllString(cols[po.Name], "$1")
 		}
-		a := GenerateRandomAgent(id, name, []Color{}, false)
+		a := GenerateRandomAgent(n.Rand(), id, name, []Color{}, false)
 		n.AddAgent(a)
 		agents[id] = a
 		if !ws.MatchString(idParent) {
@@ -145,7 +150,8 @@ func (po *ParseOptions) ParseDelim(data []string) (RelationshipMgr, error) {
 			if err != nil {
 				return nil, err
 			}
-			n.AddLink(agent1, agent2)
+			agent2.State().Manager = id1
+			po.addLink(&n, agent1, agent2)
 		}
 	}
 
@@ -161,6 +167,9 @@ func (po *ParseOptions) ParseDelim(data []string) (RelationshipMgr, error) {
 // The edges are expected to be in the form of a list of pairs of IDs. Unlike ParseDelim this function
 // will not add any Agents that are not already in the network. It will also ignore any edges that are
 // not between two Agents that are already in the network.
+// If a Weight column is set the value in it is used as the Trust of the link. An edge with a weight
+// between two Agents that are already linked sets the Trust of the existing link rather than adding
+// another. Returns an error if a weight is not a number or is negative.
 func (po *ParseOptions) ParseEdges(edges []string, n RelationshipMgr) (RelationshipMgr, error) {
 	ws := whitespace()
 	idre := po.IdentifierRegex()
@@ -168,7 +177,7 @@ func (po *ParseOptions) ParseEdges(edges []string, n RelationshipMgr) (Relations
 
 	//Using a map of maps here because the links can be duplicated in the source data
 	//and this will allow me to ignore the duplicates
-	links := map[string]map[string]struct{}{}
+	links := map[string]map[string]*float64{}
 
 	for i := 1; i < len(edges); i++ {
 		cols := strings.Split(edges[i], po.Delimiter)
@@ -200,28 +209,45 @@ func (po *ParseOptions) ParseEdges(edges []string, n RelationshipMgr) (Relations
 			continue
 		}
 
+		var trust *float64
+		if po.Weight > 0 && po.Weight < len(cols) {
+			w, err := strconv.ParseFloat(strings.TrimSpace(cols[po.Weight]), 64)
+			if err != nil || w < 0 {
+				return nil, fmt.Errorf("invalid weight '%s' for link from '%s' to '%s'", cols[po.Weight], idParent, id)
+			}
+			trust = &w
+		}
+
 		if id != idParent {
 			id1entry, exists := links[idParent]
 			if exists {
 				_, exists := id1entry[id]
 				if !exists {
-					id1entry[id] = struct{}{}
+					id1entry[id] = trust
 				}
 			} else {
-				links[idParent] = map[string]struct{}{id: {}}
+				links[idParent] = map[string]*float64{id: trust}
 			}
 		}
 	}
 
 	for id1, id1Entries := range links {
-		for id2 := range id1Entries {
+		for id2, trust := range id1Entries {
 			agent1 := n.GetAgentByID(id1)
 			agent2 := n.GetAgentByID(id2)
 			if agent1 == nil || agent2 == nil {
 				return nil, fmt.Errorf("Agent id1 '%s' or id2 '%s' not found when adding link", id1, id2)
 			}
 
-			n.AddLink(agent1, agent2)
+			if trust != nil {
+				link := n.GetLink(id1, id2)
+				if link != nil {
+					link.Trust = trust
+					continue
+				}
+			}
+			po.addLink(n, agent1, agent2)
+			n.Links()[len(n.Links())-1].Trust = trust
 		}
 	}
 
@@ -229,6 +255,15 @@ func (po *ParseOptions) ParseEdges(edges []string, n RelationshipMgr) (Relations
 	return n, err
 }
 
+// addLink adds a Link from agent1 to agent2 that is Directed if the ParseOptions are Directed
+func (po *ParseOptions) addLink(n RelationshipMgr, agent1 Agent, agent2 Agent) {
+	if po.Directed {
+		n.AddDirectedLink(agent1, agent2)
+		return
+	}
+	n.AddLink(agent1, agent2)
+}
+
 // Convenience method to get Agents and check they exist
 func getAgents(agents map[string]Agent, id1 string, id2 string) (Agent, Agent, error) {
 	agent1, exists := agents[id1]
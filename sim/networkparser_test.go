@@ -180,10 +180,10 @@ func TestParseEdgesCsv(t *testing.T) {
 		Parent:     1,
 	}
 	n := Network{}
-	n.AddAgent(GenerateRandomAgent("my_id", "An agent", []Color{}, false))
-	n.AddAgent(GenerateRandomAgent("my_parent", "Parent agent", []Color{}, false))
-	n.AddAgent(GenerateRandomAgent("Child", "Another agent", []Color{}, false))
-	n.AddAgent(GenerateRandomAgent("Parent", "Another parent agent", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "my_id", "An agent", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "my_parent", "Parent agent", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "Child", "Another agent", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "Parent", "Another parent agent", []Color{}, false))
 	n.PopulateMaps()
 
 	rm, err := po.ParseEdges(data, &n)
@@ -203,6 +203,61 @@ func TestParseEdgesCsv(t *testing.T) {
 	}
 }
 
+func TestParseEdgesAddsDirectedLinksFromParent(t *testing.T) {
+	data := []string{
+		"Child,Parent",
+		"my_id,my_parent",
+	}
+	po := ParseOptions{
+		Delimiter:  ",",
+		Identifier: 0,
+		Parent:     1,
+		Directed:   true,
+	}
+	n := Network{}
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "my_id", "An agent", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "my_parent", "Parent agent", []Color{}, false))
+	n.PopulateMaps()
+
+	rm, err := po.ParseEdges(data, &n)
+	AssertSuccess(t, err)
+	AreEqual(t, 1, len(rm.Links()), "Wrong number of links parsed from source data")
+	IsTrue(t, rm.Links()[0].Directed, "Parsed link not directed")
+	AreEqual(t, "my_parent", rm.Links()[0].Agent1ID, "Directed link should start at the parent")
+	AreEqual(t, 0, len(rm.GetRelatedAgents(rm.GetAgentByID("my_id"))), "Child can send to the parent")
+}
+
+func TestParseEdgesSetsTrustFromWeightColumn(t *testing.T) {
+	data := []string{
+		"Child,Parent,Weight",
+		"my_id,my_parent, 0.5",
+		"Child,Parent,2",
+	}
+	po := ParseOptions{
+		Delimiter:  ",",
+		Identifier: 0,
+		Parent:     1,
+		Weight:     2,
+	}
+	n := Network{}
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "my_id", "An agent", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "my_parent", "Parent agent", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "Child", "Another agent", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "Parent", "Another parent agent", []Color{}, false))
+	n.PopulateMaps()
+	n.AddLink(n.GetAgentByID("Child"), n.GetAgentByID("Parent"))
+	n.PopulateMaps()
+
+	rm, err := po.ParseEdges(data, &n)
+	AssertSuccess(t, err)
+	AreEqual(t, 2, len(rm.Links()), "Weighted edge between linked agents should not add a link")
+	AreEqual(t, 0.5, rm.GetLink("my_id", "my_parent").TrustWeight(), "Trust not parsed from weight column")
+	AreEqual(t, 2.0, rm.GetLink("Child", "Parent").TrustWeight(), "Trust of existing link not updated")
+
+	_, err = po.ParseEdges([]string{"Child,Parent,Weight", "my_id,my_parent,high"}, &n)
+	AreEqual(t, "invalid weight 'high' for link from 'my_parent' to 'my_id'", err.Error(), "Wrong error for invalid weight")
+}
+
 func TestParseEdgesThrowsIgnoresWhenAgentDoesntExist(t *testing.T) {
 	data := []string{
 		"Header row is always skipped ,check_this_is_not_an_Id,,",
@@ -214,8 +269,8 @@ func TestParseEdgesThrowsIgnoresWhenAgentDoesntExist(t *testing.T) {
 		Parent:     1,
 	}
 	n := Network{}
-	n.AddAgent(GenerateRandomAgent("my_id", "An agent", []Color{}, false))
-	n.AddAgent(GenerateRandomAgent("Child", "Another agent", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "my_id", "An agent", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "Child", "Another agent", []Color{}, false))
 	n.PopulateMaps()
 
 	_, err := po.ParseEdges(data, &n)
@@ -237,10 +292,10 @@ func TestParseEdgesAddsMultipleParentLinks(t *testing.T) {
 	}
 
 	n := Network{}
-	n.AddAgent(GenerateRandomAgent("1", "agent 1", []Color{}, false))
-	n.AddAgent(GenerateRandomAgent("1349", "agent 2", []Color{}, false))
-	n.AddAgent(GenerateRandomAgent("35", "agent 4", []Color{}, false))
-	n.AddAgent(GenerateRandomAgent("16", "agent 5", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "1", "agent 1", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "1349", "agent 2", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "35", "agent 4", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "16", "agent 5", []Color{}, false))
 	n.PopulateMaps()
 
 	rm, err := po.ParseEdges(data, &n)
@@ -278,10 +333,10 @@ func TestParseEdgesOnlyIncludesLinksWithOtherMatchingColumn(t *testing.T) {
 	}
 
 	n := Network{}
-	n.AddAgent(GenerateRandomAgent("1", "agent 1", []Color{}, false))
-	n.AddAgent(GenerateRandomAgent("1349", "agent 2", []Color{}, false))
-	n.AddAgent(GenerateRandomAgent("248", "agent 3", []Color{}, false))
-	n.AddAgent(GenerateRandomAgent("35", "agent 4", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "1", "agent 1", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "1349", "agent 2", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "248", "agent 3", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "35", "agent 4", []Color{}, false))
 	n.PopulateMaps()
 
 	rm, err := po.ParseEdges(data, &n)
@@ -311,11 +366,11 @@ func TestParseEdgesOnlyIncludesLinksWithOtherMatchingColumn2(t *testing.T) {
 	}
 
 	n := Network{}
-	n.AddAgent(GenerateRandomAgent("1", "agent 1", []Color{}, false))
-	n.AddAgent(GenerateRandomAgent("1349", "agent 2", []Color{}, false))
-	n.AddAgent(GenerateRandomAgent("248", "agent 3", []Color{}, false))
-	n.AddAgent(GenerateRandomAgent("35", "agent 4", []Color{}, false))
-	n.AddAgent(GenerateRandomAgent("351", "agent 5", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "1", "agent 1", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "1349", "agent 2", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "248", "agent 3", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "35", "agent 4", []Color{}, false))
+	n.AddAgent(GenerateRandomAgent(n.Rand(), "351", "agent 5", []Color{}, false))
 	n.PopulateMaps()
 
 	rm, err := po.ParseEdges(data, &n)
@@ -0,0 +1,168 @@
+package sim
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+)
+
+// OpinionBins is the number of equal width bins between 0 and 1 that the opinions of the
+// Agents are counted in when they are recorded in the Results of a run
+const OpinionBins = 10
+
+// Opinionated is implemented by Agents that hold a continuous opinion between 0 and 1
+type Opinionated interface {
+	GetOpinion() float64
+}
+
+// An OpinionAgent holds a continuous Opinion between 0 and 1 and follows the bounded
+// confidence model of Deffuant et al. When it hears from another Opinionated Agent it only
+// moves its Opinion towards the sender's if the two are no further apart than its Confidence,
+// moving by Convergence times the difference. If Averaging is set it follows the model of
+// Hegselmann and Krause instead, moving to the mean Opinion of itself and all the neighbours
+// that can send it Mail within its Confidence. The Color of the Agent is always derived from its Opinion. Bands
+// holds the ascending upper bounds of the Opinions for each Color in turn starting from Grey,
+// the last Color holding the Opinions above the last bound. If there are no Bands the range
+// of Opinions is divided equally between the Colors permitted on the network.
+type OpinionAgent struct {
+	AgentState
+	Opinion     float64   `json:"opinion"`
+	Confidence  float64   `json:"confidence"`
+	Convergence float64   `json:"convergence"`
+	Averaging   bool      `json:"averaging,omitempty"`
+	Bands       []float64 `json:"bands,omitempty"`
+	maxColors   int
+}
+
+func init() {
+	RegisterAgentType("OpinionAgent", func() Agent { return &OpinionAgent{} })
+}
+
+// Initialise ensures the agent is correctly initialised and that its Color matches its Opinion
+func (a *OpinionAgent) Initialise(n RelationshipMgr) {
+	a.AgentState.Initialise(n)
+	a.Type = reflect.TypeOf(a).Elem().Name()
+	a.maxColors = n.MaxColors()
+	a.Color = a.band()
+}
+
+// SampleTraits draws the Opinion, Confidence and Convergence of the Agent from the Traits
+// when it is generated. The Opinion is limited to between 0 and 1.
+func (a *OpinionAgent) SampleTraits(r *rand.Rand, t Traits) {
+	a.Opinion = math.Min(1, math.Max(0, t.Opinion.Sample(r)))
+	a.Confidence = t.Confidence.Sample(r)
+	a.Convergence = t.Convergence.Sample(r)
+	a.Averaging = t.OpinionAveraging
+	a.Bands = t.OpinionBands
+}
+
+// State returns the struct containing the state of this Agent
+func (a *OpinionAgent) State() *AgentState {
+	return &a.AgentState
+}
+
+// GetOpinion returns the Opinion of the Agent
+func (a *OpinionAgent) GetOpinion() float64 {
+	return a.Opinion
+}
+
+// ReadMail checks for any messages it received in its own Mail queue. If it receives one from
+// an Opinionated Agent then it updates its Opinion, and its Color if its Opinion has moved
+// into a different band.
+func (a *OpinionAgent) ReadMail(n RelationshipMgr) Color {
+	msg, received := a.ReceiveMsg()
+	if received {
+		ra := n.GetAgentByID(msg)
+		ro, isOpinionated := ra.(Opinionated)
+		if isOpinionated {
+			n.IncrementLinkStrength(a.ID, ra.Identifier())
+			if a.UpdateOpinion(n, ro) {
+				oldColor := a.Color
+				c := a.band()
+				if c != oldColor {
+					a.SetColor(c)
+					n.EventLog().Record(a.ID, ra.Identifier(), oldColor, a.Color, OpinionShift)
+				}
+			}
+		}
+	}
+	return a.Color
+}
+
+// UpdateOpinion moves the Opinion of the Agent following a conversation with the passed
+// Agent, and returns true if the Opinion changed
+func (a *OpinionAgent) UpdateOpinion(n RelationshipMgr, ra Opinionated) bool {
+	if math.Abs(ra.GetOpinion()-a.Opinion) > a.Confidence {
+		return false
+	}
+	old := a.Opinion
+	if a.Averaging {
+		sum, count := a.Opinion, 1
+		for _, r := range n.GetInfluencingAgents(a) {
+			ro, isOpinionated := r.(Opinionated)
+			if isOpinionated && math.Abs(ro.GetOpinion()-a.Opinion) <= a.Confidence {
+				sum += ro.GetOpinion()
+				count++
+			}
+		}
+		a.Opinion = sum / float64(count)
+	} else {
+		a.Opinion += a.Convergence * (ra.GetOpinion() - a.Opinion)
+	}
+	return a.Opinion != old
+}
+
+// MoveToBand moves the Opinion of the Agent to the middle of the band of Opinions for the passed
+// Color and sets the Color from it, so that the Color is kept when it is next derived from the
+// Opinion. A Color above the last band moves the Opinion into the last band.
+func (a *OpinionAgent) MoveToBand(c Color) {
+	lo, hi := 0.0, 1.0
+	if len(a.Bands) > 0 {
+		i := int(c)
+		if i > len(a.Bands) {
+			i = len(a.Bands)
+		}
+		if i > 0 {
+			lo = a.Bands[i-1]
+		}
+		if i < len(a.Bands) {
+			hi = a.Bands[i]
+		}
+	} else if a.maxColors > 0 {
+		lo = float64(c) / float64(a.maxColors)
+		hi = float64(c+1) / float64(a.maxColors)
+	}
+	a.Opinion = math.Min(1, math.Max(0, (lo+hi)/2))
+	a.SetColor(a.band())
+}
+
+// band returns the Color of the band the Opinion of the Agent falls in
+func (a *OpinionAgent) band() Color {
+	c := 0
+	if len(a.Bands) > 0 {
+		for c < len(a.Bands) && a.Opinion > a.Bands[c] {
+			c++
+		}
+	} else {
+		c = int(a.Opinion * float64(a.maxColors))
+	}
+	if c >= a.maxColors {
+		c = a.maxColors - 1
+	}
+	if c < 0 {
+		c = 0
+	}
+	return Color(c)
+}
+
+// opinionBin returns the index of the bin the passed opinion is counted in
+func opinionBin(opinion float64) int {
+	b := int(opinion * OpinionBins)
+	if b >= OpinionBins {
+		b = OpinionBins - 1
+	}
+	if b < 0 {
+		b = 0
+	}
+	return b
+}
@@ -0,0 +1,111 @@
+package sim
+
+import (
+	"testing"
+)
+
+func newOpinionAgent(id string, opinion float64) *OpinionAgent {
+	a := &OpinionAgent{Opinion: opinion, Confidence: 0.2, Convergence: 0.5, maxColors: 4}
+	a.ID = id
+	a.Mail = make(chan string, 1)
+	a.Color = a.band()
+	return a
+}
+
+func newOpinionTestNetwork(opinions ...float64) *testNetwork {
+	tn := testNetwork{}
+	tn.relatedAgents = []Agent{}
+	tn.agentByID = map[string]Agent{}
+	for i, o := range opinions {
+		tn.AddAgent(newOpinionAgent(string(rune('a'+i)), o))
+	}
+	return &tn
+}
+
+func TestOpinionAgentMovesTowardsCloseOpinion(t *testing.T) {
+	tn := newOpinionTestNetwork(0.6)
+	tn.SetEventLog(NewEventLog())
+	aut := newOpinionAgent("id_aut", 0.45)
+	AreEqual(t, Blue, aut.Color, "Opinion in the wrong band")
+	aut.PostMsg("a")
+	AreEqual(t, Red, aut.ReadMail(tn), "Color should follow the opinion into the next band")
+	AreEqual(t, 0.525, aut.Opinion, "Opinion should move half way to the sender's")
+	AreEqual(t, 1, tn.LinkStrength, "LinkStrength not incremented as part of reading a Mail")
+	AreEqual(t, OpinionShift, tn.EventLog().Events[0].Reason, "Wrong reason recorded")
+}
+
+func TestOpinionAgentIgnoresDistantOpinion(t *testing.T) {
+	tn := newOpinionTestNetwork(0.9)
+	aut := newOpinionAgent("id_aut", 0.45)
+	aut.PostMsg("a")
+	AreEqual(t, Blue, aut.ReadMail(tn), "Color should not change")
+	AreEqual(t, 0.45, aut.Opinion, "Opinion outside the confidence bound should be ignored")
+}
+
+func TestOpinionAgentAveragesNeighboursWithinConfidence(t *testing.T) {
+	tn := newOpinionTestNetwork(0.5, 0.6, 0.9)
+	aut := newOpinionAgent("id_aut", 0.4)
+	aut.Averaging = true
+	aut.PostMsg("a")
+	aut.ReadMail(tn)
+	AreEqual(t, 0.5, aut.Opinion, "Opinion should be the mean of the neighbours within the bound")
+}
+
+func TestOpinionAgentColorFromBands(t *testing.T) {
+	aut := newOpinionAgent("id_aut", 0.3)
+	aut.Bands = []float64{0.25, 0.75}
+	AreEqual(t, Blue, aut.band(), "Wrong band")
+	aut.Opinion = 0.25
+	AreEqual(t, Grey, aut.band(), "A bound should be in the band below it")
+	aut.Opinion = 1
+	AreEqual(t, Red, aut.band(), "Wrong band above the last bound")
+	aut.Bands = []float64{0.1, 0.2, 0.3, 0.4, 0.5}
+	AreEqual(t, Green, aut.band(), "Band beyond the permitted colors should use the last color")
+	AreEqual(t, 0, opinionBin(0), "Wrong bin")
+	AreEqual(t, 9, opinionBin(1), "Wrong bin")
+}
+
+func TestOpinionAgentsRecordOpinions(t *testing.T) {
+	traits := Traits{OpinionBands: []float64{0.5}}
+	n, _, err := GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 4, InitColors: []Color{Grey}, MaxColors: 2, AgentType: "OpinionAgent", Traits: &traits, Seed: 1})
+	AssertSuccess(t, err)
+	for _, a := range n.Agents() {
+		oa := a.(*OpinionAgent)
+		IsTrue(t, oa.Opinion >= 0 && oa.Opinion <= 1, "Opinion out of range")
+		AreEqual(t, 0.2, oa.Confidence, "Default confidence not used")
+		AreEqual(t, oa.Opinion > 0.5, oa.Color == Blue, "Color not derived from the opinion")
+	}
+	n2, err := NewNetwork(n.Serialise())
+	AssertSuccess(t, err)
+	oa := n2.Agents()[0].(*OpinionAgent)
+	AreEqual(t, n.Agents()[0].(*OpinionAgent).Opinion, oa.Opinion, "Opinion not unmarshalled")
+	AreEqual(t, 1, len(oa.Bands), "Bands not unmarshalled")
+
+	results := NewSeededRunner(n, 10, 1).Run()
+	AreEqual(t, 10, len(results.Opinions), "Opinions not recorded for every iteration")
+	total := 0
+	for _, c := range results.Opinions[9] {
+		total += c
+	}
+	AreEqual(t, len(n.Agents()), total, "Every agent's opinion should be counted")
+
+	n3, _, _ := GenerateHierarchy(HierarchySpec{Levels: 2, TeamSize: 2, InitColors: []Color{Grey}, MaxColors: 2, Seed: 1})
+	results = NewSeededRunner(n3, 2, 1).Run()
+	IsTrue(t, results.Opinions == nil, "Opinions recorded without opinionated agents")
+}
+
+func TestOpinionBandsValidate(t *testing.T) {
+	traits := Traits{OpinionBands: []float64{0.5, 0.4}}
+	AreEqual(t, "opinionBands: bands must be in ascending order between 0 and 1", traits.Validate().Error(), "Wrong error")
+}
+
+func TestOpinionAgentAveragesNeighboursAlongDirectedLinks(t *testing.T) {
+	n, err := NewNetwork(`{"maxColors":4,"nodes":[` +
+		`{"id":"a","type":"OpinionAgent","opinion":0.5,"confidence":0.2},` +
+		`{"id":"b","type":"OpinionAgent","opinion":0.4,"confidence":0.2,"averaging":true}],` +
+		`"links":[{"source":"a","target":"b","directed":true}]}`)
+	AssertSuccess(t, err)
+	NewSeededRunner(n, 1, 1).Run()
+	AreEqual(t, 0.45, n.GetAgentByID("b").(*OpinionAgent).Opinion, "Opinion should be averaged with the source of the directed link")
+	AreEqual(t, 0.5, n.GetAgentByID("a").(*OpinionAgent).Opinion, "Source of a directed link should not be influenced")
+}
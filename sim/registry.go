@@ -0,0 +1,80 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// AgentFactory creates a new, empty Agent of a registered type. The Agent is populated by
+// unmarshalling it from json, or by setting its AgentState when a random Agent is generated.
+type AgentFactory func() Agent
+
+// ErrUnknownAgentType is returned when an Agent type has not been registered
+var ErrUnknownAgentType = errors.New("unknown agent type")
+
+var agentTypes = struct {
+	sync.RWMutex
+	factories map[string]AgentFactory
+}{factories: map[string]AgentFactory{}}
+
+// The built in Agent types. Plain Agents are generated with the type "Agent" but record
+// their type as "AgentState" once they have been initialised, so both names are registered.
+func init() {
+	RegisterAgentType("Agent", func() Agent { return &AgentState{} })
+	RegisterAgentType("AgentState", func() Agent { return &AgentState{} })
+	RegisterAgentType("AgentWithMemory", func() Agent { return &AgentWithMemory{} })
+}
+
+// RegisterAgentType makes an Agent type available to networks under the passed name, which
+// should match the type recorded in the AgentState of the Agents it creates once they have
+// been initialised. It is intended to be called from the init function of the package that
+// implements the Agent, so it panics if the name is already registered or the factory is nil.
+func RegisterAgentType(name string, factory AgentFactory) {
+	agentTypes.Lock()
+	defer agentTypes.Unlock()
+	if factory == nil {
+		panic("sim: RegisterAgentType factory is nil for " + name)
+	}
+	if _, exists := agentTypes.factories[name]; exists {
+		panic("sim: RegisterAgentType called twice for " + name)
+	}
+	agentTypes.factories[name] = factory
+}
+
+// NewAgentOfType returns a new, empty Agent of the named type, or ErrUnknownAgentType if no
+// type has been registered with the name
+func NewAgentOfType(name string) (Agent, error) {
+	agentTypes.RLock()
+	factory, exists := agentTypes.factories[name]
+	agentTypes.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownAgentType, name)
+	}
+	return factory(), nil
+}
+
+// AgentTypes returns the names of all the registered Agent types in alphabetical order
+func AgentTypes() []string {
+	agentTypes.RLock()
+	defer agentTypes.RUnlock()
+	names := make([]string, 0, len(agentTypes.factories))
+	for name := range agentTypes.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// agentTypeName returns the name of the Agent type to generate, which is the passed name if
+// one is given, otherwise the built in type with or without memory
+func agentTypeName(name string, withMemory bool) string {
+	if name != "" {
+		return name
+	}
+	if withMemory {
+		return "AgentWithMemory"
+	}
+	return "Agent"
+}
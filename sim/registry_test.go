@@ -0,0 +1,83 @@
+package sim
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// registryTestAgent is an Agent type registered by the tests
+type registryTestAgent struct {
+	AgentState
+	Note string `json:"note"`
+}
+
+func (a *registryTestAgent) Initialise(n RelationshipMgr) {
+	a.AgentState.Initialise(n)
+	a.Type = reflect.TypeOf(a).Elem().Name()
+}
+
+func init() {
+	RegisterAgentType("registryTestAgent", func() Agent { return &registryTestAgent{} })
+}
+
+func TestBuiltInAgentTypesRegistered(t *testing.T) {
+	types := AgentTypes()
+	for _, name := range []string{"Agent", "AgentState", "AgentWithMemory", "registryTestAgent"} {
+		_, err := NewAgentOfType(name)
+		AssertSuccess(t, err)
+	}
+	IsTrue(t, len(types) >= 4, "Registered types not listed")
+	_, isMem := GenerateRandomAgent(NewRand(1), "a", "a", nil, true).(*AgentWithMemory)
+	IsTrue(t, isMem, "Agent with memory not generated")
+}
+
+func TestRegisterAgentTypeTwicePanics(t *testing.T) {
+	defer func() {
+		IsTrue(t, recover() != nil, "Registering a type twice did not panic")
+	}()
+	RegisterAgentType("Agent", func() Agent { return &AgentState{} })
+}
+
+func TestUnknownAgentTypeFails(t *testing.T) {
+	_, err := NewNetwork(`{"nodes":[{"id":"id_1","type":"Martian"}],"links":[]}`)
+	IsTrue(t, errors.Is(err, ErrUnknownAgentType), "Expected an unknown agent type error")
+	AreEqual(t, "unknown agent type 'Martian' for agent 'id_1'", err.Error(), "Wrong error")
+	_, err = GenerateRandomAgentOfType(NewRand(1), "a", "a", nil, "Martian", nil)
+	AreEqual(t, "unknown agent type 'Martian'", err.Error(), "Wrong error")
+	_, _, err = GenerateHierarchy(HierarchySpec{Levels: 2, TeamSize: 2, AgentType: "Martian", Seed: 1})
+	AreEqual(t, "unknown agent type 'Martian'", err.Error(), "Wrong error")
+}
+
+func TestAgentWithoutTypeIsPlainAgent(t *testing.T) {
+	n, err := NewNetwork(`{"nodes":[{"id":"id_1"}],"links":[]}`)
+	AssertSuccess(t, err)
+	_, isAgent := n.Agents()[0].(*AgentState)
+	IsTrue(t, isAgent, "Agent without a type not created as a plain Agent")
+}
+
+func TestRegisteredAgentTypeRoundTrips(t *testing.T) {
+	n, _, err := GenerateHierarchy(HierarchySpec{Levels: 2, TeamSize: 2, InitColors: []Color{Grey}, MaxColors: 2, AgentType: "registryTestAgent", Seed: 1})
+	AssertSuccess(t, err)
+	for _, a := range n.Agents() {
+		ta, isTestAgent := a.(*registryTestAgent)
+		IsTrue(t, isTestAgent, "Generated agent is not of the registered type")
+		ta.Note = "hello"
+	}
+	n2, err := NewNetwork(n.Serialise())
+	AssertSuccess(t, err)
+	ta, isTestAgent := n2.Agents()[0].(*registryTestAgent)
+	IsTrue(t, isTestAgent, "Unmarshalled agent is not of the registered type")
+	AreEqual(t, "hello", ta.Note, "Agent fields not unmarshalled")
+	AreEqual(t, "registryTestAgent", ta.Type, "Type not recorded")
+
+	o := &NetworkOptions{InitColors: []Color{Grey}, MaxColors: 2, AgentType: "registryTestAgent", Seed: 1}
+	clone, err := o.CloneModify(&Network{})
+	AssertSuccess(t, err)
+	AreEqual(t, 0, len(clone.Agents()), "Empty network cloned with agents")
+	o.AgentType = "AgentWithMemory"
+	clone, err = o.CloneModify(n2)
+	AssertSuccess(t, err)
+	_, isMem := clone.Agents()[0].(*AgentWithMemory)
+	IsTrue(t, isMem, "Cloned agents not of the options agent type")
+}
@@ -0,0 +1,235 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SchedulerMode is the name of a strategy used to schedule the Agents sending their mail
+// during each iteration of a simulation
+type SchedulerMode string
+
+// The list of supported scheduler modes
+const (
+	Sequential SchedulerMode = "sequential"
+	Concurrent SchedulerMode = "concurrent"
+	WorkerPool SchedulerMode = "pool"
+)
+
+// Scheduler performs the mail sending phase of an iteration, it has every Agent on the network
+// try to send a mail to a related Agent and returns the number of conversations that resulted.
+// Stop is called at the end of a run to release any resources held by the Scheduler.
+type Scheduler interface {
+	Mode() SchedulerMode
+	SendMail(n RelationshipMgr, agents []Agent) int
+	Stop()
+}
+
+// NewScheduler returns a Scheduler for the passed mode. If no mode is specified the
+// Sequential scheduler is returned. Returns an error if the mode is not recognised.
+func NewScheduler(mode SchedulerMode) (Scheduler, error) {
+	switch mode {
+	case Sequential, "":
+		return &SequentialScheduler{}, nil
+	case Concurrent:
+		return &ConcurrentScheduler{}, nil
+	case WorkerPool:
+		return &WorkerPoolScheduler{Workers: runtime.GOMAXPROCS(0)}, nil
+	default:
+		return nil, fmt.Errorf("unrecognised scheduler mode '%s'", mode)
+	}
+}
+
+// SequentialScheduler has each Agent send its mail one after another in an order that is
+// shuffled on every iteration using the network's random source, so that no Agent is
+// favoured when competing for a related Agent with an empty Mail queue. This is the only
+// scheduler for which a seeded run is reproducible.
+type SequentialScheduler struct {
+	order []Agent
+}
+
+// Mode returns the mode of this scheduler
+func (s *SequentialScheduler) Mode() SchedulerMode {
+	return Sequential
+}
+
+// SendMail has each Agent send its mail in a random order
+func (s *SequentialScheduler) SendMail(n RelationshipMgr, agents []Agent) int {
+	if len(s.order) != len(agents) {
+		s.order = make([]Agent, len(agents))
+	}
+	copy(s.order, agents)
+	shuffleAgents(n.Rand(), s.order)
+
+	convTotal := 0
+	for _, a := range s.order {
+		convTotal = convTotal + a.SendMail(n)
+	}
+	return convTotal
+}
+
+// Stop does nothing for the SequentialScheduler
+func (s *SequentialScheduler) Stop() {
+}
+
+// ConcurrentScheduler starts a goroutine for every Agent on every iteration, each goroutine
+// sleeps for a random number of nanoseconds before sending its mail. The Agents that get
+// matched depend on the OS scheduler so runs are not reproducible with this scheduler.
+type ConcurrentScheduler struct{}
+
+// Mode returns the mode of this scheduler
+func (s *ConcurrentScheduler) Mode() SchedulerMode {
+	return Concurrent
+}
+
+// SendMail has each Agent send its mail in its own goroutine
+func (s *ConcurrentScheduler) SendMail(n RelationshipMgr, agents []Agent) int {
+	hold := make(chan bool)
+	convCount := make(chan int)
+
+	for _, a := range agents {
+		agent := a
+		go func() {
+			<-hold
+			r := n.Rand().Intn(10)
+			time.Sleep(time.Duration(r) * time.Nanosecond)
+			convCount <- agent.SendMail(n)
+		}()
+	}
+	close(hold)
+
+	convTotal := 0
+	for j := len(agents); j > 0; j-- {
+		convTotal = convTotal + <-convCount
+	}
+	close(convCount)
+	return convTotal
+}
+
+// Stop does nothing for the ConcurrentScheduler
+func (s *ConcurrentScheduler) Stop() {
+}
+
+// workerPoolBatchSize is the number of Agents passed to a worker goroutine at a time
+const workerPoolBatchSize = 256
+
+// WorkerPoolScheduler feeds the Agents in a shuffled order to a bounded number of worker
+// goroutines which send the mail. The worker goroutines are started on the first iteration
+// and reused for every subsequent iteration until Stop is called, so that large networks
+// do not need a goroutine and channel per Agent on every iteration. As with the
+// ConcurrentScheduler the Agents that get matched depend on the OS scheduler so runs are
+// not reproducible with this scheduler.
+type WorkerPoolScheduler struct {
+	Workers   int
+	order     []Agent
+	batches   chan []Agent
+	network   RelationshipMgr
+	wg        sync.WaitGroup
+	convCount int64
+}
+
+// Mode returns the mode of this scheduler
+func (s *WorkerPoolScheduler) Mode() SchedulerMode {
+	return WorkerPool
+}
+
+// SendMail has the Agents send their mail on a bounded pool of worker goroutines
+func (s *WorkerPoolScheduler) SendMail(n RelationshipMgr, agents []Agent) int {
+	if s.batches == nil {
+		s.start()
+	}
+	if len(s.order) != len(agents) {
+		s.order = make([]Agent, len(agents))
+	}
+	copy(s.order, agents)
+	shuffleAgents(n.Rand(), s.order)
+
+	s.network = n
+	atomic.StoreInt64(&s.convCount, 0)
+	for i := 0; i < len(s.order); i += workerPoolBatchSize {
+		end := i + workerPoolBatchSize
+		if end > len(s.order) {
+			end = len(s.order)
+		}
+		s.wg.Add(1)
+		s.batches <- s.order[i:end]
+	}
+	s.wg.Wait()
+	return int(atomic.LoadInt64(&s.convCount))
+}
+
+// Stop stops the worker goroutines, they will be restarted if SendMail is called again
+func (s *WorkerPoolScheduler) Stop() {
+	if s.batches != nil {
+		close(s.batches)
+		s.batches = nil
+	}
+}
+
+// start launches the worker goroutines
+func (s *WorkerPoolScheduler) start() {
+	workers := s.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	s.batches = make(chan []Agent, workers)
+	for w := 0; w < workers; w++ {
+		go s.work(s.batches)
+	}
+}
+
+// work sends the mail for each batch of Agents it receives until the channel is closed
+func (s *WorkerPoolScheduler) work(batches chan []Agent) {
+	for batch := range batches {
+		count := 0
+		for _, a := range batch {
+			count = count + a.SendMail(s.network)
+		}
+		atomic.AddInt64(&s.convCount, int64(count))
+		s.wg.Done()
+	}
+}
+
+// shuffleAgents shuffles the passed slice of Agents in place using the passed random source
+func shuffleAgents(r *rand.Rand, agents []Agent) {
+	r.Shuffle(len(agents), func(i, j int) {
+		agents[i], agents[j] = agents[j], agents[i]
+	})
+}
+
+// lockedSource is a random source that is safe for concurrent use by multiple goroutines
+type lockedSource struct {
+	lock sync.Mutex
+	src  rand.Source64
+}
+
+// NewLockedRand returns a new random source seeded with the passed seed that is safe for
+// concurrent use by multiple goroutines
+func NewLockedRand(seed int64) *rand.Rand {
+	return rand.New(&lockedSource{src: rand.NewSource(seed).(rand.Source64)})
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer
+func (ls *lockedSource) Int63() int64 {
+	ls.lock.Lock()
+	defer ls.lock.Unlock()
+	return ls.src.Int63()
+}
+
+// Uint64 returns a pseudo-random 64-bit integer
+func (ls *lockedSource) Uint64() uint64 {
+	ls.lock.Lock()
+	defer ls.lock.Unlock()
+	return ls.src.Uint64()
+}
+
+// Seed reseeds the source
+func (ls *lockedSource) Seed(seed int64) {
+	ls.lock.Lock()
+	defer ls.lock.Unlock()
+	ls.src.Seed(seed)
+}
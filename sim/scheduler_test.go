@@ -0,0 +1,75 @@
+package sim
+
+import (
+	"fmt"
+	"testing"
+)
+
+func RunWithScheduler(t *testing.T, mode SchedulerMode) (RelationshipMgr, Results) {
+	s := HierarchySpec{
+		Levels:        4,
+		TeamSize:      4,
+		TeamLinkLevel: 3,
+		LinkTeamPeers: true,
+		InitColors:    []Color{Grey, Red, Blue},
+		MaxColors:     4,
+		Seed:          3,
+	}
+	n, _, err := GenerateHierarchy(s)
+	AssertSuccess(t, err)
+	scheduler, err := NewScheduler(mode)
+	AssertSuccess(t, err)
+	runner := NewSeededRunner(n, 50, 5)
+	runner.SetScheduler(scheduler)
+	return n, runner.Run()
+}
+
+func CheckResults(t *testing.T, n RelationshipMgr, results Results) {
+	agentCount := len(n.Agents())
+	for i := 0; i < results.Iterations; i++ {
+		total := 0
+		for _, count := range results.Colors[i] {
+			total = total + count
+		}
+		AreEqual(t, agentCount, total, fmt.Sprintf("Color counts do not add up to the number of agents on iteration %d", i))
+		IsTrue(t, results.Conversations[i] > 0, fmt.Sprintf("No conversations on iteration %d", i))
+		IsTrue(t, results.Conversations[i] <= agentCount, fmt.Sprintf("More conversations than agents on iteration %d", i))
+	}
+}
+
+func TestSequentialSchedulerRunsSim(t *testing.T) {
+	n, results := RunWithScheduler(t, Sequential)
+	AreEqual(t, Sequential, results.Scheduler, "Scheduler mode not recorded in the results")
+	CheckResults(t, n, results)
+}
+
+func TestDefaultSchedulerIsSequential(t *testing.T) {
+	n, results := RunWithScheduler(t, "")
+	AreEqual(t, Sequential, results.Scheduler, "Scheduler mode not recorded in the results")
+	CheckResults(t, n, results)
+}
+
+func TestConcurrentSchedulerRunsSim(t *testing.T) {
+	n, results := RunWithScheduler(t, Concurrent)
+	AreEqual(t, Concurrent, results.Scheduler, "Scheduler mode not recorded in the results")
+	CheckResults(t, n, results)
+}
+
+func TestWorkerPoolSchedulerRunsSim(t *testing.T) {
+	n, results := RunWithScheduler(t, WorkerPool)
+	AreEqual(t, WorkerPool, results.Scheduler, "Scheduler mode not recorded in the results")
+	CheckResults(t, n, results)
+}
+
+func TestNewSchedulerFailsWithUnknownMode(t *testing.T) {
+	_, err := NewScheduler("unknown")
+	IsTrue(t, err != nil, "No error returned for an unknown scheduler mode")
+}
+
+func TestLockedRandIsReproducible(t *testing.T) {
+	r1 := NewLockedRand(9)
+	r2 := NewRand(9)
+	for i := 0; i < 100; i++ {
+		AreEqual(t, r2.Int63(), r1.Int63(), "Locked random source differs from unlocked source with the same seed")
+	}
+}
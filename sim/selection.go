@@ -0,0 +1,76 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SelectionMode is the name of a strategy used by Agents to choose which of their related
+// Agents to try to send their Mail to
+type SelectionMode string
+
+// The list of supported selection modes
+const (
+	UniformSelection  SelectionMode = "uniform"
+	StrengthSelection SelectionMode = "strength"
+)
+
+// PartnerSelection sets how Agents choose a related Agent to send their Mail to. With the
+// uniform mode, the default, every related Agent is equally likely to be tried first. With the
+// strength mode a related Agent is tried first with a probability proportional to the Strength
+// of the Link to it plus the Baseline, so that the relationships that are used most become the
+// ones people talk through. Related Agents whose weight is zero are only tried once all the
+// others are busy.
+type PartnerSelection struct {
+	Mode     SelectionMode `json:"mode,omitempty"`
+	Baseline float64       `json:"baseline,omitempty"`
+}
+
+// Validate returns an error if the mode is not recognised or the Baseline is negative
+func (ps PartnerSelection) Validate() error {
+	switch ps.Mode {
+	case UniformSelection, StrengthSelection, "":
+	default:
+		return fmt.Errorf("unrecognised selection mode '%s'", ps.Mode)
+	}
+	if ps.Baseline < 0 {
+		return errors.New("selection baseline cannot be negative")
+	}
+	return nil
+}
+
+// weightedAgent holds a related Agent and the key it is sorted by in a weighted shuffle
+type weightedAgent struct {
+	agent Agent
+	key   float64
+}
+
+// strengthShuffle shuffles the Agents related to the passed Agent in place so that each
+// position is filled with a probability proportional to the weight of the Link to the Agent,
+// using the method of Efraimidis and Spirakis. The Agents are shuffled uniformly first so that
+// Agents with a weight of zero end up in a random order after all the others.
+func (n *Network) strengthShuffle(a Agent, r []Agent) {
+	rnd := n.Rand()
+	shuffleAgents(rnd, r)
+	links := n.AgentLinkMap[a.Identifier()]
+	weighted := make([]weightedAgent, len(r))
+	for i, ra := range r {
+		w := n.selection.Baseline
+		if al, exists := links[ra.Identifier()]; exists {
+			w += float64(al.Link.Strength)
+		}
+		key := math.Inf(1)
+		if w > 0 {
+			key = -math.Log(1-rnd.Float64()) / w
+		}
+		weighted[i] = weightedAgent{ra, key}
+	}
+	sort.SliceStable(weighted, func(i, j int) bool {
+		return weighted[i].key < weighted[j].key
+	})
+	for i, wa := range weighted {
+		r[i] = wa.agent
+	}
+}
@@ -0,0 +1,57 @@
+package sim
+
+import (
+	"testing"
+)
+
+func TestPartnerSelectionValidate(t *testing.T) {
+	AssertSuccess(t, PartnerSelection{}.Validate())
+	AssertSuccess(t, PartnerSelection{Mode: StrengthSelection, Baseline: 1}.Validate())
+	AreEqual(t, "unrecognised selection mode 'loudest'", PartnerSelection{Mode: "loudest"}.Validate().Error(), "Wrong error")
+	AreEqual(t, "selection baseline cannot be negative", PartnerSelection{Baseline: -1}.Validate().Error(), "Wrong error")
+}
+
+func TestStrengthSelectionFavoursStrongLinks(t *testing.T) {
+	n, err := NewNetwork(`{"nodes":[{"id":"a"},{"id":"b"},{"id":"c"},{"id":"d"}],"links":[{"source":"a","target":"b","strength":98},{"source":"a","target":"c"},{"source":"a","target":"d"}]}`)
+	AssertSuccess(t, err)
+	n.SetRand(NewRand(1))
+	a := n.GetAgentByID("a")
+	first := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		first[n.GetRelatedAgents(a)[0].Identifier()]++
+	}
+	IsTrue(t, first["b"] > 300 && first["b"] < 400, "Uniform selection should not favour the strong link")
+
+	n.SetPartnerSelection(PartnerSelection{Mode: StrengthSelection, Baseline: 1})
+	first = map[string]int{}
+	for i := 0; i < 1000; i++ {
+		first[n.GetRelatedAgents(a)[0].Identifier()]++
+	}
+	IsTrue(t, first["b"] > 960, "Strength selection should favour the strong link")
+	IsTrue(t, first["c"] > 0 && first["d"] > 0, "The baseline should give unused links a chance")
+
+	n.SetPartnerSelection(PartnerSelection{Mode: StrengthSelection})
+	for i := 0; i < 100; i++ {
+		AreEqual(t, "b", n.GetRelatedAgents(a)[0].Identifier(), "Unused links should only be tried last without a baseline")
+	}
+	AreEqual(t, 3, len(n.GetRelatedAgents(a)), "Related agents lost in the shuffle")
+}
+
+func TestRunnerSetsPartnerSelection(t *testing.T) {
+	s := HierarchySpec{Levels: 3, TeamSize: 3, LinkTeamPeers: true, InitColors: []Color{Grey, Blue}, MaxColors: 2, Seed: 4}
+	ps := PartnerSelection{Mode: StrengthSelection, Baseline: 0.5}
+	n1, _, _ := GenerateHierarchy(s)
+	r1 := NewSeededRunner(n1, 30, 7)
+	r1.SetPartnerSelection(ps)
+	results1 := r1.Run()
+	AreEqual(t, StrengthSelection, results1.Selection, "Selection not recorded in the results")
+	AreEqual(t, ps, n1.PartnerSelection(), "Selection not set on the network")
+
+	n2, _, _ := GenerateHierarchy(s)
+	r2 := NewSeededRunner(n2, 30, 7)
+	r2.SetPartnerSelection(ps)
+	results2 := r2.Run()
+	for i := range results1.Colors {
+		AreEqual(t, results1.Colors[i][Blue], results2.Colors[i][Blue], "Seeded runs with strength selection not reproducible")
+	}
+}
@@ -1,86 +1,390 @@
 package sim
 
 import (
+	"context"
 	"math/rand"
 	"time"
 )
 
-//Results contains the results from a Sim run over a number of iterations
+//Results contains the results from a Sim run over a number of iterations. If the network has
+//Opinionated Agents, Opinions holds the number of them with an opinion in each of OpinionBins
+//equal width bins between 0 and 1 at the end of every iteration. If the network has
+//IdeaHolders, Ideas holds the number of Agents holding each Color as an idea at the end of
+//every iteration, which can add up to more than the number of Agents. If the run has active
+//LinkDynamics, Links holds the number of active Links at the end of every iteration. If the run
+//has active HierarchyInfluence it is recorded in Hierarchy. Interventions holds the Interventions
+//applied during the run. If the run has Broadcasts, Conversions holds the number of Agents that
+//changed Color in conversations with other Agents and BroadcastConversions the number that changed
+//Color because of a Broadcast on every iteration.
 type Results struct {
-	Iterations    int     `json:"iterations"`
-	Colors        [][]int `json:"colors"`
-	Conversations []int   `json:"conversations"`
+	Iterations           int                   `json:"iterations"`
+	Colors               [][]int               `json:"colors"`
+	Conversations        []int                 `json:"conversations"`
+	Opinions             [][]int               `json:"opinions,omitempty"`
+	Ideas                [][]int               `json:"ideas,omitempty"`
+	Links                []int                 `json:"links,omitempty"`
+	Conversions          []int                 `json:"conversions,omitempty"`
+	BroadcastConversions []int                 `json:"broadcastConversions,omitempty"`
+	Seed                 int64                 `json:"seed,omitempty"`
+	Scheduler            SchedulerMode         `json:"scheduler,omitempty"`
+	Selection            SelectionMode         `json:"selection,omitempty"`
+	Hierarchy            *HierarchyInfluence   `json:"hierarchy,omitempty"`
+	Interventions        []AppliedIntervention `json:"interventions,omitempty"`
+	Stopped              *Stopped              `json:"stopped,omitempty"`
 }
 
 //RunnerInfo specifies the number of iterations and steps to run and records the results
 type RunnerInfo struct {
-	RelationshipMgr RelationshipMgr `json:"network"`
-	Iterations      int             `json:"iterations"`
+	RelationshipMgr RelationshipMgr      `json:"network"`
+	Iterations      int                  `json:"iterations"`
+	Seed            int64                `json:"seed"`
+	Scheduler       Scheduler            `json:"-"`
+	EventLog        *EventLog            `json:"-"`
+	StopConditions  StopConditions       `json:"-"`
+	Selection       PartnerSelection     `json:"-"`
+	Dynamics        LinkDynamics         `json:"-"`
+	Hierarchy       HierarchyInfluence   `json:"-"`
+	Interventions   InterventionSchedule `json:"-"`
+	Broadcasts      Broadcasts           `json:"-"`
+	rand            *rand.Rand
+	elapsed         int
+	stop            *stopChecker
+}
+
+//Observer is notified at the end of every iteration of a run with the index of the
+//iteration, the number of Agents with each Color, and the number of conversations
+type Observer interface {
+	Iteration(i int, colors []int, conversations int)
+}
+
+//ObserverFunc is an adapter to allow the use of an ordinary function as an Observer
+type ObserverFunc func(i int, colors []int, conversations int)
+
+//Iteration calls f(i, colors, conversations)
+func (f ObserverFunc) Iteration(i int, colors []int, conversations int) {
+	f(i, colors, conversations)
 }
 
 //Runner is used to run a simulation for a specified number of steps on its network
 type Runner interface {
 	Run() Results
+	RunContext(ctx context.Context, o Observer) (Results, error)
 	GetRelationshipMgr() RelationshipMgr
+	GetSeed() int64
+	SetSeed(seed int64)
+	SetScheduler(s Scheduler)
+	SetEventLog(l *EventLog)
+	SetStopConditions(sc StopConditions)
+	SetPartnerSelection(ps PartnerSelection)
+	SetLinkDynamics(d LinkDynamics)
+	SetHierarchyInfluence(h HierarchyInfluence)
+	SetInterventions(s InterventionSchedule)
+	SetBroadcasts(bs Broadcasts)
 }
 
-//NewRunner returns an instance of a sim Runner
+//NewRunner returns an instance of a sim Runner seeded from the current time
 func NewRunner(n RelationshipMgr, iterations int) Runner {
+	return NewSeededRunner(n, iterations, NewSeed())
+}
+
+//NewSeededRunner returns an instance of a sim Runner whose random behaviour is driven
+//entirely by the passed seed. Two Runners with the same seed will produce identical
+//Results when run on identical networks using the Sequential scheduler.
+func NewSeededRunner(n RelationshipMgr, iterations int, seed int64) Runner {
 	return &RunnerInfo{
 		RelationshipMgr: n,
 		Iterations:      iterations,
+		Seed:            seed,
+		Scheduler:       &SequentialScheduler{},
+		rand:            NewRand(seed),
 	}
 }
 
+//NewSeed returns a seed derived from the current time
+func NewSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+//NewRand returns a new random source seeded with the passed seed
+func NewRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
 //GetRelationshipMgr returns the internal network state
 func (ri *RunnerInfo) GetRelationshipMgr() RelationshipMgr {
 	return ri.RelationshipMgr
 }
 
+//GetSeed returns the seed used by this Runner
+func (ri *RunnerInfo) GetSeed() int64 {
+	return ri.Seed
+}
+
+//SetSeed sets the seed used by this Runner and restarts its random source from it, so that
+//the next run can be reproduced from the seed alone
+func (ri *RunnerInfo) SetSeed(seed int64) {
+	ri.Seed = seed
+	ri.rand = NewRand(seed)
+}
+
+//SetScheduler sets the Scheduler used to perform the mail sending phase of each iteration
+func (ri *RunnerInfo) SetScheduler(s Scheduler) {
+	ri.Scheduler = s
+}
+
+//SetEventLog sets the log that every change of Color made during a run is recorded in. If
+//the log is nil, which is the default, changes are not recorded.
+func (ri *RunnerInfo) SetEventLog(l *EventLog) {
+	ri.EventLog = l
+}
+
+//SetStopConditions sets the conditions that are evaluated at the end of every iteration
+//and stop the run early as soon as one of them is met. The iterations counted towards the
+//conditions, and the deadline of the TimeLimit, carry on across calls to RunContext until the
+//conditions are set again.
+func (ri *RunnerInfo) SetStopConditions(sc StopConditions) {
+	ri.StopConditions = sc
+	ri.stop = nil
+}
+
+//SetPartnerSelection sets how the Agents choose the related Agent to send their Mail to
+//during the run
+func (ri *RunnerInfo) SetPartnerSelection(ps PartnerSelection) {
+	ri.Selection = ps
+}
+
+//SetLinkDynamics sets how the Links on the network change at the end of every iteration
+func (ri *RunnerInfo) SetLinkDynamics(d LinkDynamics) {
+	ri.Dynamics = d
+}
+
+//SetHierarchyInfluence sets the multipliers for Influence along and across the reporting lines
+//during the run
+func (ri *RunnerInfo) SetHierarchyInfluence(h HierarchyInfluence) {
+	ri.Hierarchy = h
+}
+
+//SetInterventions sets the Interventions to apply to the network during the run. The Iteration
+//of each Intervention counts from the first iteration run by the Runner.
+func (ri *RunnerInfo) SetInterventions(s InterventionSchedule) {
+	ri.Interventions = s
+}
+
+//SetBroadcasts sets the Broadcasts sent to the Agents during the run. Like Interventions, the
+//iterations they are sent on count from the first iteration run by the Runner.
+func (ri *RunnerInfo) SetBroadcasts(bs Broadcasts) {
+	ri.Broadcasts = bs
+}
+
 //Run runs the simulation
 func (ri *RunnerInfo) Run() Results {
+	results, _ := ri.RunContext(context.Background(), nil)
+	return results
+}
+
+//RunContext runs the simulation, calling the passed Observer (if not nil) at the end of
+//every iteration. The context is checked before each iteration starts, if it has been
+//cancelled the run stops and the Results of the iterations completed so far are returned
+//together with the error from the context. If one of the StopConditions is met the run
+//stops at the end of that iteration, and the condition and iteration are recorded in the
+//Stopped field of the Results. If the LinkDynamics are active they are applied to the network
+//at the end of every iteration, after the Agents have read their Mail. Interventions are applied
+//at the start of the iteration they are scheduled for and recorded in the Results, and then any
+//Broadcasts that are due are sent before the Agents send their Mail.
+func (ri *RunnerInfo) RunContext(ctx context.Context, o Observer) (Results, error) {
 	results := Results{
 		Iterations:    ri.Iterations,
 		Colors:        make([][]int, ri.Iterations),
 		Conversations: make([]int, ri.Iterations),
+		Seed:          ri.Seed,
+	}
+	if ri.rand == nil {
+		ri.rand = NewRand(ri.Seed)
 	}
-	//Seed rand to make sure random behaviour is evenly distributed
-	rand.Seed(time.Now().UnixNano())
+	if ri.Scheduler == nil {
+		ri.Scheduler = &SequentialScheduler{}
+	}
+	results.Scheduler = ri.Scheduler.Mode()
+	results.Selection = ri.Selection.Mode
+	if ri.Hierarchy.Active() {
+		h := ri.Hierarchy
+		results.Hierarchy = &h
+	}
+	defer ri.Scheduler.Stop()
 
 	n := ri.RelationshipMgr
+	if ri.Scheduler.Mode() == Sequential {
+		n.SetRand(ri.rand)
+	} else {
+		//The network's random source is shared between goroutines by the other schedulers
+		n.SetRand(NewLockedRand(ri.rand.Int63()))
+	}
+	n.SetEventLog(ri.EventLog)
+	n.SetPartnerSelection(ri.Selection)
+	n.SetHierarchyInfluence(ri.Hierarchy)
+	//Carry on counting iterations for the Interventions the next time the Runner is run
+	defer func() {
+		ri.elapsed += results.Iterations
+	}()
 	agents := n.Agents()
+	var stop *stopChecker
+	if ri.StopConditions.Active() {
+		//Keep counting from the last call so that a condition can be met across several calls
+		if ri.stop == nil {
+			ri.stop = newStopChecker(ri.StopConditions)
+		}
+		stop = ri.stop
+	}
+	allocateAgentCounts(&results, agents, ri.Iterations)
+	if ri.Dynamics.Active() {
+		results.Links = make([]int, ri.Iterations)
+	}
+	if len(ri.Broadcasts) > 0 {
+		results.Conversions = make([]int, ri.Iterations)
+		results.BroadcastConversions = make([]int, ri.Iterations)
+	}
 
 	for i := 0; i < ri.Iterations; i++ {
-		hold := make(chan bool)
-		convCount := make(chan int)
-
-		nc := len(agents)
-
-		for _, a := range agents {
-			agent := a
-			go func() {
-				<-hold
-				r := rand.Intn(10)
-				time.Sleep(time.Duration(r) * time.Nanosecond)
-				convCount <- agent.SendMail(n)
-			}()
+		err := ctx.Err()
+		if err != nil {
+			truncate(&results, i)
+			return results, err
 		}
-		close(hold)
 
-		convTotal := 0
-		for j := nc; j > 0; j-- {
-			convTotal = convTotal + <-convCount
+		ri.EventLog.SetIteration(i)
+		for _, iv := range ri.Interventions.at(ri.elapsed + i) {
+			err = iv.Apply(n)
+			if err != nil {
+				truncate(&results, i)
+				return results, err
+			}
+			results.Interventions = append(results.Interventions, AppliedIntervention{i, iv})
 		}
-		close(convCount)
+		broadcast := ri.Broadcasts.send(n, ri.elapsed+i)
+		convTotal := ri.Scheduler.SendMail(n, agents)
 
 		colorCounts := make([]int, n.MaxColors())
+		changes := 0
 		for _, a := range agents {
+			oldColor := a.GetColor()
 			color := a.ReadMail(n)
 			colorCounts[color]++
+			if color != oldColor {
+				changes++
+			}
+		}
+		if results.Conversions != nil {
+			results.Conversions[i] = changes
+			results.BroadcastConversions[i] = broadcast
+		}
+		changes += broadcast
+		if results.Links != nil {
+			err = ri.Dynamics.Apply(n)
+			if err != nil {
+				truncate(&results, i)
+				return results, err
+			}
+			results.Links[i] = activeLinks(n)
 		}
 		results.Colors[i] = colorCounts
 		results.Conversations[i] = convTotal
+		recordAgentCounts(&results, i, agents, n.MaxColors())
+		if o != nil {
+			o.Iteration(i, colorCounts, convTotal)
+		}
+		if stop != nil {
+			if c := stop.check(colorCounts, changes); c != "" {
+				truncate(&results, i+1)
+				results.Stopped = &Stopped{c, i}
+				break
+			}
+		}
 	}
 
+	return results, nil
+}
+
+//truncate cuts the results down to the first i iterations
+func truncate(results *Results, i int) {
+	results.Iterations = i
+	results.Colors = results.Colors[:i]
+	results.Conversations = results.Conversations[:i]
+	if results.Opinions != nil {
+		results.Opinions = results.Opinions[:i]
+	}
+	if results.Ideas != nil {
+		results.Ideas = results.Ideas[:i]
+	}
+	if results.Links != nil {
+		results.Links = results.Links[:i]
+	}
+	if results.Conversions != nil {
+		results.Conversions = results.Conversions[:i]
+		results.BroadcastConversions = results.BroadcastConversions[:i]
+	}
+}
+
+//activeLinks returns the number of active Links on the network
+func activeLinks(n RelationshipMgr) int {
+	count := 0
+	for _, l := range n.Links() {
+		if !l.Inactive {
+			count++
+		}
+	}
+	return count
+}
+
+//InitialResults returns Results with no iterations that record the number of Agents with each
+//Color on the network, along with their opinions and ideas if they have them, before it is run
+func InitialResults(n RelationshipMgr) Results {
+	agents := n.Agents()
+	results := Results{
+		Iterations:    0,
+		Colors:        make([][]int, 1),
+		Conversations: make([]int, 1),
+	}
+	colorCounts := make([]int, n.MaxColors())
+	for _, a := range agents {
+		colorCounts[a.GetColor()]++
+	}
+	results.Colors[0] = colorCounts
+	allocateAgentCounts(&results, agents, 1)
+	recordAgentCounts(&results, 0, agents, n.MaxColors())
 	return results
 }
+
+//allocateAgentCounts makes room in the results to record the opinions and ideas of the Agents
+//for the passed number of iterations, if there are Agents that have them
+func allocateAgentCounts(results *Results, agents []Agent, iterations int) {
+	for _, a := range agents {
+		if _, isOpinionated := a.(Opinionated); isOpinionated && results.Opinions == nil {
+			results.Opinions = make([][]int, iterations)
+		}
+		if _, isIdeaHolder := a.(IdeaHolder); isIdeaHolder && results.Ideas == nil {
+			results.Ideas = make([][]int, iterations)
+		}
+	}
+}
+
+//recordAgentCounts records the opinions and ideas of the Agents on iteration i if there is
+//room for them in the results
+func recordAgentCounts(results *Results, i int, agents []Agent, maxColors int) {
+	if results.Opinions != nil {
+		results.Opinions[i] = opinionCounts(agents)
+	}
+	if results.Ideas != nil {
+		results.Ideas[i] = ideaCounts(agents, maxColors)
+	}
+}
+
+//opinionCounts returns the number of Opinionated Agents with an opinion in each bin
+func opinionCounts(agents []Agent) []int {
+	counts := make([]int, OpinionBins)
+	for _, a := range agents {
+		if o, isOpinionated := a.(Opinionated); isOpinionated {
+			counts[opinionBin(o.GetOpinion())]++
+		}
+	}
+	return counts
+}
@@ -2,6 +2,7 @@ package sim
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -81,7 +82,7 @@ func WriteOutput(t *testing.T, filename string, s HierarchySpec, n RelationshipM
 			buffer.WriteString(",,,,,,,")
 		}
 		if i < linkCount {
-			buffer.WriteString(fmt.Sprintf(",,%s-%s,%d", links[i].Agent1ID, links[i].Agent2ID, links[i].Strength))
+			buffer.WriteString(fmt.Sprintf(",,%s-%s,%g", links[i].Agent1ID, links[i].Agent2ID, links[i].Strength))
 		} else {
 			buffer.WriteString(",,,")
 		}
@@ -165,3 +166,161 @@ func TestRunSim(t *testing.T) {
 	s := HierarchySpec{}
 	RunSimFromJSON(t, filename, s)
 }
+
+func RunSeededSim(t *testing.T, json string, seed int64) Results {
+	n, err := NewNetwork(json)
+	AssertSuccess(t, err)
+	runner := NewSeededRunner(n, 200, seed)
+	return runner.Run()
+}
+
+func TestSeededRunsAreReproducible(t *testing.T) {
+	s := HierarchySpec{
+		Levels:           4,
+		TeamSize:         4,
+		TeamLinkLevel:    3,
+		LinkTeamPeers:    true,
+		LinkTeams:        true,
+		InitColors:       []Color{Grey, Red},
+		MaxColors:        4,
+		EvangelistAgents: true,
+		AgentsWithMemory: true,
+		Seed:             7,
+	}
+	n, _, err := GenerateHierarchy(s)
+	AssertSuccess(t, err)
+	json := n.Serialise()
+
+	r1 := RunSeededSim(t, json, 42)
+	r2 := RunSeededSim(t, json, 42)
+	AreEqual(t, int64(42), r1.Seed, "Seed not recorded in Results")
+	for i := 0; i < r1.Iterations; i++ {
+		AreEqual(t, r1.Conversations[i], r2.Conversations[i], fmt.Sprintf("Conversations differ on iteration %d", i))
+		for c := range r1.Colors[i] {
+			AreEqual(t, r1.Colors[i][c], r2.Colors[i][c], fmt.Sprintf("Color %d differs on iteration %d", c, i))
+		}
+	}
+}
+
+func TestSeededGenerateHierarchyIsReproducible(t *testing.T) {
+	s := HierarchySpec{
+		Levels:     3,
+		TeamSize:   3,
+		InitColors: []Color{Grey, Red, Blue},
+		MaxColors:  4,
+		Seed:       11,
+	}
+	n1, _, err := GenerateHierarchy(s)
+	AssertSuccess(t, err)
+	n2, _, err := GenerateHierarchy(s)
+	AssertSuccess(t, err)
+	AreEqual(t, n1.Serialise(), n2.Serialise(), "Networks generated with the same seed are not identical")
+}
+
+func TestGenerateHierarchyFailsWhenTeamsTooSmall(t *testing.T) {
+	_, _, err := GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 3, TeamLinkLevel: 2, EvangelistAgents: true, Seed: 1})
+	AreEqual(t, "teamSize must be at least 4 for evangelistAgents", err.Error(), "Wrong error for evangelists")
+	_, _, err = GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 2, TeamLinkLevel: 2, LoneEvangelist: true, Seed: 1})
+	AreEqual(t, "teamSize must be at least 3 for loneEvangelist", err.Error(), "Wrong error for lone evangelist")
+	_, _, err = GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 3, TeamLinkLevel: 3, LinkTeams: true, Seed: 1})
+	AreEqual(t, "teamLinkLevel 3 must be less than levels 3", err.Error(), "Wrong error for team link level")
+}
+
+func TestGenerateHierarchyWithDirectedLinks(t *testing.T) {
+	n, o, err := GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 3, LinkTeamPeers: true, InitColors: []Color{Grey}, MaxColors: 4, DirectedLinks: true, Seed: 1})
+	AssertSuccess(t, err)
+	directed := 0
+	for _, l := range n.Links() {
+		if l.Directed {
+			directed++
+		}
+	}
+	AreEqual(t, 12, directed, "Links from parents to children should be directed")
+	AreEqual(t, 5, len(n.GetRelatedAgents(n.GetAgentByID("id_2"))), "Agent should only send to its children and peers")
+
+	o.LinkTeamPeers = false
+	clone, err := o.CloneModify(n)
+	AssertSuccess(t, err)
+	AreEqual(t, len(n.Links()), len(clone.Links()), "Wrong number of links cloned")
+	for i, l := range clone.Links() {
+		AreEqual(t, n.Links()[i].Directed, l.Directed, "Directed flag not kept when cloning")
+	}
+}
+
+func TestRunContextCallsObserverEveryIteration(t *testing.T) {
+	n, _, err := GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 3, InitColors: []Color{Grey, Red}, MaxColors: 4, Seed: 3})
+	AssertSuccess(t, err)
+	runner := NewSeededRunner(n, 20, 5)
+	calls := 0
+	results, err := runner.RunContext(context.Background(), ObserverFunc(func(i int, colors []int, conversations int) {
+		AreEqual(t, calls, i, "Observer called with wrong iteration")
+		AreEqual(t, 4, len(colors), "Observer called with wrong number of colors")
+		calls++
+	}))
+	AssertSuccess(t, err)
+	AreEqual(t, 20, calls, "Observer not called on every iteration")
+	AreEqual(t, 20, results.Iterations, "Wrong number of iterations")
+}
+
+func TestRunContextStopsWhenCancelled(t *testing.T) {
+	n, _, err := GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 3, InitColors: []Color{Grey, Red}, MaxColors: 4, Seed: 3})
+	AssertSuccess(t, err)
+	runner := NewSeededRunner(n, 20, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results, err := runner.RunContext(ctx, ObserverFunc(func(i int, colors []int, conversations int) {
+		if i == 6 {
+			cancel()
+		}
+	}))
+	AreEqual(t, context.Canceled, err, "Expected the context error to be returned")
+	AreEqual(t, 7, results.Iterations, "Wrong number of iterations")
+	AreEqual(t, 7, len(results.Colors), "Colors not truncated")
+	AreEqual(t, 7, len(results.Conversations), "Conversations not truncated")
+}
+
+// Generates a hierarchy with TeamSize 10 so each extra level multiplies the number of agents by 10
+func GenerateBenchmarkNetwork(b *testing.B, levels int) *Network {
+	s := HierarchySpec{
+		Levels:        levels,
+		TeamSize:      10,
+		TeamLinkLevel: levels - 1,
+		LinkTeamPeers: true,
+		InitColors:    []Color{Grey, Red, Blue},
+		MaxColors:     4,
+		Seed:          1,
+	}
+	n, _, err := GenerateHierarchy(s)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return n
+}
+
+func BenchmarkRun(b *testing.B) {
+	sizes := []struct {
+		name   string
+		levels int
+	}{
+		{"1k", 4},
+		{"10k", 5},
+		{"100k", 6},
+	}
+	for _, size := range sizes {
+		n := GenerateBenchmarkNetwork(b, size.levels)
+		for _, mode := range []SchedulerMode{Sequential, WorkerPool, Concurrent} {
+			b.Run(fmt.Sprintf("%s/%s", size.name, mode), func(b *testing.B) {
+				scheduler, err := NewScheduler(mode)
+				if err != nil {
+					b.Fatal(err)
+				}
+				runner := NewSeededRunner(n, b.N, 1)
+				runner.SetScheduler(scheduler)
+				b.ReportAllocs()
+				b.ResetTimer()
+				runner.Run()
+				b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "iterations/s")
+			})
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package sim
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// StopCondition is the name of a condition that can end a run before all of its
+// iterations have completed
+type StopCondition string
+
+// The list of conditions that can stop a run early
+const (
+	//ColorShareReached means a Color was held by at least ColorShare of the Agents
+	ColorShareReached StopCondition = "colorShare"
+	//NoChange means no Agent changed Color for StableIterations iterations in a row
+	NoChange StopCondition = "noChange"
+	//LowEntropy means the entropy of the Color distribution stayed below EntropyThreshold
+	//for EntropyIterations iterations in a row
+	LowEntropy StopCondition = "lowEntropy"
+	//TimeLimitReached means the run used up its wall-clock budget
+	TimeLimitReached StopCondition = "timeLimit"
+)
+
+// StopConditions are evaluated at the end of every iteration of a run, and the run stops
+// as soon as any one of them is met. A condition is switched off by leaving it as zero.
+//
+// ColorShare is the fraction of Agents, between 0 and 1, that a single Color must reach.
+// Only the Colors in ShareColors are considered, or every Color other than Grey if
+// ShareColors is empty. StableIterations is the number of consecutive iterations in which
+// no Agent changes Color. EntropyThreshold is the Shannon entropy, in bits, of the share of
+// Agents holding each Color that the distribution must stay below for EntropyIterations
+// consecutive iterations (1 if not set). TimeLimit is the wall-clock budget of the run in
+// seconds.
+type StopConditions struct {
+	ColorShare        float64 `json:"colorShare,omitempty"`
+	ShareColors       []Color `json:"shareColors,omitempty"`
+	StableIterations  int     `json:"stableIterations,omitempty"`
+	EntropyThreshold  float64 `json:"entropyThreshold,omitempty"`
+	EntropyIterations int     `json:"entropyIterations,omitempty"`
+	TimeLimit         float64 `json:"timeLimit,omitempty"`
+}
+
+// Stopped records the condition that ended a run early and the index of the iteration on
+// which it was met
+type Stopped struct {
+	Condition StopCondition `json:"condition"`
+	Iteration int           `json:"iteration"`
+}
+
+// Validate returns an error if any of the StopConditions is out of range
+func (sc StopConditions) Validate() error {
+	if sc.ColorShare < 0 || sc.ColorShare > 1 {
+		return errors.New("colorShare must be between 0 and 1")
+	}
+	if sc.StableIterations < 0 || sc.EntropyIterations < 0 {
+		return errors.New("stableIterations and entropyIterations cannot be negative")
+	}
+	if sc.EntropyThreshold < 0 {
+		return errors.New("entropyThreshold cannot be negative")
+	}
+	if sc.TimeLimit < 0 {
+		return errors.New("timeLimit cannot be negative")
+	}
+	return nil
+}
+
+// Active returns true if at least one of the StopConditions is switched on
+func (sc StopConditions) Active() bool {
+	return sc.ColorShare > 0 || sc.StableIterations > 0 || sc.EntropyThreshold > 0 || sc.TimeLimit > 0
+}
+
+// Entropy returns the Shannon entropy in bits of the distribution of Agents across the
+// Colors, where colors holds the number of Agents with each Color
+func Entropy(colors []int) float64 {
+	total := 0
+	for _, c := range colors {
+		total += c
+	}
+	h := 0.0
+	if total == 0 {
+		return h
+	}
+	for _, c := range colors {
+		if c > 0 {
+			p := float64(c) / float64(total)
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// stopChecker holds the state needed to evaluate the StopConditions over a run, which may be
+// made up of several calls to RunContext on the same Runner
+type stopChecker struct {
+	sc       StopConditions
+	deadline time.Time
+	stable   int
+	low      int
+}
+
+func newStopChecker(sc StopConditions) *stopChecker {
+	if sc.EntropyIterations == 0 {
+		sc.EntropyIterations = 1
+	}
+	s := &stopChecker{sc: sc}
+	if sc.TimeLimit > 0 {
+		s.deadline = time.Now().Add(time.Duration(sc.TimeLimit * float64(time.Second)))
+	}
+	return s
+}
+
+// check is called at the end of every iteration with the number of Agents holding each
+// Color and the number of Agents that changed Color during the iteration. It returns the
+// condition that has been met, or an empty string if the run should continue.
+func (s *stopChecker) check(colors []int, changes int) StopCondition {
+	if s.sc.ColorShare > 0 && s.shareReached(colors) {
+		return ColorShareReached
+	}
+	if s.sc.StableIterations > 0 {
+		if changes == 0 {
+			s.stable++
+		} else {
+			s.stable = 0
+		}
+		if s.stable >= s.sc.StableIterations {
+			return NoChange
+		}
+	}
+	if s.sc.EntropyThreshold > 0 {
+		if Entropy(colors) < s.sc.EntropyThreshold {
+			s.low++
+		} else {
+			s.low = 0
+		}
+		if s.low >= s.sc.EntropyIterations {
+			return LowEntropy
+		}
+	}
+	if !s.deadline.IsZero() && !time.Now().Before(s.deadline) {
+		return TimeLimitReached
+	}
+	return ""
+}
+
+func (s *stopChecker) shareReached(colors []int) bool {
+	total := 0
+	for _, c := range colors {
+		total += c
+	}
+	if total == 0 {
+		return false
+	}
+	reached := func(c Color) bool {
+		return int(c) < len(colors) && float64(colors[c]) >= s.sc.ColorShare*float64(total)
+	}
+	if len(s.sc.ShareColors) == 0 {
+		for c := range colors {
+			if Color(c) != Grey && reached(Color(c)) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, c := range s.sc.ShareColors {
+		if reached(c) {
+			return true
+		}
+	}
+	return false
+}
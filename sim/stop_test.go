@@ -0,0 +1,123 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+func CreateStopTestNetwork(t *testing.T) RelationshipMgr {
+	n, _, err := GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 4, TeamLinkLevel: 2, LinkTeamPeers: true, InitColors: []Color{Grey}, MaxColors: 4, EvangelistAgents: true, Seed: 3})
+	AssertSuccess(t, err)
+	return n
+}
+
+func TestEntropy(t *testing.T) {
+	AreEqual(t, 0.0, Entropy([]int{0, 0, 0}), "Entropy of no Agents should be zero")
+	AreEqual(t, 0.0, Entropy([]int{5, 0, 0}), "Entropy of a single Color should be zero")
+	AreEqual(t, 1.0, Entropy([]int{3, 3, 0}), "Entropy of two equal Colors should be 1 bit")
+	AreEqual(t, 2.0, Entropy([]int{2, 2, 2, 2}), "Entropy of four equal Colors should be 2 bits")
+}
+
+func TestValidateStopConditions(t *testing.T) {
+	AssertSuccess(t, StopConditions{}.Validate())
+	AssertSuccess(t, StopConditions{ColorShare: 1, StableIterations: 5, EntropyThreshold: 0.5, TimeLimit: 10}.Validate())
+	IsFalse(t, StopConditions{ColorShare: 1.5}.Validate() == nil, "ColorShare above 1 not rejected")
+	IsFalse(t, StopConditions{StableIterations: -1}.Validate() == nil, "Negative StableIterations not rejected")
+	IsFalse(t, StopConditions{EntropyThreshold: -1}.Validate() == nil, "Negative EntropyThreshold not rejected")
+	IsFalse(t, StopConditions{TimeLimit: -1}.Validate() == nil, "Negative TimeLimit not rejected")
+}
+
+func TestStopCheckerColorShare(t *testing.T) {
+	s := newStopChecker(StopConditions{ColorShare: 0.5})
+	AreEqual(t, StopCondition(""), s.check([]int{6, 4, 0}, 1), "Stopped before a Color reached its share")
+	AreEqual(t, StopCondition(""), s.check([]int{10, 0, 0}, 1), "Grey should not count towards the share")
+	AreEqual(t, ColorShareReached, s.check([]int{5, 5, 0}, 1), "Did not stop when a Color reached its share")
+
+	s = newStopChecker(StopConditions{ColorShare: 0.5, ShareColors: []Color{Blue}})
+	AreEqual(t, StopCondition(""), s.check([]int{0, 2, 8}, 1), "Color not in ShareColors should be ignored")
+	AreEqual(t, ColorShareReached, s.check([]int{0, 5, 5}, 1), "Did not stop when a Color in ShareColors reached its share")
+}
+
+func TestStopCheckerNoChange(t *testing.T) {
+	s := newStopChecker(StopConditions{StableIterations: 2})
+	AreEqual(t, StopCondition(""), s.check([]int{5, 5}, 0), "Stopped after one stable iteration")
+	AreEqual(t, StopCondition(""), s.check([]int{5, 5}, 1), "Stopped after a change")
+	AreEqual(t, StopCondition(""), s.check([]int{5, 5}, 0), "Stable count not reset by a change")
+	AreEqual(t, NoChange, s.check([]int{5, 5}, 0), "Did not stop after two stable iterations")
+}
+
+func TestStopCheckerLowEntropy(t *testing.T) {
+	s := newStopChecker(StopConditions{EntropyThreshold: 0.5, EntropyIterations: 2})
+	AreEqual(t, StopCondition(""), s.check([]int{10, 0}, 1), "Stopped after one low entropy iteration")
+	AreEqual(t, StopCondition(""), s.check([]int{5, 5}, 1), "Stopped when entropy was high")
+	AreEqual(t, StopCondition(""), s.check([]int{10, 0}, 1), "Low entropy count not reset")
+	AreEqual(t, LowEntropy, s.check([]int{10, 0}, 1), "Did not stop after two low entropy iterations")
+
+	s = newStopChecker(StopConditions{EntropyThreshold: 0.5})
+	AreEqual(t, LowEntropy, s.check([]int{10, 0}, 1), "EntropyIterations should default to 1")
+}
+
+func TestStopCheckerTimeLimit(t *testing.T) {
+	s := newStopChecker(StopConditions{TimeLimit: math.SmallestNonzeroFloat64})
+	AreEqual(t, TimeLimitReached, s.check([]int{5, 5}, 1), "Did not stop when the time limit was reached")
+	s = newStopChecker(StopConditions{TimeLimit: 3600})
+	AreEqual(t, StopCondition(""), s.check([]int{5, 5}, 1), "Stopped before the time limit was reached")
+}
+
+func TestRunStopsWhenColorShareReached(t *testing.T) {
+	runner := NewSeededRunner(CreateStopTestNetwork(t), 500, 5)
+	runner.SetStopConditions(StopConditions{ColorShare: 0.5, ShareColors: []Color{Blue}})
+	results := runner.Run()
+	IsTrue(t, results.Stopped != nil, "Run not stopped")
+	AreEqual(t, ColorShareReached, results.Stopped.Condition, "Wrong stop condition")
+	AreEqual(t, results.Iterations-1, results.Stopped.Iteration, "Wrong stop iteration")
+	AreEqual(t, results.Iterations, len(results.Colors), "Colors not truncated")
+	AreEqual(t, results.Iterations, len(results.Conversations), "Conversations not truncated")
+	last := results.Colors[results.Iterations-1]
+	total := 0
+	for _, c := range last {
+		total += c
+	}
+	IsTrue(t, float64(last[Blue]) >= 0.5*float64(total), "Blue has not reached half the Agents")
+	prev := results.Colors[results.Iterations-2]
+	IsTrue(t, float64(prev[Blue]) < 0.5*float64(total), "Run did not stop on the first iteration Blue reached half the Agents")
+}
+
+func TestRunStopsWhenNoChange(t *testing.T) {
+	n := CreateStopTestNetwork(t)
+	for _, a := range n.Agents() {
+		a.State().Susceptability = 100
+	}
+	runner := NewSeededRunner(n, 100, 5)
+	runner.SetStopConditions(StopConditions{StableIterations: 3})
+	results := runner.Run()
+	IsTrue(t, results.Stopped != nil, "Run not stopped")
+	AreEqual(t, NoChange, results.Stopped.Condition, "Wrong stop condition")
+	AreEqual(t, 2, results.Stopped.Iteration, "Wrong stop iteration")
+	AreEqual(t, 3, results.Iterations, "Wrong number of iterations")
+}
+
+func TestRunStopsWhenNoChangeAcrossRuns(t *testing.T) {
+	n := CreateStopTestNetwork(t)
+	for _, a := range n.Agents() {
+		a.State().Susceptability = 100
+	}
+	runner := NewSeededRunner(n, 2, 5)
+	runner.SetStopConditions(StopConditions{StableIterations: 3})
+	results := runner.Run()
+	IsTrue(t, results.Stopped == nil, "Run stopped before the condition was met")
+	results = runner.Run()
+	IsTrue(t, results.Stopped != nil, "Unchanged iterations not counted from the previous run")
+	AreEqual(t, 0, results.Stopped.Iteration, "Wrong stop iteration")
+
+	runner.SetStopConditions(StopConditions{StableIterations: 3})
+	results = runner.Run()
+	IsTrue(t, results.Stopped == nil, "Unchanged iterations not reset when the conditions were set")
+}
+
+func TestRunWithoutStopConditionsRunsEveryIteration(t *testing.T) {
+	runner := NewSeededRunner(CreateStopTestNetwork(t), 20, 5)
+	results := runner.Run()
+	IsTrue(t, results.Stopped == nil, "Run stopped without any stop conditions")
+	AreEqual(t, 20, results.Iterations, "Wrong number of iterations")
+}
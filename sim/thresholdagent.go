@@ -0,0 +1,85 @@
+package sim
+
+import (
+	"math/rand"
+	"reflect"
+)
+
+// A ThresholdAgent follows the threshold model of Granovetter. It only adopts a Color it hears
+// about in a conversation once enough of its linked neighbours already hold that Color, giving
+// a model of change that needs social proof. If Count is set the Threshold is the number of
+// neighbours that must hold the Color, otherwise it is the fraction of its neighbours.
+type ThresholdAgent struct {
+	AgentState
+	Threshold float64 `json:"threshold"`
+	Count     bool    `json:"count,omitempty"`
+}
+
+func init() {
+	RegisterAgentType("ThresholdAgent", func() Agent { return &ThresholdAgent{} })
+}
+
+// Initialise ensures the agent is correctly initialised
+func (a *ThresholdAgent) Initialise(n RelationshipMgr) {
+	a.AgentState.Initialise(n)
+	a.Type = reflect.TypeOf(a).Elem().Name()
+}
+
+// SampleTraits draws the Threshold of the Agent from the Traits when it is generated
+func (a *ThresholdAgent) SampleTraits(r *rand.Rand, t Traits) {
+	a.Threshold = t.Threshold.Sample(r)
+	a.Count = t.ThresholdCount
+}
+
+// State returns the struct containing the state of this Agent
+func (a *ThresholdAgent) State() *AgentState {
+	return &a.AgentState
+}
+
+// ReadMail checks for any messages it received in its own Mail queue. If it receives one then
+// it decides whether to adopt the Color of the sender.
+func (a *ThresholdAgent) ReadMail(n RelationshipMgr) Color {
+	msg, received := a.ReceiveMsg()
+	if received {
+		ra := n.GetAgentByID(msg)
+		if ra != nil {
+			c, reason, update := a.UpdateColor(n, ra.State())
+			if update {
+				oldColor := a.Color
+				a.SetColor(c)
+				n.EventLog().Record(a.ID, ra.Identifier(), oldColor, a.Color, reason)
+			}
+		}
+	}
+	return a.Color
+}
+
+// UpdateColor decides whether the Agent should adopt the Color of the passed Agent. The Color
+// is adopted if the number, or fraction, of the Agent's neighbours holding it has reached the
+// Agent's Threshold. Only the neighbours that can send the Agent Mail are counted, so a Directed
+// Link only counts towards the threshold of its target. Grey is never adopted as it represents
+// not holding any idea.
+func (a *ThresholdAgent) UpdateColor(n RelationshipMgr, ra *AgentState) (Color, ChangeReason, bool) {
+	n.IncrementLinkStrength(a.Identifier(), ra.Identifier())
+	if ra.Color == Grey || ra.Color == a.Color {
+		return Grey, "", false
+	}
+	related := n.GetInfluencingAgents(a)
+	if len(related) == 0 {
+		return Grey, "", false
+	}
+	count := 0
+	for _, r := range related {
+		if r.GetColor() == ra.Color {
+			count++
+		}
+	}
+	level := float64(count)
+	if !a.Count {
+		level = level / float64(len(related))
+	}
+	if level >= a.Threshold {
+		return ra.Color, ThresholdReached, true
+	}
+	return Grey, "", false
+}
@@ -0,0 +1,101 @@
+package sim
+
+import (
+	"testing"
+)
+
+func newThresholdAgent(threshold float64, count bool) *ThresholdAgent {
+	a := &ThresholdAgent{Threshold: threshold, Count: count}
+	a.ID = "id_aut"
+	a.Mail = make(chan string, 1)
+	return a
+}
+
+func TestThresholdAgentAdoptsColorWhenFractionReached(t *testing.T) {
+	tn := newTestNetwork()
+	tn.SetEventLog(NewEventLog())
+	aut := newThresholdAgent(0.3, false)
+	aut.PostMsg("id_1")
+	AreEqual(t, Blue, aut.ReadMail(tn), "One of three neighbours is Blue so the threshold is reached")
+	AreEqual(t, 1, tn.LinkStrength, "LinkStrength not incremented as part of reading a Mail")
+	events := tn.EventLog().Events
+	AreEqual(t, 1, len(events), "Change not recorded")
+	AreEqual(t, ThresholdReached, events[0].Reason, "Wrong reason recorded")
+	AreEqual(t, "id_1", events[0].SenderID, "Wrong source recorded")
+}
+
+func TestThresholdAgentIgnoresColorBelowThreshold(t *testing.T) {
+	tn := newTestNetwork()
+	aut := newThresholdAgent(0.5, false)
+	aut.PostMsg("id_1")
+	AreEqual(t, Grey, aut.ReadMail(tn), "One of three neighbours is below the threshold")
+	tn.GetAgentByID("id_2").State().Color = Blue
+	aut.PostMsg("id_1")
+	AreEqual(t, Blue, aut.ReadMail(tn), "Two of three neighbours reaches the threshold")
+}
+
+func TestThresholdAgentCountsNeighbours(t *testing.T) {
+	tn := newTestNetwork()
+	aut := newThresholdAgent(2, true)
+	aut.PostMsg("id_1")
+	AreEqual(t, Grey, aut.ReadMail(tn), "One neighbour is below a count of two")
+	tn.GetAgentByID("id_3").State().Color = Blue
+	aut.PostMsg("id_1")
+	AreEqual(t, Blue, aut.ReadMail(tn), "Two neighbours reaches a count of two")
+}
+
+func TestThresholdAgentNeverAdoptsGrey(t *testing.T) {
+	tn := newTestNetwork()
+	aut := newThresholdAgent(0, false)
+	aut.Color = Red
+	aut.PostMsg("id_2")
+	AreEqual(t, Red, aut.ReadMail(tn), "Grey should never be adopted")
+}
+
+func TestGenerateThresholdAgents(t *testing.T) {
+	traits := Traits{Threshold: TraitDistribution{Type: Fixed, Value: 3}, ThresholdCount: true}
+	n, _, err := GenerateHierarchy(HierarchySpec{Levels: 2, TeamSize: 3, InitColors: []Color{Grey}, MaxColors: 2, AgentType: "ThresholdAgent", Traits: &traits, Seed: 1})
+	AssertSuccess(t, err)
+	for _, a := range n.Agents() {
+		ta, isThreshold := a.(*ThresholdAgent)
+		IsTrue(t, isThreshold, "Generated agent is not a ThresholdAgent")
+		AreEqual(t, 3.0, ta.Threshold, "Threshold not drawn from the traits")
+		IsTrue(t, ta.Count, "Threshold count not set from the traits")
+	}
+	n2, err := NewNetwork(n.Serialise())
+	AssertSuccess(t, err)
+	ta := n2.Agents()[0].(*ThresholdAgent)
+	AreEqual(t, 3.0, ta.Threshold, "Threshold not unmarshalled")
+	AreEqual(t, "ThresholdAgent", ta.Type, "Type not recorded")
+
+	//The default threshold is a fraction drawn between 0 and 1
+	n, _, err = GenerateHierarchy(HierarchySpec{Levels: 2, TeamSize: 3, InitColors: []Color{Grey}, MaxColors: 2, AgentType: "ThresholdAgent", Seed: 1})
+	AssertSuccess(t, err)
+	for _, a := range n.Agents() {
+		ta := a.(*ThresholdAgent)
+		IsTrue(t, ta.Threshold >= 0 && ta.Threshold <= 1, "Default threshold out of range")
+		IsFalse(t, ta.Count, "Default threshold should be a fraction")
+	}
+}
+
+func TestThresholdAgentsRun(t *testing.T) {
+	n, _, err := GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 4, InitColors: []Color{Grey, Grey, Blue}, MaxColors: 2, AgentType: "ThresholdAgent", Seed: 1})
+	AssertSuccess(t, err)
+	results := NewSeededRunner(n, 20, 1).Run()
+	blue := results.Colors[len(results.Colors)-1][Blue]
+	IsTrue(t, blue >= results.Colors[0][Blue], "Blue should only spread")
+}
+
+func TestThresholdAgentsAdoptAlongDirectedLinks(t *testing.T) {
+	n, err := NewNetwork(`{"maxColors":4,"nodes":[{"id":"a","color":1},` +
+		`{"id":"b","type":"ThresholdAgent","threshold":0},{"id":"c","type":"ThresholdAgent","threshold":1},` +
+		`{"id":"d","type":"ThresholdAgent","threshold":0}],` +
+		`"links":[{"source":"a","target":"b","directed":true},{"source":"b","target":"c","directed":true}]}`)
+	AssertSuccess(t, err)
+	AreEqual(t, 0, len(n.GetInfluencingAgents(n.GetAgentByID("a"))), "The source of a directed link cannot be influenced along it")
+	AreEqual(t, "a", n.GetInfluencingAgents(n.GetAgentByID("b"))[0].Identifier(), "The target of a directed link is influenced by its source")
+	results := NewSeededRunner(n, 3, 1).Run()
+	AreEqual(t, 3, results.Colors[2][Blue], "Agents at the end of directed links did not adopt the Color")
+	_, _, update := n.GetAgentByID("d").(*ThresholdAgent).UpdateColor(n, n.GetAgentByID("a").State())
+	IsFalse(t, update, "Agent with no neighbours that can send it Mail should not adopt a Color")
+}
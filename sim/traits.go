@@ -0,0 +1,214 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Distribution is the name of a probability distribution that an Agent trait is drawn from
+type Distribution string
+
+// The distributions an Agent trait can be drawn from
+const (
+	Normal  Distribution = "normal"
+	Uniform Distribution = "uniform"
+	Beta    Distribution = "beta"
+	Fixed   Distribution = "fixed"
+)
+
+// TraitDistribution describes the distribution a trait of a generated Agent is drawn from.
+// A normal distribution uses Mean and SD, a uniform distribution is between Min and Max, a
+// beta distribution with shape parameters Alpha and Beta is scaled from [0,1] to the range
+// Min to Max (or left on [0,1] if neither is set), and a fixed distribution is always Value.
+type TraitDistribution struct {
+	Type  Distribution `json:"type"`
+	Mean  float64      `json:"mean,omitempty"`
+	SD    float64      `json:"sd,omitempty"`
+	Min   float64      `json:"min,omitempty"`
+	Max   float64      `json:"max,omitempty"`
+	Alpha float64      `json:"alpha,omitempty"`
+	Beta  float64      `json:"beta,omitempty"`
+	Value float64      `json:"value,omitempty"`
+}
+
+// Traits holds the distributions that the Influence, Susceptability and Contrariness of a
+// generated Agent are drawn from, along with the distributions of the traits that only some
+// types of Agent have. A trait with no distribution Type uses its default from DefaultTraits.
+type Traits struct {
+	Influence      TraitDistribution `json:"influence"`
+	Susceptability TraitDistribution `json:"susceptability"`
+	Contrariness   TraitDistribution `json:"contrariness"`
+	// Threshold is the threshold of a ThresholdAgent
+	Threshold TraitDistribution `json:"threshold"`
+	// ThresholdCount makes the Threshold a count of neighbours rather than a fraction
+	ThresholdCount bool `json:"thresholdCount,omitempty"`
+	// Opinion, Confidence and Convergence are the traits of an OpinionAgent
+	Opinion     TraitDistribution `json:"opinion"`
+	Confidence  TraitDistribution `json:"confidence"`
+	Convergence TraitDistribution `json:"convergence"`
+	// OpinionBands sets the Bands of an OpinionAgent
+	OpinionBands []float64 `json:"opinionBands,omitempty"`
+	// OpinionAveraging sets the Averaging of an OpinionAgent
+	OpinionAveraging bool `json:"opinionAveraging,omitempty"`
+	// ExclusiveIdeas sets the groups of exclusive ideas of a MultiIdeaAgent
+	ExclusiveIdeas [][]Color `json:"exclusiveIdeas,omitempty"`
+	// LongMemorySpan is the number of iterations an AgentWithMemory remembers a Color for
+	// after leaving it. It is fixed for the run rather than sampled.
+	LongMemorySpan int `json:"longMemorySpan,omitempty"`
+	// ShortMemorySpan is the number of iterations an AgentWithMemory remembers hearing about
+	// a Color for. It is fixed for the run rather than sampled.
+	ShortMemorySpan int `json:"shortMemorySpan,omitempty"`
+}
+
+// TraitSampler is implemented by Agents that have traits beyond those in the AgentState. The
+// traits are drawn from the passed Traits after the AgentState of a generated Agent is set.
+type TraitSampler interface {
+	SampleTraits(r *rand.Rand, t Traits)
+}
+
+// DefaultTraits returns the distributions used when no Traits are specified. Influence and
+// Susceptability are normal with mean 1 and sd 0.25, Contrariness is normal with mean 0.7
+// and sd 0.15, and Threshold is uniform between 0 and 1 as in Granovetter's model. Opinion is
+// uniform between 0 and 1, Confidence is fixed at 0.2 and Convergence is fixed at 0.5.
+func DefaultTraits() Traits {
+	return Traits{
+		Influence:      TraitDistribution{Type: Normal, Mean: 1, SD: 0.25},
+		Susceptability: TraitDistribution{Type: Normal, Mean: 1, SD: 0.25},
+		Contrariness:   TraitDistribution{Type: Normal, Mean: 0.7, SD: 0.15},
+		Threshold:      TraitDistribution{Type: Uniform, Min: 0, Max: 1},
+		Opinion:        TraitDistribution{Type: Uniform, Min: 0, Max: 1},
+		Confidence:     TraitDistribution{Type: Fixed, Value: 0.2},
+		Convergence:    TraitDistribution{Type: Fixed, Value: 0.5},
+	}
+}
+
+// Validate returns an error if any of the trait distributions cannot be sampled, if the
+// OpinionBands are not in ascending order between 0 and 1, if a group of ExclusiveIdeas has
+// fewer than two ideas, or if a memory span is negative
+func (t *Traits) Validate() error {
+	if t == nil {
+		return nil
+	}
+	names := []string{"influence", "susceptability", "contrariness", "threshold", "opinion", "confidence", "convergence"}
+	for i, d := range []TraitDistribution{t.Influence, t.Susceptability, t.Contrariness, t.Threshold, t.Opinion, t.Confidence, t.Convergence} {
+		err := d.Validate()
+		if err != nil {
+			return fmt.Errorf("%s: %s", names[i], err.Error())
+		}
+	}
+	for i, b := range t.OpinionBands {
+		if b < 0 || b > 1 || (i > 0 && b <= t.OpinionBands[i-1]) {
+			return errors.New("opinionBands: bands must be in ascending order between 0 and 1")
+		}
+	}
+	for _, group := range t.ExclusiveIdeas {
+		if len(group) < 2 {
+			return errors.New("exclusiveIdeas: each group must have at least two ideas")
+		}
+	}
+	if t.LongMemorySpan < 0 || t.ShortMemorySpan < 0 {
+		return errors.New("memory spans cannot be negative")
+	}
+	return nil
+}
+
+// withDefaults returns a copy of the Traits with every trait that has no distribution
+// replaced by its default
+func (t *Traits) withDefaults() Traits {
+	dt := DefaultTraits()
+	if t == nil {
+		return dt
+	}
+	ret := *t
+	if ret.Influence.Type == "" {
+		ret.Influence = dt.Influence
+	}
+	if ret.Susceptability.Type == "" {
+		ret.Susceptability = dt.Susceptability
+	}
+	if ret.Contrariness.Type == "" {
+		ret.Contrariness = dt.Contrariness
+	}
+	if ret.Threshold.Type == "" {
+		ret.Threshold = dt.Threshold
+	}
+	if ret.Opinion.Type == "" {
+		ret.Opinion = dt.Opinion
+	}
+	if ret.Confidence.Type == "" {
+		ret.Confidence = dt.Confidence
+	}
+	if ret.Convergence.Type == "" {
+		ret.Convergence = dt.Convergence
+	}
+	return ret
+}
+
+// Validate returns an error if the distribution cannot be sampled. A distribution with no
+// Type is valid as the default for the trait is used in its place.
+func (d TraitDistribution) Validate() error {
+	switch d.Type {
+	case "", Fixed:
+	case Normal:
+		if d.SD < 0 {
+			return fmt.Errorf("sd cannot be negative")
+		}
+	case Uniform:
+		if d.Max < d.Min {
+			return fmt.Errorf("max must not be less than min")
+		}
+	case Beta:
+		if d.Alpha <= 0 || d.Beta <= 0 {
+			return fmt.Errorf("alpha and beta must be greater than zero")
+		}
+		if d.Max < d.Min {
+			return fmt.Errorf("max must not be less than min")
+		}
+	default:
+		return fmt.Errorf("unknown distribution '%s'", d.Type)
+	}
+	return nil
+}
+
+// Sample draws a value from the distribution using the passed random source
+func (d TraitDistribution) Sample(r *rand.Rand) float64 {
+	switch d.Type {
+	case Uniform:
+		return d.Min + r.Float64()*(d.Max-d.Min)
+	case Beta:
+		x := sampleGamma(r, d.Alpha)
+		y := sampleGamma(r, d.Beta)
+		v := x / (x + y)
+		if d.Min == 0 && d.Max == 0 {
+			return v
+		}
+		return d.Min + v*(d.Max-d.Min)
+	case Fixed:
+		return d.Value
+	}
+	return r.NormFloat64()*d.SD + d.Mean
+}
+
+// sampleGamma draws a value from a gamma distribution with the passed shape and a scale of
+// one, using the method of Marsaglia and Tsang
+func sampleGamma(r *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		return sampleGamma(r, shape+1) * math.Pow(r.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := r.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := r.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
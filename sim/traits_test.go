@@ -0,0 +1,73 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDefaultTraitsMatchOriginalDistributions(t *testing.T) {
+	r1 := NewRand(5)
+	r2 := NewRand(5)
+	a := GenerateRandomAgent(r1, "a", "a", []Color{Grey, Red}, false).State()
+	influence := r2.NormFloat64()*0.25 + 1
+	susceptability := r2.NormFloat64()*0.25 + 1
+	contrariness := r2.NormFloat64()*0.15 + 0.7
+	AreEqual(t, influence, a.Influence, "Wrong default influence")
+	AreEqual(t, susceptability, a.Susceptability, "Wrong default susceptability")
+	AreEqual(t, contrariness, a.Contrariness, "Wrong default contrariness")
+}
+
+func TestTraitDistributionsSampleWithinRange(t *testing.T) {
+	r := NewRand(3)
+	uniform := TraitDistribution{Type: Uniform, Min: 0.5, Max: 1.5}
+	beta := TraitDistribution{Type: Beta, Alpha: 2, Beta: 5, Min: 1, Max: 3}
+	unitBeta := TraitDistribution{Type: Beta, Alpha: 0.5, Beta: 0.5}
+	fixed := TraitDistribution{Type: Fixed, Value: 0.3}
+	betaSum := 0.0
+	for i := 0; i < 2000; i++ {
+		v := uniform.Sample(r)
+		IsTrue(t, v >= 0.5 && v < 1.5, "Uniform sample out of range")
+		v = beta.Sample(r)
+		IsTrue(t, v >= 1 && v <= 3, "Beta sample out of range")
+		betaSum += v
+		v = unitBeta.Sample(r)
+		IsTrue(t, v >= 0 && v <= 1, "Unit beta sample out of range")
+		AreEqual(t, 0.3, fixed.Sample(r), "Fixed sample not the fixed value")
+	}
+	//The mean of beta(2,5) is 2/7, scaled to the range 1 to 3
+	IsTrue(t, math.Abs(betaSum/2000-(1+2*2.0/7)) < 0.02, "Beta samples have the wrong mean")
+}
+
+func TestGenerateHierarchyUsesTraits(t *testing.T) {
+	traits := &Traits{
+		Influence:    TraitDistribution{Type: Fixed, Value: 2},
+		Contrariness: TraitDistribution{Type: Uniform, Min: 0, Max: 0.1},
+	}
+	n, o, err := GenerateHierarchy(HierarchySpec{Levels: 3, TeamSize: 3, InitColors: []Color{Grey, Red}, MaxColors: 3, Seed: 2, Traits: traits})
+	AssertSuccess(t, err)
+	AreEqual(t, traits, o.Traits, "Traits not passed to the network options")
+	for _, a := range n.Agents() {
+		AreEqual(t, 2.0, a.State().Influence, "Influence not fixed")
+		IsTrue(t, a.State().Contrariness < 0.1, "Contrariness not drawn from the uniform distribution")
+		NotEqual(t, 0.0, a.State().Susceptability, "Susceptability not drawn from the default")
+	}
+}
+
+func TestCloneModifyUsesTraits(t *testing.T) {
+	n, _, err := GenerateHierarchy(HierarchySpec{Levels: 2, TeamSize: 3, InitColors: []Color{Grey}, MaxColors: 2, Seed: 2})
+	AssertSuccess(t, err)
+	o := &NetworkOptions{InitColors: []Color{Grey}, MaxColors: 2, Seed: 1, Traits: &Traits{Susceptability: TraitDistribution{Type: Fixed, Value: 0.5}}}
+	clone, err := o.CloneModify(n)
+	AssertSuccess(t, err)
+	for _, a := range clone.Agents() {
+		AreEqual(t, 0.5, a.State().Susceptability, "Susceptability not fixed")
+	}
+}
+
+func TestInvalidTraitsFail(t *testing.T) {
+	_, _, err := GenerateHierarchy(HierarchySpec{Levels: 2, TeamSize: 2, Seed: 1, Traits: &Traits{Influence: TraitDistribution{Type: "poisson"}}})
+	AreEqual(t, "influence: unknown distribution 'poisson'", err.Error(), "Wrong error")
+	o := &NetworkOptions{Traits: &Traits{Contrariness: TraitDistribution{Type: Beta, Alpha: 1}}}
+	_, err = o.CloneModify(&Network{})
+	AreEqual(t, "contrariness: alpha and beta must be greater than zero", err.Error(), "Wrong error")
+}
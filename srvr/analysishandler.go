@@ -0,0 +1,209 @@
+package srvr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/codeafix/orgnetsim/sim"
+	"github.com/spaceweasel/mango"
+)
+
+// Analysis is a ListItem holding the results of many runs performed on a simulation, such as
+// a batch or a sweep
+type Analysis interface {
+	ListItem
+	// Summary returns the item without its results, for listings of the items
+	Summary() interface{}
+	// HasResults returns false if the item has no results to write as csv
+	HasResults() bool
+	// WriteCsv writes the results of the item in csv format
+	WriteCsv(w io.Writer) error
+	// CsvName returns the name of the csv file the results are downloaded as
+	CsvName() string
+}
+
+// Validator is implemented by the specs posted to an AnalysisHandlerState
+type Validator interface {
+	Validate() error
+}
+
+// AnalysisHandlerState holds the state shared by the handlers that perform many runs from the
+// network of a simulation and save the results to a list on the simulation without changing
+// its steps. Listname is the name of that list, and FromRelPath returns an empty item for a
+// relative path in the list.
+type AnalysisHandlerState struct {
+	ListHandlerState
+	PersistableHandlerState
+	JobManager  JobManager
+	Listname    string
+	FromRelPath func(relPath string) Analysis
+}
+
+// NewAnalysisHandlerState returns the state shared by the handlers of a list of Analysis items
+func NewAnalysisHandlerState(fm FileManager, jm JobManager, listname string, fromRelPath func(relPath string) Analysis) AnalysisHandlerState {
+	return AnalysisHandlerState{
+		ListHandlerState{
+			FileManager: fm,
+		},
+		PersistableHandlerState{
+			FileManager: fm,
+		},
+		jm,
+		listname,
+		fromRelPath,
+	}
+}
+
+// EncodeList returns a summary of each item in the list, without the results
+func (ah *AnalysisHandlerState) EncodeList(listHolder ListHolder, listname string) (interface{}, error) {
+	items := []interface{}{}
+	for _, path := range listHolder.GetItems(listname) {
+		a := ah.FromRelPath(path)
+		err := ah.PersistableHandlerState.FileManager.Get(a.Filepath()).Read(a)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, a.Summary())
+	}
+	return items, nil
+}
+
+// title returns the name of the list starting with a capital letter, for use in messages
+func (ah *AnalysisHandlerState) title() string {
+	return strings.ToUpper(ah.Listname[:1]) + ah.Listname[1:]
+}
+
+// GetList returns a summary of the items that have been run on a simulation
+func (ah *AnalysisHandlerState) GetList(c *mango.Context) {
+	siminfo := NewSimInfo(c.RouteParams["sim_id"])
+	ah.ListHandlerState.GetList(siminfo, c, ah.Listname)
+}
+
+// ReadSpec reads the simulation in the route and binds and validates the posted spec. An
+// error is sent in the response and false is returned if either fails.
+func (ah *AnalysisHandlerState) ReadSpec(c *mango.Context, spec Validator) (*SimInfo, bool) {
+	siminfo := NewSimInfo(c.RouteParams["sim_id"])
+	err := ah.PersistableHandlerState.FileManager.Get(siminfo.Filepath()).Read(siminfo)
+	if err != nil {
+		c.Error(err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	err = c.Bind(spec)
+	if err != nil {
+		c.Error(fmt.Sprintf("%s: Error reading %sSpec", err.Error(), ah.title()), http.StatusBadRequest)
+		return nil, false
+	}
+	err = spec.Validate()
+	if err != nil {
+		c.Error(err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return siminfo, true
+}
+
+// LatestNetwork returns the network in the latest step of the simulation. An error is sent in
+// the response and false is returned if the simulation has no steps or the step cannot be read.
+func (ah *AnalysisHandlerState) LatestNetwork(c *mango.Context, siminfo *SimInfo) (sim.RelationshipMgr, bool) {
+	if len(siminfo.Steps) == 0 {
+		c.Error(fmt.Sprintf("The %s cannot be run without a hierarchy or a step containing a network", ah.Listname), http.StatusBadRequest)
+		return nil, false
+	}
+	ls := NewSimStepFromRelPath(siminfo.Steps[len(siminfo.Steps)-1])
+	err := ah.PersistableHandlerState.FileManager.Get(ls.Filepath()).Read(ls)
+	if err != nil {
+		c.Error(err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return ls.Network, true
+}
+
+// Run calls perform to do the runs and save the item, either in a background job that is
+// returned immediately if async is set, or before responding with the saved item. Runs and
+// iterations are the sizes reported on the job. The simulation is not locked, because the
+// runs do not change it and the item is added to its list with retries in case a step is
+// added to it at the same time.
+func (ah *AnalysisHandlerState) Run(c *mango.Context, siminfo *SimInfo, async bool, runs, iterations int, perform func(ctx context.Context, jp *JobProgress) (Analysis, error)) {
+	if async {
+		j, err := ah.JobManager.StartRuns(siminfo.ID, runs, iterations, func(ctx context.Context, jp *JobProgress) error {
+			_, err := perform(ctx, jp)
+			return err
+		})
+		if err != nil {
+			c.Error(err.Error(), http.StatusConflict)
+			return
+		}
+		c.RespondWith(j).WithStatus(http.StatusAccepted)
+		return
+	}
+
+	ctx := c.Request.Context()
+	a, err := perform(ctx, nil)
+	if err != nil {
+		if errors.Is(err, ctx.Err()) {
+			c.Error(fmt.Sprintf("%s: %s stopped before completion", err.Error(), ah.title()), http.StatusServiceUnavailable)
+			return
+		}
+		c.Error(err.Error(), http.StatusInternalServerError)
+		return
+	}
+	c.RespondWith(a).WithStatus(http.StatusCreated)
+}
+
+// Save adds the item to the list on the simulation and reports its path to the JobProgress
+// if it is not nil
+func (ah *AnalysisHandlerState) Save(a Analysis, siminfo *SimInfo, jp *JobProgress) error {
+	err := ah.AddItem(a, siminfo, nil, ah.Listname)
+	if err != nil {
+		return err
+	}
+	if jp != nil {
+		jp.StepSaved(a.RelPath())
+	}
+	return nil
+}
+
+// GetItem returns the item, in text/csv format if that is the content type of the request
+func (ah *AnalysisHandlerState) GetItem(a Analysis, c *mango.Context) {
+	for _, header := range c.Request.Header[http.CanonicalHeaderKey("content-type")] {
+		if header == "text/csv" {
+			ah.GetCsv(a, c)
+			return
+		}
+	}
+	ah.GetObject(a, c)
+}
+
+// GetCsv returns the results of the item in text/csv format
+func (ah *AnalysisHandlerState) GetCsv(a Analysis, c *mango.Context) {
+	err := ah.PersistableHandlerState.FileManager.Get(a.Filepath()).Read(a)
+	if err != nil {
+		c.Error(err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !a.HasResults() {
+		c.Error(fmt.Sprintf("this %s has no results", ah.Listname), http.StatusBadRequest)
+		return
+	}
+	var buffer bytes.Buffer
+	err = a.WriteCsv(&buffer)
+	if err != nil {
+		c.Error(err.Error(), http.StatusInternalServerError)
+		return
+	}
+	name := a.CsvName()
+	r := c.RespondWith(buffer.String())
+	r.WithContentType("text/csv")
+	r.WithHeader(http.CanonicalHeaderKey("Content-Disposition"), fmt.Sprintf("attachment; filename=\"%s.csv\"; filename*=\"%s.csv\"", name, name))
+	r.WithStatus(http.StatusOK)
+}
+
+// DeleteFromSim removes the item from the list on the simulation in the route
+func (ah *AnalysisHandlerState) DeleteFromSim(a Analysis, c *mango.Context) {
+	siminfo := NewSimInfo(c.RouteParams["sim_id"])
+	ah.DeleteItem(a, siminfo, c, ah.Listname)
+}
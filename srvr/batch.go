@@ -0,0 +1,104 @@
+package srvr
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/codeafix/orgnetsim/sim"
+	"github.com/google/uuid"
+)
+
+// BatchSpec specifies a batch of runs to perform on a simulation. If Hierarchy is set a new
+// network is generated from it for every run, using the seed of the run, otherwise every run
+// starts from a copy of the network in the latest step of the simulation. If Async is set the
+// batch is performed in a background job and the job is returned immediately.
+type BatchSpec struct {
+	sim.BatchSpec
+	Hierarchy *sim.HierarchySpec `json:"hierarchy,omitempty"`
+	Async     bool               `json:"async,omitempty"`
+}
+
+// Batch holds the aggregated results of a batch of runs on a simulation, together with
+// the spec the batch was run with
+type Batch struct {
+	TimestampHolder
+	ID       string            `json:"id"`
+	ParentID string            `json:"parent"`
+	Spec     BatchSpec         `json:"spec"`
+	Results  *sim.BatchResults `json:"results"`
+}
+
+// BatchSummary holds a summary of a batch, excluding the results. This is used for listings
+// of batches to reduce payload size.
+type BatchSummary struct {
+	ID       string    `json:"id"`
+	ParentID string    `json:"parent"`
+	Spec     BatchSpec `json:"spec"`
+}
+
+// CreateBatch creates a new Batch object with a new ID
+func CreateBatch(parentID string) *Batch {
+	return NewBatch(uuid.New().String(), parentID)
+}
+
+// NewBatch returns a Batch object for the passed ID
+func NewBatch(id string, parentID string) *Batch {
+	return &Batch{
+		ID:       id,
+		ParentID: parentID,
+	}
+}
+
+// NewBatchFromRelPath returns a Batch object extracting IDs from the relative path in the
+// passed string
+func NewBatchFromRelPath(relPath string) *Batch {
+	elems := strings.Split(relPath, "/")
+	return NewBatch(elems[len(elems)-1], elems[len(elems)-3])
+}
+
+// CopyValues copies the values from the passed Batch object to this object.
+// Returns an error if the values could not be copied
+func (b *Batch) CopyValues(obj Persistable) error {
+	bToCopy, ok := obj.(*Batch)
+	if !ok {
+		return fmt.Errorf("failed to copy values")
+	}
+	b.Spec = bToCopy.Spec
+	b.Results = bToCopy.Results
+	return nil
+}
+
+// Filepath returns the Filepath used by this item
+func (b *Batch) Filepath() string {
+	return fmt.Sprintf("batch_%s.json", b.ID)
+}
+
+// RelPath returns the relative API path for this item
+func (b *Batch) RelPath() string {
+	return fmt.Sprintf("/api/simulation/%s/batch/%s", b.ParentID, b.ID)
+}
+
+// Summary returns the batch without its results
+func (b *Batch) Summary() interface{} {
+	return &BatchSummary{
+		ID:       b.ID,
+		ParentID: b.ParentID,
+		Spec:     b.Spec,
+	}
+}
+
+// HasResults returns false if the batch has no results
+func (b *Batch) HasResults() bool {
+	return b.Results != nil
+}
+
+// WriteCsv writes the results of the batch in csv format
+func (b *Batch) WriteCsv(w io.Writer) error {
+	return b.Results.WriteCsv(w)
+}
+
+// CsvName returns the name of the csv file the results are downloaded as
+func (b *Batch) CsvName() string {
+	return "batch-" + b.ID
+}
@@ -0,0 +1,109 @@
+package srvr
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/codeafix/orgnetsim/sim"
+	"github.com/spaceweasel/mango"
+)
+
+// BatchHandlerState holds state data for the BatchHandler
+type BatchHandlerState struct {
+	AnalysisHandlerState
+}
+
+// BatchHandler provides methods to run, read and delete batches of runs on a simulation
+type BatchHandler interface {
+	mango.Registerer
+	GetList(c *mango.Context)
+	Post(c *mango.Context)
+	Get(c *mango.Context)
+	Delete(c *mango.Context)
+}
+
+// NewBatchHandler returns a new instance of BatchHandler
+func NewBatchHandler(fm FileManager, jm JobManager) BatchHandler {
+	bh := &BatchHandlerState{
+		NewAnalysisHandlerState(fm, jm, "batch", func(relPath string) Analysis {
+			return NewBatchFromRelPath(relPath)
+		}),
+	}
+	bh.ListHandlerState.EncodeFunc = bh.EncodeList
+	return bh
+}
+
+// Register the routes for this routehandler
+func (bh *BatchHandlerState) Register(r *mango.Router) {
+	r.Get("/api/simulation/{sim_id}/batch", bh.GetList)
+	r.Post("/api/simulation/{sim_id}/batch", bh.Post)
+	r.Get("/api/simulation/{sim_id}/batch/{batch_id}", bh.Get)
+	r.Delete("/api/simulation/{sim_id}/batch/{batch_id}", bh.Delete)
+}
+
+// Post performs a batch of runs on the simulation and saves the aggregated results. The
+// runs start from the network in the latest step, or from networks generated from the
+// HierarchySpec in the BatchSpec if one is given. The simulation itself is not changed.
+func (bh *BatchHandlerState) Post(c *mango.Context) {
+	bs := BatchSpec{}
+	siminfo, ok := bh.ReadSpec(c, &bs)
+	if !ok {
+		return
+	}
+	var source sim.NetworkSource
+	if bs.Hierarchy != nil {
+		source = sim.HierarchySource(*bs.Hierarchy)
+	} else {
+		n, ok := bh.LatestNetwork(c, siminfo)
+		if !ok {
+			return
+		}
+		var err error
+		source, err = sim.CopySource(n)
+		if err != nil {
+			c.Error(err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	//Record the seed so that the batch can be reproduced
+	if bs.Seed == 0 {
+		bs.Seed = sim.NewSeed()
+	}
+	bh.Run(c, siminfo, bs.Async, bs.Runs, bs.Iterations, func(ctx context.Context, jp *JobProgress) (Analysis, error) {
+		return bh.runBatch(ctx, source, siminfo, bs, jp)
+	})
+}
+
+// runBatch performs the runs in the BatchSpec and adds the batch to the simulation. The
+// number of runs completed is reported to the JobProgress if it is not nil. Nothing is saved
+// if the context is cancelled before all the runs complete.
+func (bh *BatchHandlerState) runBatch(ctx context.Context, source sim.NetworkSource, siminfo *SimInfo, bs BatchSpec, jp *JobProgress) (*Batch, error) {
+	results, err := sim.RunBatch(ctx, bs.BatchSpec, source, func(completed int) {
+		if jp != nil {
+			jp.RunsCompleted(completed)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	b := CreateBatch(siminfo.ID)
+	b.Spec = bs
+	b.Spec.Async = false
+	b.Spec.Percentiles = results.Percentiles
+	b.Results = results
+	err = bh.Save(b, siminfo, jp)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Get returns a batch, in text/csv format if that is the content type of the request
+func (bh *BatchHandlerState) Get(c *mango.Context) {
+	bh.GetItem(NewBatch(c.RouteParams["batch_id"], c.RouteParams["sim_id"]), c)
+}
+
+// Delete removes a batch from the simulation
+func (bh *BatchHandlerState) Delete(c *mango.Context) {
+	bh.DeleteFromSim(NewBatch(c.RouteParams["batch_id"], c.RouteParams["sim_id"]), c)
+}
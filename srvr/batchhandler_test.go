@@ -0,0 +1,191 @@
+package srvr
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/codeafix/orgnetsim/sim"
+	"github.com/spaceweasel/mango"
+)
+
+func CreateBatchHandlerBrowser() (*mango.Browser, *TestFileManager, *TestFileUpdater, *TestFileUpdater, string) {
+	tfm, simfu, _, dfu, _, simid := CreateTestFileManagerWithSteps(2)
+	r := CreateRouter(tfm)
+	return mango.NewBrowser(r), tfm, simfu, dfu, simid
+}
+
+func AddTestBatch(tfm *TestFileManager, simfu *TestFileUpdater, simid string) *Batch {
+	b := CreateBatch(simid)
+	b.Spec = BatchSpec{BatchSpec: sim.BatchSpec{Runs: 2, Iterations: 1, Seed: 3, Percentiles: []float64{50}}}
+	b.Results = &sim.BatchResults{
+		Runs:          2,
+		Iterations:    1,
+		Seeds:         []int64{1, 2},
+		Percentiles:   []float64{50},
+		Colors:        [][]sim.Summary{{{Mean: 1, Median: 1, Min: 0, Max: 2, Percentiles: []float64{1}}}},
+		Conversations: []sim.Summary{{Mean: 2, Median: 2, Min: 1, Max: 3, Percentiles: []float64{2}}},
+	}
+	tfm.Add(b.Filepath(), &TestFileUpdater{Obj: b, Filepath: b.Filepath()})
+	si := simfu.Obj.(*SimInfo)
+	si.Batches = append(si.Batches, b.RelPath())
+	return b
+}
+
+func TestPostBatchRunsFromLatestStep(t *testing.T) {
+	br, _, simfu, dfu, simid := CreateBatchHandlerBrowser()
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/batch", simid), `{"runs":3,"iterations":5,"seed":4}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	b := dfu.Obj.(*Batch)
+	AreEqual(t, simid, b.ParentID, "Wrong parent")
+	AreEqual(t, int64(4), b.Spec.Seed, "Wrong seed")
+	AreEqual(t, 3, b.Results.Runs, "Wrong number of runs")
+	AreEqual(t, 5, len(b.Results.Colors), "Wrong number of iterations")
+	AreEqual(t, 4, len(b.Results.Colors[0]), "Wrong number of colors")
+	total := b.Results.Colors[0][sim.Blue].Mean + b.Results.Colors[0][sim.Red].Mean + b.Results.Colors[0][sim.Grey].Mean + b.Results.Colors[0][sim.Green].Mean
+	IsTrue(t, math.Abs(total-3.0) < 1e-9, fmt.Sprintf("Mean counts do not add up to the number of agents: %g", total))
+	si := simfu.Obj.(*SimInfo)
+	AreEqual(t, 1, len(si.Batches), "Batch not added to the simulation")
+	AreEqual(t, b.RelPath(), si.Batches[0], "Wrong batch path")
+	AreEqual(t, 3, len(si.Steps), "Steps should not change")
+}
+
+func TestPostBatchGeneratesNetworksFromHierarchy(t *testing.T) {
+	br, _, simfu, dfu, simid := CreateBatchHandlerBrowser()
+	simfu.Obj.(*SimInfo).Steps = []string{}
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	data := `{"runs":4,"iterations":5,"parallel":2,"percentiles":[10,90],"hierarchy":{"levels":2,"teamSize":3,"initColors":[0,1,2],"maxColors":3}}`
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/batch", simid), data, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	b := dfu.Obj.(*Batch)
+	NotEqual(t, int64(0), b.Spec.Seed, "Seed not recorded")
+	AreEqual(t, 4, b.Results.Runs, "Wrong number of runs")
+	AreEqual(t, 3, len(b.Results.Colors[0]), "Wrong number of colors")
+	AreEqual(t, 2, len(b.Results.Conversations[0].Percentiles), "Wrong number of percentiles")
+}
+
+func TestPostBatchFailsWithoutNetwork(t *testing.T) {
+	br, _, simfu, _, simid := CreateBatchHandlerBrowser()
+	simfu.Obj.(*SimInfo).Steps = []string{}
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/batch", simid), `{"runs":3,"iterations":5}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusBadRequest, resp.Code, "Not Bad request")
+}
+
+func TestPostBatchFailsWithInvalidSpec(t *testing.T) {
+	br, _, _, _, simid := CreateBatchHandlerBrowser()
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/batch", simid), `{"runs":0,"iterations":5}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusBadRequest, resp.Code, "Not Bad request")
+	AreEqual(t, "runs and iterations must be greater than zero", strings.TrimSpace(resp.Body.String()), "Incorrect error response")
+}
+
+func TestPostBatchAsyncStartsJob(t *testing.T) {
+	br, _, simfu, _, simid := CreateBatchHandlerBrowser()
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/batch", simid), `{"runs":3,"iterations":5,"async":true}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusAccepted, resp.Code, "Not accepted")
+	j := Job{}
+	AssertSuccess(t, json.Unmarshal(resp.Body.Bytes(), &j))
+	AreEqual(t, 3, j.Runs, "Wrong number of runs")
+	j = WaitForJob(t, br, j.ID)
+	AreEqual(t, JobCompleted, j.State, "Job not completed")
+	AreEqual(t, 3, j.RunsCompleted, "Completed runs not reported")
+	AreEqual(t, 1, len(j.StepPaths), "Batch path not reported")
+	AreEqual(t, j.StepPaths[0], simfu.Obj.(*SimInfo).Batches[0], "Wrong batch path reported")
+}
+
+func TestPostBatchNotBlockedByRunInProgress(t *testing.T) {
+	tfm, _, _, _, _, simid := CreateTestFileManagerWithSteps(2)
+	jm := NewJobManager()
+	r := mango.NewRouter()
+	r.RegisterModules([]mango.Registerer{NewBatchHandler(tfm, jm), NewJobHandler(jm)})
+	br := mango.NewBrowser(r)
+	j := StartBlockingJob(t, jm, simid)
+	defer jm.Cancel(j.ID)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/batch", simid), `{"runs":2,"iterations":5}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Batch blocked by the run in progress")
+	resp, err = br.PostS(fmt.Sprintf("/api/simulation/%s/batch", simid), `{"runs":2,"iterations":5,"async":true}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusAccepted, resp.Code, "Batch job blocked by the run in progress")
+	bj := Job{}
+	AssertSuccess(t, json.Unmarshal(resp.Body.Bytes(), &bj))
+	AreEqual(t, JobCompleted, WaitForJob(t, br, bj.ID).State, "Batch job not completed")
+	IsFalse(t, jm.LockSim(simid), "Simulation unlocked by the batch job")
+}
+
+func TestGetBatchListReturnsSummaries(t *testing.T) {
+	br, tfm, simfu, _, simid := CreateBatchHandlerBrowser()
+	b := AddTestBatch(tfm, simfu, simid)
+
+	resp, err := br.Get(fmt.Sprintf("/api/simulation/%s/batch", simid), http.Header{})
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not OK")
+	items := []*BatchSummary{}
+	AssertSuccess(t, json.Unmarshal(resp.Body.Bytes(), &items))
+	AreEqual(t, 1, len(items), "Wrong number of batches")
+	AreEqual(t, b.ID, items[0].ID, "Wrong batch")
+	AreEqual(t, 2, items[0].Spec.Runs, "Wrong spec")
+	IsFalse(t, strings.Contains(resp.Body.String(), "conversations"), "Results included in the list")
+}
+
+func TestGetBatchSuccess(t *testing.T) {
+	br, tfm, simfu, _, simid := CreateBatchHandlerBrowser()
+	b := AddTestBatch(tfm, simfu, simid)
+
+	resp, err := br.Get(fmt.Sprintf("/api/simulation/%s/batch/%s", simid, b.ID), http.Header{})
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not OK")
+	rb := &Batch{}
+	AssertSuccess(t, json.Unmarshal(resp.Body.Bytes(), rb))
+	AreEqual(t, b.ID, rb.ID, "Wrong batch")
+	AreEqual(t, 2.0, rb.Results.Conversations[0].Mean, "Wrong results")
+}
+
+func TestGetBatchAsCsv(t *testing.T) {
+	br, tfm, simfu, _, simid := CreateBatchHandlerBrowser()
+	b := AddTestBatch(tfm, simfu, simid)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "text/csv")
+	resp, err := br.Get(fmt.Sprintf("/api/simulation/%s/batch/%s", simid, b.ID), hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not OK")
+	AreEqual(t, "Iteration,Series,Mean,Median,Min,Max,P50\n0,Grey,1,1,0,2,1\n0,Conversations,2,2,1,3,2\n", resp.Body.String(), "Wrong csv")
+	IsTrue(t, strings.Contains(resp.Header().Get("Content-Disposition"), "batch-"+b.ID+".csv"), "Wrong filename")
+}
+
+func TestDeleteBatchSuccess(t *testing.T) {
+	br, tfm, simfu, _, simid := CreateBatchHandlerBrowser()
+	b := AddTestBatch(tfm, simfu, simid)
+
+	resp, err := br.Delete(fmt.Sprintf("/api/simulation/%s/batch/%s", simid, b.ID), http.Header{})
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not OK")
+	AreEqual(t, 0, len(simfu.Obj.(*SimInfo).Batches), "Batch not removed from the simulation")
+	AreEqual(t, 3, len(simfu.Obj.(*SimInfo).Steps), "Steps should not change")
+	IsTrue(t, tfm.Get(b.Filepath()).(*TestFileUpdater).DeleteCalled, "Batch file not deleted")
+}
@@ -0,0 +1,248 @@
+package srvr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobState is the state of a background job
+type JobState string
+
+// The list of states a background job can be in
+const (
+	JobRunning   JobState = "running"
+	JobCompleted JobState = "completed"
+	JobFailed    JobState = "failed"
+	JobCancelled JobState = "cancelled"
+)
+
+// jobRetention is how long a job that has finished is kept by the JobManager
+const jobRetention = 24 * time.Hour
+
+// Job records the progress of a simulation run that is executing in the background.
+// Step is the number of the step currently running (starting at 1), and Iteration is the
+// number of iterations completed within that step. StepPaths holds the relative paths of
+// the steps that have been saved so far. A job performing a number of independent runs,
+// such as a batch, has a single step holding the saved results, and RunsCompleted counts
+// the runs completed out of Runs.
+type Job struct {
+	ID            string     `json:"id"`
+	SimID         string     `json:"simId"`
+	State         JobState   `json:"state"`
+	Steps         int        `json:"steps"`
+	Iterations    int        `json:"iterations"`
+	Step          int        `json:"step"`
+	Iteration     int        `json:"iteration"`
+	Runs          int        `json:"runs,omitempty"`
+	RunsCompleted int        `json:"runsCompleted,omitempty"`
+	StepPaths     []string   `json:"stepPaths"`
+	Error         string     `json:"error,omitempty"`
+	Started       time.Time  `json:"started"`
+	Finished      *time.Time `json:"finished,omitempty"`
+	cancel        context.CancelFunc
+}
+
+// JobManager keeps track of the jobs running in the background. Jobs are only held in
+// memory so they are lost if the server restarts, although the steps they have saved are not.
+// The JobManager also ensures only one run at a time can add steps to a simulation.
+type JobManager interface {
+	Start(simID string, steps, iterations int, run func(ctx context.Context, jp *JobProgress) error) (Job, error)
+	StartRuns(simID string, runs, iterations int, run func(ctx context.Context, jp *JobProgress) error) (Job, error)
+	Get(id string) (Job, bool)
+	Cancel(id string) (Job, bool)
+	LockSim(simID string) bool
+	UnlockSim(simID string)
+}
+
+// JobProgress is passed to the function run by a job so that it can report its progress
+type JobProgress struct {
+	jm  *jobManager
+	job *Job
+}
+
+// jobManager is the in memory implementation of JobManager
+type jobManager struct {
+	lock sync.Mutex
+	jobs map[string]*Job
+	sims map[string]bool
+}
+
+// NewJobManager returns a new instance of a JobManager
+func NewJobManager() JobManager {
+	return &jobManager{
+		jobs: map[string]*Job{},
+		sims: map[string]bool{},
+	}
+}
+
+// LockSim marks the simulation as having a run in progress, it returns false if the
+// simulation already has a run in progress
+func (jm *jobManager) LockSim(simID string) bool {
+	jm.lock.Lock()
+	defer jm.lock.Unlock()
+	if jm.sims[simID] {
+		return false
+	}
+	jm.sims[simID] = true
+	return true
+}
+
+// UnlockSim marks the simulation as no longer having a run in progress
+func (jm *jobManager) UnlockSim(simID string) {
+	jm.lock.Lock()
+	defer jm.lock.Unlock()
+	delete(jm.sims, simID)
+}
+
+// Start runs the passed function in the background as a new job on the simulation. The
+// simulation is locked while the job runs, and an error is returned if the simulation
+// already has a run in progress. If the function returns the error from the context, or an
+// error wrapping it, the job is recorded as cancelled, any other error is recorded as a failure.
+func (jm *jobManager) Start(simID string, steps, iterations int, run func(ctx context.Context, jp *JobProgress) error) (Job, error) {
+	if !jm.LockSim(simID) {
+		return Job{}, fmt.Errorf("a run is already in progress on this simulation")
+	}
+	return jm.start(&Job{
+		SimID:      simID,
+		Steps:      steps,
+		Iterations: iterations,
+	}, true, run), nil
+}
+
+// StartRuns runs the passed function in the background as a new job performing a number of
+// independent runs from the simulation, which only adds its results to the simulation when
+// they are complete. The simulation is not locked, so steps can be added to it while the job
+// runs, and steps being added do not prevent the job from starting.
+func (jm *jobManager) StartRuns(simID string, runs, iterations int, run func(ctx context.Context, jp *JobProgress) error) (Job, error) {
+	return jm.start(&Job{
+		SimID:      simID,
+		Steps:      1,
+		Iterations: iterations,
+		Runs:       runs,
+	}, false, run), nil
+}
+
+// start runs the passed function in the background as the passed job, and returns a copy of
+// the job. If locked is set the simulation is unlocked when the job finishes.
+func (jm *jobManager) start(j *Job, locked bool, run func(ctx context.Context, jp *JobProgress) error) Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	j.ID = uuid.New().String()
+	j.State = JobRunning
+	j.StepPaths = []string{}
+	j.Started = time.Now()
+	j.cancel = cancel
+
+	jm.lock.Lock()
+	jm.removeExpired()
+	jm.jobs[j.ID] = j
+	snapshot := j.snapshot()
+	jm.lock.Unlock()
+
+	go func() {
+		defer cancel()
+		var err error
+		func() {
+			//A panic in the job must not take down the server
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("job failed: %v", r)
+				}
+			}()
+			err = run(ctx, &JobProgress{jm: jm, job: j})
+		}()
+
+		jm.lock.Lock()
+		defer jm.lock.Unlock()
+		if locked {
+			delete(jm.sims, j.SimID)
+		}
+		finished := time.Now()
+		j.Finished = &finished
+		switch {
+		case err == nil:
+			j.State = JobCompleted
+		case errors.Is(err, context.Canceled):
+			j.State = JobCancelled
+			j.Error = err.Error()
+		default:
+			j.State = JobFailed
+			j.Error = err.Error()
+		}
+	}()
+	return snapshot
+}
+
+// Get returns a copy of the job with the passed ID, returns false if the job is not found
+func (jm *jobManager) Get(id string) (Job, bool) {
+	jm.lock.Lock()
+	defer jm.lock.Unlock()
+	j, exists := jm.jobs[id]
+	if !exists {
+		return Job{}, false
+	}
+	return j.snapshot(), true
+}
+
+// Cancel asks the job with the passed ID to stop at the end of its current iteration and
+// returns a copy of the job, returns false if the job is not found
+func (jm *jobManager) Cancel(id string) (Job, bool) {
+	jm.lock.Lock()
+	defer jm.lock.Unlock()
+	j, exists := jm.jobs[id]
+	if !exists {
+		return Job{}, false
+	}
+	j.cancel()
+	return j.snapshot(), true
+}
+
+// removeExpired deletes finished jobs older than the retention period, the lock must be held
+func (jm *jobManager) removeExpired() {
+	for id, j := range jm.jobs {
+		if j.State != JobRunning && time.Since(*j.Finished) > jobRetention {
+			delete(jm.jobs, id)
+		}
+	}
+}
+
+// snapshot returns a copy of the job that is safe to use outside the lock
+func (j *Job) snapshot() Job {
+	cp := *j
+	cp.StepPaths = append([]string{}, j.StepPaths...)
+	return cp
+}
+
+// StartStep records that the job has started running the passed step (starting at 1)
+func (jp *JobProgress) StartStep(step int) {
+	jp.jm.lock.Lock()
+	defer jp.jm.lock.Unlock()
+	jp.job.Step = step
+	jp.job.Iteration = 0
+}
+
+// Iteration records the number of iterations completed in the current step, it implements
+// sim.Observer so it can be passed to RunContext
+func (jp *JobProgress) Iteration(i int, colors []int, conversations int) {
+	jp.jm.lock.Lock()
+	defer jp.jm.lock.Unlock()
+	jp.job.Iteration = i + 1
+}
+
+// RunsCompleted records the number of runs the job has completed
+func (jp *JobProgress) RunsCompleted(completed int) {
+	jp.jm.lock.Lock()
+	defer jp.jm.lock.Unlock()
+	jp.job.RunsCompleted = completed
+}
+
+// StepSaved records the relative path of a step that has been saved
+func (jp *JobProgress) StepSaved(relPath string) {
+	jp.jm.lock.Lock()
+	defer jp.jm.lock.Unlock()
+	jp.job.StepPaths = append(jp.job.StepPaths, relPath)
+}
@@ -0,0 +1,53 @@
+package srvr
+
+import (
+	"net/http"
+
+	"github.com/spaceweasel/mango"
+)
+
+// JobHandlerState holds state data for the JobHandler
+type JobHandlerState struct {
+	JobManager JobManager
+}
+
+// JobHandler provides Read/Cancel methods for jobs running in the background
+type JobHandler interface {
+	mango.Registerer
+	Get(c *mango.Context)
+	Cancel(c *mango.Context)
+}
+
+// NewJobHandler returns a new instance of JobHandler
+func NewJobHandler(jm JobManager) JobHandler {
+	return &JobHandlerState{
+		JobManager: jm,
+	}
+}
+
+// Register the routes for this routehandler
+func (jh *JobHandlerState) Register(r *mango.Router) {
+	r.Get("/api/jobs/{job_id}", jh.Get)
+	r.Delete("/api/jobs/{job_id}", jh.Cancel)
+}
+
+// Get returns the current state of a job
+func (jh *JobHandlerState) Get(c *mango.Context) {
+	j, exists := jh.JobManager.Get(c.RouteParams["job_id"])
+	if !exists {
+		c.Error("Job not found", http.StatusNotFound)
+		return
+	}
+	c.RespondWith(j).WithStatus(http.StatusOK)
+}
+
+// Cancel asks a job to stop at the end of its current iteration. The steps it has already
+// saved are kept, and the step it is running is saved with the iterations completed so far.
+func (jh *JobHandlerState) Cancel(c *mango.Context) {
+	j, exists := jh.JobManager.Cancel(c.RouteParams["job_id"])
+	if !exists {
+		c.Error("Job not found", http.StatusNotFound)
+		return
+	}
+	c.RespondWith(j).WithStatus(http.StatusAccepted)
+}
@@ -0,0 +1,174 @@
+package srvr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/spaceweasel/mango"
+)
+
+func WaitForJob(t *testing.T, br *mango.Browser, id string) Job {
+	for i := 0; i < 500; i++ {
+		resp, err := br.Get(fmt.Sprintf("/api/jobs/%s", id), http.Header{})
+		AssertSuccess(t, err)
+		AreEqual(t, http.StatusOK, resp.Code, "Not OK")
+		j := Job{}
+		err = json.Unmarshal(resp.Body.Bytes(), &j)
+		AssertSuccess(t, err)
+		if j.State != JobRunning {
+			return j
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish", id)
+	return Job{}
+}
+
+func CreateJobHandlerBrowser(jm JobManager) *mango.Browser {
+	r := mango.NewRouter()
+	r.RegisterModules([]mango.Registerer{
+		NewJobHandler(jm),
+	})
+	return mango.NewBrowser(r)
+}
+
+func StartBlockingJob(t *testing.T, jm JobManager, simid string) Job {
+	j, err := jm.Start(simid, 1, 1, func(ctx context.Context, jp *JobProgress) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	AssertSuccess(t, err)
+	return j
+}
+
+func TestPostRunAsyncReturnsJob(t *testing.T) {
+	br, simfu, _, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+	rs := RunSpec{
+		Iterations: 5,
+		Steps:      2,
+		Async:      true,
+	}
+	rss, err := json.Marshal(rs)
+	AssertSuccess(t, err)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), string(rss), hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusAccepted, resp.Code, "Not accepted")
+	j := Job{}
+	err = json.Unmarshal(resp.Body.Bytes(), &j)
+	AssertSuccess(t, err)
+	NotEqual(t, "", j.ID, "Job has no ID")
+	AreEqual(t, simid, j.SimID, "Wrong simulation ID on job")
+	AreEqual(t, 2, j.Steps, "Wrong number of steps on job")
+	AreEqual(t, 5, j.Iterations, "Wrong number of iterations on job")
+
+	j = WaitForJob(t, br, j.ID)
+	AreEqual(t, JobCompleted, j.State, "Job not completed")
+	AreEqual(t, "", j.Error, "Job has an error")
+	AreEqual(t, 2, j.Step, "Wrong current step")
+	AreEqual(t, 5, j.Iteration, "Wrong current iteration")
+	AreEqual(t, 2, len(j.StepPaths), "Wrong number of steps saved")
+	NotEqual(t, nil, j.Finished, "Finished time not set")
+
+	//Each step is added to the simulation as it is saved
+	si := simfu.Obj.(*SimInfo)
+	AreEqual(t, 5, len(si.Steps), "Steps not added to the simulation")
+	AreEqual(t, j.StepPaths[1], si.Steps[4], "Last step saved is not the last step in the simulation")
+	ns := dfu.Obj.(*SimStep)
+	AreEqual(t, 5, ns.Results.Iterations, "Wrong number of iterations in the last step")
+}
+
+func TestPostRunAsyncFailsWithZeroIterations(t *testing.T) {
+	br, _, _, _, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":0,"async":true}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusBadRequest, resp.Code, "Not Bad request")
+}
+
+func TestPostRunAsyncRecordsFailure(t *testing.T) {
+	br, _, _, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+	dfu.CreateErr = fmt.Errorf("disk full")
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":2,"iterations":5,"async":true}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusAccepted, resp.Code, "Not accepted")
+	j := Job{}
+	err = json.Unmarshal(resp.Body.Bytes(), &j)
+	AssertSuccess(t, err)
+
+	j = WaitForJob(t, br, j.ID)
+	AreEqual(t, JobFailed, j.State, "Job not failed")
+	AreEqual(t, "disk full", j.Error, "Wrong error on job")
+	AreEqual(t, 0, len(j.StepPaths), "No steps should have been saved")
+}
+
+func TestGetJobFailsWhenNotFound(t *testing.T) {
+	br := CreateJobHandlerBrowser(NewJobManager())
+	resp, err := br.Get("/api/jobs/unknown", http.Header{})
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusNotFound, resp.Code, "Not Not found")
+}
+
+func TestDeleteJobCancelsJob(t *testing.T) {
+	jm := NewJobManager()
+	br := CreateJobHandlerBrowser(jm)
+	j := StartBlockingJob(t, jm, "sim1")
+	AreEqual(t, JobRunning, j.State, "Job not running")
+
+	resp, err := br.Delete(fmt.Sprintf("/api/jobs/%s", j.ID), http.Header{})
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusAccepted, resp.Code, "Not accepted")
+
+	j = WaitForJob(t, br, j.ID)
+	AreEqual(t, JobCancelled, j.State, "Job not cancelled")
+	AreEqual(t, context.Canceled.Error(), j.Error, "Wrong error on job")
+}
+
+func TestDeleteJobCancelsJobReturningWrappedError(t *testing.T) {
+	jm := NewJobManager()
+	br := CreateJobHandlerBrowser(jm)
+	j, err := jm.Start("sim1", 1, 1, func(ctx context.Context, jp *JobProgress) error {
+		<-ctx.Done()
+		return fmt.Errorf("step 1: %w", ctx.Err())
+	})
+	AssertSuccess(t, err)
+
+	jm.Cancel(j.ID)
+	j = WaitForJob(t, br, j.ID)
+	AreEqual(t, JobCancelled, j.State, "Job returning a wrapped cancellation not cancelled")
+	AreEqual(t, "step 1: context canceled", j.Error, "Wrong error on job")
+}
+
+func TestDeleteJobFailsWhenNotFound(t *testing.T) {
+	br := CreateJobHandlerBrowser(NewJobManager())
+	resp, err := br.Delete("/api/jobs/unknown", http.Header{})
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusNotFound, resp.Code, "Not Not found")
+}
+
+func TestOnlyOneRunAtATimeOnASimulation(t *testing.T) {
+	jm := NewJobManager()
+	br := CreateJobHandlerBrowser(jm)
+	j := StartBlockingJob(t, jm, "sim1")
+
+	_, err := jm.Start("sim1", 1, 1, func(ctx context.Context, jp *JobProgress) error { return nil })
+	NotEqual(t, nil, err, "Second job started on the same simulation")
+	IsFalse(t, jm.LockSim("sim1"), "Simulation locked while a job is running")
+	IsTrue(t, jm.LockSim("sim2"), "Unable to lock a different simulation")
+	jm.UnlockSim("sim2")
+
+	jm.Cancel(j.ID)
+	WaitForJob(t, br, j.ID)
+	IsTrue(t, jm.LockSim("sim1"), "Simulation not unlocked when the job finished")
+}
@@ -3,6 +3,7 @@ package srvr
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -16,6 +17,8 @@ import (
 type SimHandlerState struct {
 	ListHandlerState
 	PersistableHandlerState
+	JobManager JobManager
+	Streams    *StreamHub
 }
 
 // SimHandler provides Read/Update methods for simulations on the simulation list
@@ -39,7 +42,7 @@ type ParseBody struct {
 }
 
 // NewSimHandler returns a new instance of SimHandler
-func NewSimHandler(fm FileManager) SimHandler {
+func NewSimHandler(fm FileManager, jm JobManager, hub *StreamHub) SimHandler {
 	sh := &SimHandlerState{
 		ListHandlerState{
 			FileManager: fm,
@@ -47,6 +50,8 @@ func NewSimHandler(fm FileManager) SimHandler {
 		PersistableHandlerState{
 			FileManager: fm,
 		},
+		jm,
+		hub,
 	}
 	sh.ListHandlerState.EncodeFunc = sh.EncodeStepList
 	return sh
@@ -71,6 +76,7 @@ func (sh *SimHandlerState) EncodeStepList(listHolder ListHolder, listname string
 			Results:         fullStep.Results,
 			ID:              fullStep.ID,
 			ParentID:        fullStep.ParentID,
+			Seed:            fullStep.Seed,
 		}
 		items = append(items, summaryItem)
 	}
@@ -136,13 +142,27 @@ func (sh *SimHandlerState) GetResultsCsv(c *mango.Context) {
 	for c := 0; c < maxColors; c++ {
 		buffer.WriteString(fmt.Sprintf("%s,", sim.Color(c).String()))
 	}
-	buffer.WriteString("Conversations\n")
+	buffer.WriteString("Conversations")
+	if results.Links != nil {
+		buffer.WriteString(",Links")
+	}
+	if results.BroadcastConversions != nil {
+		buffer.WriteString(",Peer Conversions,Broadcast Conversions")
+	}
+	buffer.WriteString("\n")
 
 	for i := 0; i < results.Iterations; i++ {
 		for j := 0; j < maxColors; j++ {
 			buffer.WriteString(fmt.Sprintf("%d,", results.Colors[i][j]))
 		}
-		buffer.WriteString(fmt.Sprintf("%d\n", results.Conversations[i]))
+		buffer.WriteString(fmt.Sprintf("%d", results.Conversations[i]))
+		if results.Links != nil {
+			buffer.WriteString(fmt.Sprintf(",%d", results.Links[i]))
+		}
+		if results.BroadcastConversions != nil {
+			buffer.WriteString(fmt.Sprintf(",%d,%d", results.Conversions[i], results.BroadcastConversions[i]))
+		}
+		buffer.WriteString("\n")
 	}
 
 	r := c.RespondWith(buffer.String())
@@ -161,7 +181,11 @@ func (sh *SimHandlerState) GetResults(c *mango.Context) {
 	c.RespondWith(results).WithStatus(http.StatusOK)
 }
 
-// collectAllResults gets a concatenated set of results from all the steps in this simulation
+// collectAllResults gets a concatenated set of results from all the steps in this simulation.
+// The iterations of the Interventions and Stopped condition are made relative to the start of
+// the simulation. The active Links and the peer and broadcast Conversions are only recorded by
+// the steps that were run with link dynamics or broadcasts, they are zero for the iterations of
+// the other steps.
 func (sh *SimHandlerState) collectAllResults(c *mango.Context) (sim.Results, string, error) {
 	siminfo := sh.readSiminfo(c)
 	results := sim.Results{
@@ -180,13 +204,46 @@ func (sh *SimHandlerState) collectAllResults(c *mango.Context) (sim.Results, str
 		if err != nil {
 			return results, "", err
 		}
+		if step.Results.Stopped != nil {
+			//Report the iteration the run stopped on relative to the start of the simulation
+			results.Stopped = &sim.Stopped{
+				Condition: step.Results.Stopped.Condition,
+				Iteration: results.Iterations + step.Results.Stopped.Iteration,
+			}
+		}
+		for _, ai := range step.Results.Interventions {
+			ai.Iteration += results.Iterations
+			results.Interventions = append(results.Interventions, ai)
+		}
+		results.Links = appendSeries(results.Links, step.Results.Links, results.Iterations, step.Results.Iterations)
+		results.Conversions = appendSeries(results.Conversions, step.Results.Conversions, results.Iterations, step.Results.Iterations)
+		results.BroadcastConversions = appendSeries(results.BroadcastConversions, step.Results.BroadcastConversions, results.Iterations, step.Results.Iterations)
 		results.Iterations += step.Results.Iterations
 		results.Colors = append(results.Colors, step.Results.Colors...)
 		results.Conversations = append(results.Conversations, step.Results.Conversations...)
+		results.Opinions = append(results.Opinions, step.Results.Opinions...)
+		results.Ideas = append(results.Ideas, step.Results.Ideas...)
 	}
 	return results, siminfo.Name, nil
 }
 
+// appendSeries appends the series of values recorded on each iteration of a step to the series
+// for all the steps so far, which covers the passed number of iterations before the step. Zeros
+// are filled in for the iterations of any steps that did not record the series, so that the
+// series stays aligned with the Colors. Returns nil if none of the steps recorded the series.
+func appendSeries(all []int, series []int, before int, iterations int) []int {
+	if all == nil && series == nil {
+		return nil
+	}
+	if all == nil {
+		all = make([]int, before)
+	}
+	if series == nil {
+		series = make([]int, iterations)
+	}
+	return append(all, series...)
+}
+
 // RunGenerateParseCopyNetwork handles three possible routes:
 // /simulation/{id}/run Runs the simulation for the specified number of steps and iterations.
 // /simulation/{id}/generate Generates a network to simulate, this will throw if the
@@ -249,6 +306,31 @@ func (sh *SimHandlerState) CopySim(siminfo *SimInfo, c *mango.Context) {
 type RunSpec struct {
 	Steps      int `json:"steps"`
 	Iterations int `json:"iterations"`
+	// Seed is optional, if it is not specified the run is seeded from the current time
+	Seed int64 `json:"seed,omitempty"`
+	// Scheduler selects how agents are scheduled in each iteration, the default is the
+	// sequential scheduler
+	Scheduler sim.SchedulerMode `json:"scheduler,omitempty"`
+	// Async runs the steps in a background job and returns the job immediately
+	Async bool `json:"async,omitempty"`
+	// Events records every change of color made by an agent and saves it with the step
+	Events bool `json:"events,omitempty"`
+	// Selection sets how agents choose which related agent to send their mail to
+	Selection sim.PartnerSelection `json:"selection"`
+	// Dynamics sets how the links decay, are deactivated and form during the run
+	Dynamics sim.LinkDynamics `json:"dynamics"`
+	// Hierarchy sets multipliers for influence up, down and across the reporting lines
+	Hierarchy sim.HierarchyInfluence `json:"hierarchy"`
+	// Interventions schedules changes to the network at iterations counted from the start of
+	// the run, the interventions applied are recorded in the results of each step
+	Interventions sim.InterventionSchedule `json:"interventions,omitempty"`
+	// Broadcasts sends mass communications to many agents at once at a fixed frequency, the
+	// conversions they cause are recorded separately from peer conversions
+	Broadcasts sim.Broadcasts `json:"broadcasts,omitempty"`
+	// Stop holds conditions that are evaluated after every iteration, when one of them is met
+	// the step is saved and no further steps are run. The conditions apply to the whole run
+	// rather than to each step, so iterations in a row are counted across the steps.
+	Stop sim.StopConditions `json:"stop"`
 }
 
 // PostRun adds a new step to the list of simulations
@@ -267,6 +349,27 @@ func (sh *SimHandlerState) PostRun(siminfo *SimInfo, c *mango.Context) {
 		c.Error("Steps and Iterations cannot be zero", http.StatusBadRequest)
 		return
 	}
+	scheduler, err := sim.NewScheduler(rs.Scheduler)
+	if err != nil {
+		c.Error(err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = rs.Stop.Validate()
+	if err != nil {
+		c.Error(err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = rs.Selection.Validate()
+	if err == nil {
+		err = rs.Dynamics.Validate()
+	}
+	if err == nil {
+		err = rs.Hierarchy.Validate()
+	}
+	if err != nil {
+		c.Error(err.Error(), http.StatusBadRequest)
+		return
+	}
 	ls := NewSimStepFromRelPath(siminfo.Steps[len(siminfo.Steps)-1])
 	objUpdater := sh.ListHandlerState.FileManager.Get(ls.Filepath())
 	err = objUpdater.Read(ls)
@@ -279,21 +382,129 @@ func (sh *SimHandlerState) PostRun(siminfo *SimInfo, c *mango.Context) {
 		c.Error(err.Error(), http.StatusInternalServerError)
 		return
 	}
-	r := sim.NewRunner(ls.Network, rs.Iterations)
-	var ns *SimStep
-	for i := 0; i < rs.Steps; i++ {
-		ns = CreateSimStep(siminfo.ID)
-		ns.Results = r.Run()
-		ns.Network = r.GetRelationshipMgr()
-		err = sh.AddItem(ns, siminfo, c, "step")
+	err = rs.Interventions.Validate(ls.Network)
+	if err == nil {
+		err = rs.Broadcasts.Validate(ls.Network)
+	}
+	if err != nil {
+		c.Error(err.Error(), http.StatusBadRequest)
+		return
+	}
+	seed := rs.Seed
+	if seed == 0 {
+		seed = sim.NewSeed()
+	}
+	r := sim.NewSeededRunner(ls.Network, rs.Iterations, seed)
+	r.SetScheduler(scheduler)
+	r.SetPartnerSelection(rs.Selection)
+	r.SetLinkDynamics(rs.Dynamics)
+	r.SetHierarchyInfluence(rs.Hierarchy)
+	r.SetInterventions(rs.Interventions)
+	r.SetBroadcasts(rs.Broadcasts)
+
+	if rs.Async {
+		j, err := sh.JobManager.Start(siminfo.ID, rs.Steps, rs.Iterations, func(ctx context.Context, jp *JobProgress) error {
+			_, err := sh.runSteps(ctx, r, siminfo, rs, jp)
+			return err
+		})
 		if err != nil {
-			c.Error(err.Error(), http.StatusInternalServerError)
+			c.Error(err.Error(), http.StatusConflict)
+			return
+		}
+		c.RespondWith(j).WithStatus(http.StatusAccepted)
+		return
+	}
+
+	if !sh.JobManager.LockSim(siminfo.ID) {
+		c.Error("a run is already in progress on this simulation", http.StatusConflict)
+		return
+	}
+	defer sh.JobManager.UnlockSim(siminfo.ID)
+	//The run stops at the end of the current iteration if the request is cancelled
+	ctx := c.Request.Context()
+	ns, err := sh.runSteps(ctx, r, siminfo, rs, nil)
+	if err != nil {
+		if errors.Is(err, ctx.Err()) {
+			c.Error(err.Error()+": Run stopped before completion", http.StatusServiceUnavailable)
 			return
 		}
+		c.Error(err.Error(), http.StatusInternalServerError)
+		return
 	}
 	c.RespondWith(ns).WithStatus(http.StatusCreated)
 }
 
+// runSteps runs the number of steps in the RunSpec, adding each step to the simulation as soon
+// as it completes. If the context is cancelled the current step is saved with the iterations
+// completed so far, and the error from the context is returned. If one of the stop conditions
+// in the RunSpec is met the step is saved and no further steps are run. The first step uses the
+// seed of the run, and each later step is given its own seed drawn from it, so that any step can
+// be replayed alone from the step before it. Progress is reported to the JobProgress if it is
+// not nil, and published to any clients streaming the simulation. Returns the last step saved.
+func (sh *SimHandlerState) runSteps(ctx context.Context, r sim.Runner, siminfo *SimInfo, rs RunSpec, jp *JobProgress) (ns *SimStep, err error) {
+	step, saved := 0, 0
+	o := sim.ObserverFunc(func(i int, colors []int, conversations int) {
+		if jp != nil {
+			jp.Iteration(i, colors, conversations)
+		}
+		sh.Streams.Publish(siminfo.ID, StreamEvent{"iteration", IterationEvent{step, i, colors, conversations}})
+	})
+	defer func() {
+		end := EndEvent{Steps: saved}
+		if err != nil {
+			end.Error = err.Error()
+		}
+		sh.Streams.Publish(siminfo.ID, StreamEvent{"end", end})
+	}()
+
+	//The stop conditions apply to the whole run, so a condition met across steps stops it and
+	//the time limit is a budget shared by all of the steps
+	r.SetStopConditions(rs.Stop)
+	seeds := sim.NewRand(r.GetSeed())
+	for i := 0; i < rs.Steps; i++ {
+		step = i + 1
+		if i > 0 {
+			r.SetSeed(seeds.Int63())
+		}
+		if jp != nil {
+			jp.StartStep(step)
+		}
+		ss := CreateSimStep(siminfo.ID)
+		var log *sim.EventLog
+		if rs.Events {
+			log = sim.NewEventLog()
+		}
+		r.SetEventLog(log)
+		results, runErr := r.RunContext(ctx, o)
+		ss.Results = results
+		ss.Network = r.GetRelationshipMgr()
+		ss.Seed = r.GetSeed()
+		if log != nil {
+			ss.Events = log.Events
+		}
+		if results.Iterations > 0 {
+			//Save partial steps so that the saved network is consistent with the results
+			err = sh.AddItem(ss, siminfo, nil, "step")
+			if err != nil {
+				return ns, err
+			}
+			ns = ss
+			saved++
+			if jp != nil {
+				jp.StepSaved(ns.RelPath())
+			}
+			sh.Streams.Publish(siminfo.ID, StreamEvent{"step", StepEvent{step, ns.ID, ns.RelPath(), results.Iterations}})
+		}
+		if runErr != nil {
+			return ns, runErr
+		}
+		if results.Stopped != nil {
+			return ns, nil
+		}
+	}
+	return ns, nil
+}
+
 // GenerateNetwork generates a hierarchical network to be simulated.
 func (sh *SimHandlerState) GenerateNetwork(siminfo *SimInfo, c *mango.Context) {
 	hs := sim.HierarchySpec{}
@@ -326,17 +537,7 @@ func (sh *SimHandlerState) GenerateNetwork(siminfo *SimInfo, c *mango.Context) {
 func (sh *SimHandlerState) createFirstSimStep(siminfo *SimInfo, rm sim.RelationshipMgr, c *mango.Context) {
 	step := CreateSimStep(siminfo.ID)
 	step.Network = rm
-	step.Results = sim.Results{
-		Iterations:    0,
-		Colors:        make([][]int, 1),
-		Conversations: make([]int, 1),
-	}
-	agents := rm.Agents()
-	colorCounts := make([]int, rm.MaxColors())
-	for _, a := range agents {
-		colorCounts[a.GetColor()]++
-	}
-	step.Results.Colors[0] = colorCounts
+	step.Results = sim.InitialResults(rm)
 	err := sh.AddItem(step, siminfo, c, "step")
 	if err != nil {
 		c.Error(err.Error(), http.StatusInternalServerError)
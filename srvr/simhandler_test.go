@@ -16,11 +16,11 @@ import (
 
 func CreateNetwork() sim.RelationshipMgr {
 	rm := &sim.Network{}
-	agent1 := sim.GenerateRandomAgent("Agent_1", "Agent 1", []sim.Color{sim.Blue}, false)
+	agent1 := sim.GenerateRandomAgent(rm.Rand(), "Agent_1", "Agent 1", []sim.Color{sim.Blue}, false)
 	rm.AddAgent(agent1)
-	agent2 := sim.GenerateRandomAgent("Agent_2", "Agent 2", []sim.Color{sim.Blue}, false)
+	agent2 := sim.GenerateRandomAgent(rm.Rand(), "Agent_2", "Agent 2", []sim.Color{sim.Blue}, false)
 	rm.AddAgent(agent2)
-	agent3 := sim.GenerateRandomAgent("Agent_3", "Agent 3", []sim.Color{sim.Blue}, false)
+	agent3 := sim.GenerateRandomAgent(rm.Rand(), "Agent_3", "Agent 3", []sim.Color{sim.Blue}, false)
 	rm.AddAgent(agent3)
 	rm.AddLink(agent1, agent2)
 	rm.AddLink(agent1, agent3)
@@ -30,6 +30,14 @@ func CreateNetwork() sim.RelationshipMgr {
 }
 
 func CreateSimHandlerBrowserWithSteps(deleteItemIndex int) (*mango.Browser, *TestFileUpdater, *TestFileUpdater, *TestFileUpdater, []string, string) {
+	tfm, simfu, ssfu, dfu, steps, simid := CreateTestFileManagerWithSteps(deleteItemIndex)
+	r := CreateRouter(tfm)
+	br := mango.NewBrowser(r)
+
+	return br, simfu, ssfu, dfu, steps, simid
+}
+
+func CreateTestFileManagerWithSteps(deleteItemIndex int) (*TestFileManager, *TestFileUpdater, *TestFileUpdater, *TestFileUpdater, []string, string) {
 	simid := uuid.New().String()
 	sim := NewSimInfo(simid)
 	sim.Name = "mySavedSim"
@@ -63,10 +71,7 @@ func CreateSimHandlerBrowserWithSteps(deleteItemIndex int) (*mango.Browser, *Tes
 	dfu := &TestFileUpdater{}
 	tfm.Default = dfu
 
-	r := CreateRouter(tfm)
-	br := mango.NewBrowser(r)
-
-	return br, simfu, ssfu, dfu, steps, simid
+	return tfm, simfu, ssfu, dfu, steps, simid
 }
 
 func TestMarshalling(t *testing.T) {
@@ -338,8 +343,9 @@ func TestParseNetworkFailsWithNoParseOptions(t *testing.T) {
 
 func TestAddLinksSucceeds(t *testing.T) {
 	br, _, ssfu, _, _, simid := CreateSimHandlerBrowserWithSteps(2)
-	ssfu.Obj.(*SimStep).Network.AddAgent(sim.GenerateRandomAgent("Agent_4", "Agent 4", []sim.Color{sim.Blue}, false))
-	ssfu.Obj.(*SimStep).Network.AddAgent(sim.GenerateRandomAgent("Agent_5", "Agent 5", []sim.Color{sim.Blue}, false))
+	rm := ssfu.Obj.(*SimStep).Network
+	rm.AddAgent(sim.GenerateRandomAgent(rm.Rand(), "Agent_4", "Agent 4", []sim.Color{sim.Blue}, false))
+	rm.AddAgent(sim.GenerateRandomAgent(rm.Rand(), "Agent_5", "Agent 5", []sim.Color{sim.Blue}, false))
 
 	data := []string{
 		"Header always skipped ,check_this_is_not_an_Id\n",
@@ -377,6 +383,70 @@ func TestAddLinksSucceeds(t *testing.T) {
 	AreEqual(t, len(simstep.Network.Agents()), 5, "Agents array should have 5 items")
 }
 
+func TestAddLinksAddsDirectedLinks(t *testing.T) {
+	br, _, ssfu, _, _, simid := CreateSimHandlerBrowserWithSteps(2)
+	rm := ssfu.Obj.(*SimStep).Network
+	rm.AddAgent(sim.GenerateRandomAgent(rm.Rand(), "Agent_4", "Agent 4", []sim.Color{sim.Blue}, false))
+
+	pb := ParseBody{
+		ParseOptions: sim.ParseOptions{
+			Delimiter:  ",",
+			Identifier: 0,
+			Parent:     1,
+			Directed:   true,
+		},
+		Payload: []byte("Child,Parent\nAgent_4,Agent_2\n"),
+	}
+
+	pbs, err := json.Marshal(pb)
+	AssertSuccess(t, err)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PutS(fmt.Sprintf("/api/simulation/%s/links", simid), string(pbs), hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not Updated")
+	links := ssfu.Obj.(*SimStep).Network.Links()
+	l := links[len(links)-1]
+	AreEqual(t, "Agent_2", l.Agent1ID, "Directed link should start at the parent")
+	IsTrue(t, l.Directed, "Added link not directed")
+	IsTrue(t, strings.Contains(resp.Body.String(), `"directed":true`), "Directed flag not returned")
+}
+
+func TestAddLinksSetsTrustFromWeightColumn(t *testing.T) {
+	br, _, ssfu, _, _, simid := CreateSimHandlerBrowserWithSteps(2)
+	rm := ssfu.Obj.(*SimStep).Network
+	rm.AddAgent(sim.GenerateRandomAgent(rm.Rand(), "Agent_4", "Agent 4", []sim.Color{sim.Blue}, false))
+
+	pb := ParseBody{
+		ParseOptions: sim.ParseOptions{
+			Delimiter:  ",",
+			Identifier: 0,
+			Parent:     1,
+			Weight:     2,
+		},
+		Payload: []byte("Child,Parent,Weight\nAgent_4,Agent_2,0.5\n"),
+	}
+
+	pbs, err := json.Marshal(pb)
+	AssertSuccess(t, err)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PutS(fmt.Sprintf("/api/simulation/%s/links", simid), string(pbs), hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not Updated")
+	links := ssfu.Obj.(*SimStep).Network.Links()
+	AreEqual(t, 0.5, links[len(links)-1].TrustWeight(), "Trust not set from the weight column")
+
+	pb.Payload = []byte("Child,Parent,Weight\nAgent_4,Agent_2,-1\n")
+	pbs, err = json.Marshal(pb)
+	AssertSuccess(t, err)
+	resp, err = br.PutS(fmt.Sprintf("/api/simulation/%s/links", simid), string(pbs), hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusBadRequest, resp.Code, "Negative weight not rejected")
+}
+
 func TestAddLinksIgnoresLinksWhenAgentDoesntExist(t *testing.T) {
 	br, _, ssfu, _, _, simid := CreateSimHandlerBrowserWithSteps(2)
 
@@ -735,6 +805,298 @@ func TestPostRunSucceedsWithOneStep(t *testing.T) {
 	AreEqual(t, 5, len(ns.Results.Conversations), "Wrong number of items in the Conversations array")
 }
 
+func TestPostRunContinuesAgentMemoryFromLastStep(t *testing.T) {
+	br, _, ssfu, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+	n, err := sim.NewNetwork(`{"links":[{"source":"id_1","target":"id_2"}],"nodes":[` +
+		`{"id":"id_1","color":2,"type":"AgentWithMemory","previousColors":{"0":0,"1":6},"shortMemory":{"3":7},"clock":7},` +
+		`{"id":"id_2","color":2,"type":"AgentWithMemory","previousColors":{"0":0,"1":6},"clock":7}],"maxColors":4}`)
+	AssertSuccess(t, err)
+	ssfu.Obj.(*SimStep).Network = n
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":1}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	ns := dfu.Obj.(*SimStep)
+	a := ns.Network.Agents()[0].(*sim.AgentWithMemory)
+	AreEqual(t, 8, a.Clock, "Clock not continued from the last step")
+	AreEqual(t, 6, a.PreviousColors[sim.Blue], "Long term memory not continued from the last step")
+	AreEqual(t, 4, a.MaxColors, "MaxColors not taken from the network")
+}
+
+func TestPostRunRecordsSeed(t *testing.T) {
+	br, _, _, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+	rs := RunSpec{
+		Iterations: 5,
+		Steps:      1,
+		Seed:       42,
+	}
+	rss, err := json.Marshal(rs)
+	AssertSuccess(t, err)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), string(rss), hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	ns := dfu.Obj.(*SimStep)
+	AreEqual(t, int64(42), ns.Seed, "Seed not recorded on the new step")
+	AreEqual(t, int64(42), ns.Results.Seed, "Seed not recorded in the step results")
+}
+
+func TestPostRunStepCanBeReplayedFromItsSeed(t *testing.T) {
+	network := `{"links":[{"source":"a","target":"b"},{"source":"b","target":"c"},{"source":"c","target":"d"},{"source":"d","target":"a"},{"source":"a","target":"c"}],"nodes":[` +
+		`{"id":"a","color":1,"influence":1,"susceptability":0.5},{"id":"b","color":2,"influence":1,"susceptability":0.5},` +
+		`{"id":"c","color":3,"influence":1,"susceptability":0.5},{"id":"d","influence":1,"susceptability":0.5}],"maxColors":4}`
+	run := func(n sim.RelationshipMgr, spec string) *SimStep {
+		br, _, ssfu, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+		ssfu.Obj.(*SimStep).Network = n
+		hdrs := http.Header{}
+		hdrs.Set("Content-Type", "application/json")
+		resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), spec, hdrs)
+		AssertSuccess(t, err)
+		AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+		return dfu.Obj.(*SimStep)
+	}
+	parse := func() sim.RelationshipMgr {
+		n, err := sim.NewNetwork(network)
+		AssertSuccess(t, err)
+		return n
+	}
+
+	last := run(parse(), `{"steps":2,"iterations":3,"seed":42}`)
+	NotEqual(t, int64(42), last.Seed, "Later step should have its own seed")
+	first := run(parse(), `{"steps":1,"iterations":3,"seed":42}`)
+	AreEqual(t, int64(42), first.Seed, "First step should use the seed of the run")
+	replay := run(first.Network, fmt.Sprintf(`{"steps":1,"iterations":3,"seed":%d}`, last.Seed))
+	AreEqual(t, fmt.Sprint(last.Results.Colors), fmt.Sprint(replay.Results.Colors), "Step not replayed from its seed")
+	AreEqual(t, fmt.Sprint(last.Results.Conversations), fmt.Sprint(replay.Results.Conversations), "Step not replayed from its seed")
+}
+
+func TestPostRunRecordsScheduler(t *testing.T) {
+	br, _, _, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+	rs := RunSpec{
+		Iterations: 5,
+		Steps:      1,
+		Scheduler:  sim.WorkerPool,
+	}
+	rss, err := json.Marshal(rs)
+	AssertSuccess(t, err)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), string(rss), hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	ns := dfu.Obj.(*SimStep)
+	AreEqual(t, sim.WorkerPool, ns.Results.Scheduler, "Scheduler not recorded in the step results")
+}
+
+func TestPostRunRecordsPartnerSelection(t *testing.T) {
+	br, _, _, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":5,"selection":{"mode":"strength","baseline":1}}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	ns := dfu.Obj.(*SimStep)
+	AreEqual(t, sim.StrengthSelection, ns.Results.Selection, "Selection not recorded in the step results")
+}
+
+func TestPostRunFailsWithUnknownPartnerSelection(t *testing.T) {
+	br, _, _, _, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":5,"selection":{"mode":"loudest"}}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusBadRequest, resp.Code, "Unknown selection mode not rejected")
+}
+
+func TestPostRunRecordsActiveLinks(t *testing.T) {
+	br, _, _, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":5,"dynamics":{"decay":0.1,"closure":0.5}}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	ns := dfu.Obj.(*SimStep)
+	AreEqual(t, 5, len(ns.Results.Links), "Active links not recorded in the step results")
+	AreEqual(t, ns.Results.Links[4], len(ns.Network.Links()), "Saved network does not reflect the link dynamics")
+}
+
+func TestPostRunFailsWithInvalidLinkDynamics(t *testing.T) {
+	br, _, _, _, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":5,"dynamics":{"decay":2}}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusBadRequest, resp.Code, "Invalid link dynamics not rejected")
+}
+
+func TestPostRunRecordsHierarchyInfluence(t *testing.T) {
+	br, _, _, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":5,"hierarchy":{"downward":2,"upward":0.5}}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	ns := dfu.Obj.(*SimStep)
+	AreEqual(t, sim.HierarchyInfluence{Upward: 0.5, Downward: 2}, *ns.Results.Hierarchy, "Hierarchy influence not recorded in the step results")
+}
+
+func TestPostRunFailsWithNegativeHierarchyInfluence(t *testing.T) {
+	br, _, _, _, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":5,"hierarchy":{"peer":-1}}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusBadRequest, resp.Code, "Negative hierarchy influence not rejected")
+}
+
+func TestPostRunRecordsInterventions(t *testing.T) {
+	br, _, _, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":5,"interventions":[{"iteration":2,"type":"color","agents":["Agent_2"],"color":2}]}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	ns := dfu.Obj.(*SimStep)
+	AreEqual(t, 1, len(ns.Results.Interventions), "Intervention not recorded in the step results")
+	AreEqual(t, 2, ns.Results.Interventions[0].Iteration, "Wrong iteration recorded for the intervention")
+	AreEqual(t, "Agent_2", ns.Results.Interventions[0].Intervention.Agents[0], "Wrong intervention recorded")
+}
+
+func TestPostRunFailsWithInterventionForUnknownAgent(t *testing.T) {
+	br, _, _, _, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":5,"interventions":[{"iteration":2,"type":"color","agents":["Agent_9"],"color":2}]}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusBadRequest, resp.Code, "Intervention for an unknown agent not rejected")
+	AreEqual(t, "unrecognised Agent Id 'Agent_9' in intervention", strings.TrimSpace(resp.Body.String()), "Incorrect error response")
+}
+
+func TestPostRunRecordsBroadcastConversions(t *testing.T) {
+	br, _, _, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":5,"broadcasts":[{"name":"email","color":3,"frequency":2,"influence":100,"reach":{"agents":["Agent_1"]}}]}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	ns := dfu.Obj.(*SimStep)
+	AreEqual(t, 5, len(ns.Results.BroadcastConversions), "Broadcast conversions not recorded in the step results")
+	AreEqual(t, 5, len(ns.Results.Conversions), "Peer conversions not recorded in the step results")
+	AreEqual(t, 1, ns.Results.BroadcastConversions[0], "Wrong number of broadcast conversions")
+}
+
+func TestPostRunFailsWithInvalidBroadcast(t *testing.T) {
+	br, _, _, _, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":5,"broadcasts":[{"color":1,"influence":1}]}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusBadRequest, resp.Code, "Broadcast without a frequency not rejected")
+	AreEqual(t, "broadcast frequency must be at least 1", strings.TrimSpace(resp.Body.String()), "Incorrect error response")
+}
+
+func TestPostRunRecordsEvents(t *testing.T) {
+	br, _, _, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":20,"events":true}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	ns := dfu.Obj.(*SimStep)
+	IsTrue(t, ns.Events != nil, "Events not recorded on the new step")
+	changes := 0
+	for _, a := range ns.Network.Agents() {
+		changes += a.State().ChangeCount
+	}
+	AreEqual(t, changes, len(ns.Events), "Not every change recorded")
+}
+
+func TestPostRunDoesNotRecordEventsByDefault(t *testing.T) {
+	br, _, _, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":20}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	ns := dfu.Obj.(*SimStep)
+	AreEqual(t, 0, len(ns.Events), "Events recorded when not requested")
+}
+
+func TestPostRunStopsWhenStopConditionMet(t *testing.T) {
+	br, simfu, _, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":3,"iterations":20,"stop":{"timeLimit":0.000001}}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	ns := dfu.Obj.(*SimStep)
+	IsTrue(t, ns.Results.Stopped != nil, "Stop condition not recorded on the new step")
+	AreEqual(t, sim.TimeLimitReached, ns.Results.Stopped.Condition, "Wrong stop condition")
+	AreEqual(t, 0, ns.Results.Stopped.Iteration, "Wrong stop iteration")
+	AreEqual(t, 1, ns.Results.Iterations, "Wrong number of iterations")
+	AreEqual(t, 4, len(simfu.Obj.(*SimInfo).Steps), "Steps run after the stop condition was met")
+}
+
+func TestPostRunStopsWhenStopConditionMetAcrossSteps(t *testing.T) {
+	br, simfu, ssfu, dfu, _, simid := CreateSimHandlerBrowserWithSteps(2)
+	n, err := sim.NewNetwork(`{"links":[{"source":"id_1","target":"id_2"}],"nodes":[` +
+		`{"id":"id_1","color":1,"susceptability":100},{"id":"id_2","color":1,"susceptability":100}],"maxColors":4}`)
+	AssertSuccess(t, err)
+	ssfu.Obj.(*SimStep).Network = n
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":3,"iterations":2,"stop":{"stableIterations":3}}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	ns := dfu.Obj.(*SimStep)
+	IsTrue(t, ns.Results.Stopped != nil, "Stop condition met across steps not recorded")
+	AreEqual(t, sim.NoChange, ns.Results.Stopped.Condition, "Wrong stop condition")
+	AreEqual(t, 0, ns.Results.Stopped.Iteration, "Unchanged iterations not counted from the previous step")
+	AreEqual(t, 5, len(simfu.Obj.(*SimInfo).Steps), "Steps run after the stop condition was met")
+}
+
+func TestPostRunFailsWithInvalidStopConditions(t *testing.T) {
+	br, _, _, _, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), `{"steps":1,"iterations":5,"stop":{"colorShare":2}}`, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusBadRequest, resp.Code, "Not Bad request")
+	AreEqual(t, "colorShare must be between 0 and 1", strings.TrimSpace(resp.Body.String()), "Incorrect error response")
+}
+
+func TestPostRunFailsWithUnknownScheduler(t *testing.T) {
+	br, _, _, _, _, simid := CreateSimHandlerBrowserWithSteps(2)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	data := `{"steps":1,"iterations":5,"scheduler":"unknown"}`
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/run", simid), data, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusBadRequest, resp.Code, "Not Bad request")
+	AreEqual(t, "unrecognised scheduler mode 'unknown'", strings.TrimSpace(resp.Body.String()), "Incorrect error response")
+}
+
 func CreateResults(iterations, maxColors int) sim.Results {
 	results := sim.Results{
 		Iterations:    iterations,
@@ -753,27 +1115,29 @@ func CreateResults(iterations, maxColors int) sim.Results {
 }
 
 func CreateSimHandlerBrowserWithStepsAndResults() (*mango.Browser, string) {
+	results := []sim.Results{CreateResults(1, 4), CreateResults(2, 4), CreateResults(3, 4)}
+	results[2].Stopped = &sim.Stopped{Condition: sim.NoChange, Iteration: 2}
+	return CreateSimHandlerBrowserWithResults(results)
+}
+
+func CreateSimHandlerBrowserWithResults(results []sim.Results) (*mango.Browser, string) {
 	simid := uuid.New().String()
-	sim := NewSimInfo(simid)
-	sim.Name = "mySavedSim"
-	sim.Description = "A description of mySavedSim"
-	ids := []string{
-		uuid.New().String(),
-		uuid.New().String(),
-		uuid.New().String(),
-	}
+	si := NewSimInfo(simid)
+	si.Name = "mySavedSim"
+	si.Description = "A description of mySavedSim"
 	simfu := &TestFileUpdater{
-		Obj:      sim,
-		Filepath: sim.Filepath(),
+		Obj:      si,
+		Filepath: si.Filepath(),
 	}
 	tfm := NewTestFileManager(simfu)
-	steps := make([]string, len(ids))
-	for i, id := range ids {
+	steps := make([]string, len(results))
+	for i, r := range results {
+		id := uuid.New().String()
 		steps[i] = fmt.Sprintf("/api/simulation/%s/step/%s", simid, id)
 		ss := &SimStep{
 			ID:       id,
 			ParentID: simid,
-			Results:  CreateResults(i+1, 4),
+			Results:  r,
 		}
 		ssfu := &TestFileUpdater{
 			Obj:      ss,
@@ -781,7 +1145,7 @@ func CreateSimHandlerBrowserWithStepsAndResults() (*mango.Browser, string) {
 		}
 		tfm.Add(ss.Filepath(), ssfu)
 	}
-	sim.Steps = steps
+	si.Steps = steps
 	r := CreateRouter(tfm)
 	br := mango.NewBrowser(r)
 
@@ -810,6 +1174,9 @@ func TestGetResultsSucceeds(t *testing.T) {
 	AreEqual(t, 3, rs.Colors[3][3], "Wrong color count")
 	AreEqual(t, 4, rs.Colors[4][3], "Wrong color count")
 	AreEqual(t, 5, rs.Colors[5][3], "Wrong color count")
+	IsTrue(t, rs.Stopped != nil, "Stop condition from the last step not returned")
+	AreEqual(t, sim.NoChange, rs.Stopped.Condition, "Wrong stop condition")
+	AreEqual(t, 5, rs.Stopped.Iteration, "Stop iteration not relative to the start of the simulation")
 }
 
 func TestGetResultsSucceedsAsCsv(t *testing.T) {
@@ -866,3 +1233,46 @@ func TestGetResultsSucceedsAsCsv(t *testing.T) {
 	AreEqual(t, 4, rs[4][3], "Wrong color count")
 	AreEqual(t, 5, rs[5][3], "Wrong color count")
 }
+
+func CreateResultsWithSeries() []sim.Results {
+	results := []sim.Results{CreateResults(2, 2), CreateResults(2, 2), CreateResults(1, 2)}
+	results[0].Links = []int{4, 3}
+	results[1].Conversions = []int{1, 2}
+	results[1].BroadcastConversions = []int{5, 6}
+	results[1].Interventions = []sim.AppliedIntervention{{Iteration: 1, Intervention: sim.Intervention{Iteration: 3, Type: sim.ColorIntervention}}}
+	results[2].Interventions = []sim.AppliedIntervention{{Iteration: 0, Intervention: sim.Intervention{Iteration: 4, Type: sim.EvangelistIntervention}}}
+	return results
+}
+
+func TestGetResultsConcatenatesSeriesRecordedBySomeSteps(t *testing.T) {
+	br, simid := CreateSimHandlerBrowserWithResults(CreateResultsWithSeries())
+
+	resp, err := br.Get(fmt.Sprintf("/api/simulation/%s/results", simid), http.Header{})
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not OK")
+	rs := &sim.Results{}
+	AssertSuccess(t, json.Unmarshal(resp.Body.Bytes(), rs))
+	AreEqual(t, 5, rs.Iterations, "Wrong number of iterations")
+	AreEqual(t, "[4 3 0 0 0]", fmt.Sprint(rs.Links), "Wrong links")
+	AreEqual(t, "[0 0 1 2 0]", fmt.Sprint(rs.Conversions), "Wrong peer conversions")
+	AreEqual(t, "[0 0 5 6 0]", fmt.Sprint(rs.BroadcastConversions), "Wrong broadcast conversions")
+	AreEqual(t, 2, len(rs.Interventions), "Interventions not collected from every step")
+	AreEqual(t, 3, rs.Interventions[0].Iteration, "Intervention iteration not relative to the start of the simulation")
+	AreEqual(t, 4, rs.Interventions[1].Iteration, "Intervention iteration not relative to the start of the simulation")
+}
+
+func TestGetResultsAsCsvIncludesSeriesRecordedBySomeSteps(t *testing.T) {
+	br, simid := CreateSimHandlerBrowserWithResults(CreateResultsWithSeries())
+
+	hdrs := http.Header{
+		"Content-Type": []string{"text/csv"},
+	}
+	resp, err := br.Get(fmt.Sprintf("/api/simulation/%s/results", simid), hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not OK")
+	lines := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+	AreEqual(t, 6, len(lines), "Wrong number of lines")
+	AreEqual(t, "Grey,Blue,Conversations,Links,Peer Conversions,Broadcast Conversions", lines[0], "Wrong header")
+	AreEqual(t, "0,1,1,4,0,0", lines[1], "Wrong first row")
+	AreEqual(t, "1,2,2,0,2,6", lines[4], "Wrong row from the step with broadcasts")
+}
@@ -14,6 +14,8 @@ type SimInfo struct {
 	Name        string             `json:"name"`
 	Description string             `json:"description"`
 	Steps       []string           `json:"steps"`
+	Batches     []string           `json:"batches,omitempty"`
+	Sweeps      []string           `json:"sweeps,omitempty"`
 	Options     sim.NetworkOptions `json:"options"`
 }
 
@@ -61,12 +63,25 @@ func (si *SimInfo) RelPath() string {
 	return fmt.Sprintf("/api/simulation/%s", si.ID)
 }
 
-//GetItems returns the items in the specified list
+//GetItems returns the items in the specified list, either the "batch" or "sweep" list or the list of steps
 func (si *SimInfo) GetItems(listname string) []string {
+	switch listname {
+	case "batch":
+		return si.Batches
+	case "sweep":
+		return si.Sweeps
+	}
 	return si.Steps
 }
 
-//UpdateItems updates the items in the specified list
+//UpdateItems updates the items in the specified list, either the "batch" or "sweep" list or the list of steps
 func (si *SimInfo) UpdateItems(listname string, items []string) {
-	si.Steps = items
+	switch listname {
+	case "batch":
+		si.Batches = items
+	case "sweep":
+		si.Sweeps = items
+	default:
+		si.Steps = items
+	}
 }
@@ -16,6 +16,8 @@ type SimStep struct {
 	Results  sim.Results         `json:"results"`
 	ID       string              `json:"id"`
 	ParentID string              `json:"parent"`
+	Seed     int64               `json:"seed,omitempty"`
+	Events   []sim.ColorChange   `json:"events,omitempty"`
 }
 
 // SimStepSummary holds a summary of a simulation step, excluding the detailed network.
@@ -25,6 +27,7 @@ type SimStepSummary struct {
 	Results  sim.Results `json:"results"`
 	ID       string      `json:"id"`
 	ParentID string      `json:"parent"`
+	Seed     int64       `json:"seed,omitempty"`
 }
 
 //UnmarshalJSON implements unmarshaling to make sure network is properly unmarshalled into sim.Network
@@ -51,6 +54,22 @@ func (ss *SimStep) UnmarshalJSON(b []byte) error {
 	if err != nil {
 		return err
 	}
+	//Steps saved before seeds were recorded will not have a seed
+	seed, exists := simstep["seed"]
+	if exists {
+		err = json.Unmarshal(seed, &ss.Seed)
+		if err != nil {
+			return err
+		}
+	}
+	//Events are only recorded if they were requested when the step was run
+	events, exists := simstep["events"]
+	if exists {
+		err = json.Unmarshal(events, &ss.Events)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -98,6 +117,8 @@ func (ss *SimStep) CopyValues(obj Persistable) error {
 	}
 	ss.Network = ssToCopy.Network
 	ss.Results = ssToCopy.Results
+	ss.Seed = ssToCopy.Seed
+	ss.Events = ssToCopy.Events
 	return nil
 }
 
@@ -3,7 +3,10 @@ package srvr
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 
+	"github.com/codeafix/orgnetsim/cascade"
 	"github.com/codeafix/orgnetsim/sim"
 	"github.com/spaceweasel/mango"
 )
@@ -53,7 +56,8 @@ func (sh *StepHandlerState) Put(c *mango.Context) {
 	sh.UpdateObjectWithContextBind(step, savedstep, c)
 }
 
-// GetStepData returns specific data (network or agents) for a simulation step.
+// GetStepData returns specific data (network, agents, color change events or the cascades
+// analysed from those events) for a simulation step.
 func (sh *StepHandlerState) GetStepData(c *mango.Context) {
 	if c.RouteParams == nil {
 		c.Error("RouteParams is nil in GetStepData", http.StatusInternalServerError)
@@ -87,11 +91,47 @@ func (sh *StepHandlerState) GetStepData(c *mango.Context) {
 	case "agents":
 		agents := step.Network.Agents()
 		c.RespondWith(agents).WithStatus(http.StatusOK)
+	case "events":
+		c.RespondWith(filterEvents(step.Events, c.Request.URL.Query())).WithStatus(http.StatusOK)
+	case "cascades":
+		siminfo := NewSimInfo(simID)
+		err := sh.FileManager.Get(siminfo.Filepath()).Read(siminfo)
+		if err != nil {
+			c.Error(err.Error(), http.StatusInternalServerError)
+			return
+		}
+		c.RespondWith(cascade.Analyse(step.Events, step.Network, siminfo.Options)).WithStatus(http.StatusOK)
 	default:
 		c.Error("Not Found", http.StatusNotFound)
 	}
 }
 
+// filterEvents returns the color changes that match the agent, sender, reason and iteration
+// query parameters, any parameter that is not specified matches every change
+func filterEvents(events []sim.ColorChange, query url.Values) []sim.ColorChange {
+	filtered := []sim.ColorChange{}
+	iteration, err := strconv.Atoi(query.Get("iteration"))
+	if err != nil {
+		iteration = -1
+	}
+	for _, e := range events {
+		if query.Has("agent") && e.AgentID != query.Get("agent") {
+			continue
+		}
+		if query.Has("sender") && e.SenderID != query.Get("sender") {
+			continue
+		}
+		if query.Has("reason") && string(e.Reason) != query.Get("reason") {
+			continue
+		}
+		if iteration >= 0 && e.Iteration != iteration {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+	return filtered
+}
+
 // PutStepNetworkData updates the network data for a specific simulation step.
 func (sh *StepHandlerState) PutStepNetworkData(c *mango.Context) {
 	if c.RouteParams == nil {
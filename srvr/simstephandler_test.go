@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/codeafix/orgnetsim/cascade"
 	"github.com/codeafix/orgnetsim/sim"
 	"github.com/google/uuid"
 	"github.com/spaceweasel/mango"
@@ -231,6 +233,28 @@ func TestGetAgentColorsForStepSuccess(t *testing.T) {
 	AreEqual(t, sim.Blue, rResults[2].GetColor(), "Wrong color for agent 3")
 }
 
+func TestGetAgentIdeasForStepSuccess(t *testing.T) {
+	br, _, ssfu, _, _, simid := CreateSimHandlerBrowserWithSteps(0)
+	mockStep := ssfu.Obj.(*SimStep)
+	n, err := sim.NewNetwork(`{"nodes":[{"id":"id_1","type":"MultiIdeaAgent","color":1,"ideas":[1,3]},{"id":"id_2","type":"MultiIdeaAgent","color":2}],"links":[],"maxColors":4}`)
+	AssertSuccess(t, err)
+	mockStep.Network = n
+
+	hdrs := http.Header{}
+	resp, err := br.Get(fmt.Sprintf("/api/simulation/%s/step/%s/agents", simid, mockStep.ID), hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not OK")
+
+	rResults := []*sim.MultiIdeaAgent{}
+	err = json.Unmarshal(resp.Body.Bytes(), &rResults)
+	AssertSuccess(t, err)
+	AreEqual(t, 2, len(rResults), "Wrong number of agents")
+	AreEqual(t, "MultiIdeaAgent", rResults[0].Type, "Wrong type for agent 1")
+	AreEqual(t, 2, len(rResults[0].Ideas), "Wrong ideas for agent 1")
+	AreEqual(t, sim.Green, rResults[0].Ideas[1], "Wrong ideas for agent 1")
+	AreEqual(t, 1, len(rResults[1].Ideas), "Color not held as an idea by agent 2")
+}
+
 func TestGetAgentColorsForStepNotFound(t *testing.T) {
 	{
 		ts := &SimStep{
@@ -251,6 +275,86 @@ func TestGetAgentColorsForStepNotFound(t *testing.T) {
 	}
 }
 
+func TestGetEventsForStepSuccess(t *testing.T) {
+	br, _, ssfu, _, _, simid := CreateSimHandlerBrowserWithSteps(0)
+	mockStep := ssfu.Obj.(*SimStep)
+	mockStep.Events = []sim.ColorChange{
+		{Iteration: 0, AgentID: "Agent_2", SenderID: "Agent_1", OldColor: sim.Grey, NewColor: sim.Blue, Reason: sim.Influenced},
+		{Iteration: 1, AgentID: "Agent_3", SenderID: "Agent_1", OldColor: sim.Grey, NewColor: sim.Red, Reason: sim.Contrarian},
+		{Iteration: 2, AgentID: "Agent_1", SenderID: "Agent_3", OldColor: sim.Blue, NewColor: sim.Red, Reason: sim.Influenced},
+	}
+
+	hdrs := http.Header{}
+	resp, err := br.Get(fmt.Sprintf("/api/simulation/%s/step/%s/events", simid, mockStep.ID), hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not OK")
+	events := []sim.ColorChange{}
+	err = json.Unmarshal(resp.Body.Bytes(), &events)
+	AssertSuccess(t, err)
+	AreEqual(t, 3, len(events), "Wrong number of events")
+	AreEqual(t, mockStep.Events[1], events[1], "Wrong event returned")
+
+	resp, err = br.Get(fmt.Sprintf("/api/simulation/%s/step/%s/events?sender=Agent_1&reason=influenced", simid, mockStep.ID), hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not OK")
+	events = []sim.ColorChange{}
+	err = json.Unmarshal(resp.Body.Bytes(), &events)
+	AssertSuccess(t, err)
+	AreEqual(t, 1, len(events), "Wrong number of filtered events")
+	AreEqual(t, mockStep.Events[0], events[0], "Wrong filtered event returned")
+
+	resp, err = br.Get(fmt.Sprintf("/api/simulation/%s/step/%s/events?iteration=2", simid, mockStep.ID), hdrs)
+	AssertSuccess(t, err)
+	events = []sim.ColorChange{}
+	err = json.Unmarshal(resp.Body.Bytes(), &events)
+	AssertSuccess(t, err)
+	AreEqual(t, 1, len(events), "Wrong number of events for iteration")
+	AreEqual(t, "Agent_1", events[0].AgentID, "Wrong event for iteration")
+}
+
+func TestGetEventsForStepWithoutEventsReturnsEmptyList(t *testing.T) {
+	br, _, ssfu, _, _, simid := CreateSimHandlerBrowserWithSteps(0)
+	mockStep := ssfu.Obj.(*SimStep)
+
+	resp, err := br.Get(fmt.Sprintf("/api/simulation/%s/step/%s/events", simid, mockStep.ID), http.Header{})
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not OK")
+	AreEqual(t, "[]", strings.TrimSpace(resp.Body.String()), "Expected an empty list")
+}
+
+func TestGetCascadesForStepSuccess(t *testing.T) {
+	br, simfu, ssfu, _, _, simid := CreateSimHandlerBrowserWithSteps(0)
+	simfu.Obj.(*SimInfo).Options.LinkedTeamList = []string{"Agent_1", "Agent_3"}
+	mockStep := ssfu.Obj.(*SimStep)
+	mockStep.Events = []sim.ColorChange{
+		{Iteration: 0, AgentID: "Agent_2", SenderID: "Agent_1", OldColor: sim.Red, NewColor: sim.Blue, Reason: sim.Influenced},
+		{Iteration: 1, AgentID: "Agent_3", SenderID: "Agent_1", OldColor: sim.Red, NewColor: sim.Blue, Reason: sim.Influenced},
+	}
+
+	resp, err := br.Get(fmt.Sprintf("/api/simulation/%s/step/%s/cascades", simid, mockStep.ID), http.Header{})
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not OK")
+	a := cascade.Analysis{}
+	err = json.Unmarshal(resp.Body.Bytes(), &a)
+	AssertSuccess(t, err)
+	AreEqual(t, 2, a.Adoptions, "Wrong number of adoptions")
+	AreEqual(t, 0.5, a.CrossTeamFraction, "Wrong cross team fraction")
+	blue := a.Tree(sim.Blue)
+	AreEqual(t, 1, len(blue.Cascades), "Wrong number of Blue cascades")
+	AreEqual(t, "Agent_1", blue.Cascades[0].SeedID, "Wrong seed")
+	AreEqual(t, 2, blue.Cascades[0].Breadth, "Wrong breadth")
+}
+
+func TestGetCascadesForStepFailsWhenSimNotRead(t *testing.T) {
+	br, simfu, ssfu, _, _, simid := CreateSimHandlerBrowserWithSteps(0)
+	simfu.ReadErr = fmt.Errorf("Not Found")
+	mockStep := ssfu.Obj.(*SimStep)
+
+	resp, err := br.Get(fmt.Sprintf("/api/simulation/%s/step/%s/cascades", simid, mockStep.ID), http.Header{})
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusInternalServerError, resp.Code, "Not InternalServerError")
+}
+
 func TestPutStepNetworkData_Success(t *testing.T) {
 	simID := uuid.New().String()
 	stepID := uuid.New().String()
@@ -274,7 +378,7 @@ func TestPutStepNetworkData_Success(t *testing.T) {
 			&sim.AgentState{ID: "agent2", X: 30, Y: 40, Color: 2},
 		},
 		Edges: []*sim.Link{
-			{Agent1ID: "agent1", Agent2ID: "agent2", Strength: 15},
+			{Agent1ID: "agent1", Agent2ID: "agent2", Strength: 15, Directed: true},
 		},
 		MaxColorCount: 2,
 	}
@@ -306,6 +410,7 @@ func TestPutStepNetworkData_Success(t *testing.T) {
 	IsFalse(t, updatedStep.Network == nil, "Updated step network should not be nil")
 	AreEqual(t, len(newNetwork.Nodes), len(updatedStep.Network.Agents()), "Persisted network node count mismatch")
 	AreEqual(t, len(newNetwork.Edges), len(updatedStep.Network.Links()), "Persisted network edge count mismatch")
+	IsTrue(t, updatedStep.Network.Links()[0].Directed, "Persisted network edge not directed")
 	if len(newNetwork.Nodes) > 0 && len(updatedStep.Network.Agents()) > 0 {
 		AreEqual(t, newNetwork.Nodes[0].Identifier(), updatedStep.Network.Agents()[0].Identifier(), "Persisted network node ID mismatch")
 	}
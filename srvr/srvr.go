@@ -12,9 +12,10 @@ import (
 // StaticRouter is a derived version of mango.Router that also serves an embedded
 // copy of the orgnetsim UI
 type StaticRouter struct {
-	Router       *mango.Router
-	embedHandler http.Handler
-	webpath      string
+	Router        *mango.Router
+	StreamHandler http.Handler
+	embedHandler  http.Handler
+	webpath       string
 }
 
 // ListenAndServe launches the web server
@@ -25,7 +26,8 @@ type StaticRouter struct {
 // port is the port to listen on
 func ListenAndServe(rootpath string, webpath string, webfs fs.FS, port string) {
 	fm := NewFileManager(rootpath)
-	r := CreateRouter(fm)
+	hub := NewStreamHub()
+	r := CreateRouterWithStreams(fm, hub)
 
 	r.RequestLogger = func(l *mango.RequestLog) {
 		fmt.Println(l.CombinedFormat())
@@ -39,9 +41,12 @@ func ListenAndServe(rootpath string, webpath string, webfs fs.FS, port string) {
 	}
 	r.SetGlobalCORS(corsConfig)
 
+	sh := NewStreamHandler(fm, hub)
+	sh.CORS = &corsConfig
 	sr := StaticRouter{
-		Router:  r,
-		webpath: webpath,
+		Router:        r,
+		StreamHandler: sh,
+		webpath:       webpath,
 	}
 
 	if len(webpath) > 0 {
@@ -57,12 +62,22 @@ func ListenAndServe(rootpath string, webpath string, webfs fs.FS, port string) {
 // CreateRouter registers the route handlers. This function allows the route handlers
 // to be tested with the mango.Browser
 func CreateRouter(fm FileManager) *mango.Router {
+	return CreateRouterWithStreams(fm, NewStreamHub())
+}
+
+// CreateRouterWithStreams registers the route handlers, publishing the progress of runs
+// to the passed StreamHub
+func CreateRouterWithStreams(fm FileManager, hub *StreamHub) *mango.Router {
 	r := mango.NewRouter()
+	jm := NewJobManager()
 
 	r.RegisterModules([]mango.Registerer{
 		NewSimListHandler(fm),
-		NewSimHandler(fm),
+		NewSimHandler(fm, jm, hub),
 		NewStepHandler(fm),
+		NewJobHandler(jm),
+		NewBatchHandler(fm, jm),
+		NewSweepHandler(fm, jm),
 	})
 
 	return r
@@ -83,5 +98,10 @@ func (sr *StaticRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		sr.embedHandler.ServeHTTP(w, req)
 		return
 	}
+	//The stream route is served outside the API router so that events can be flushed
+	if _, ok := StreamSimID(req.URL.Path); ok && sr.StreamHandler != nil {
+		sr.StreamHandler.ServeHTTP(w, req)
+		return
+	}
 	sr.Router.ServeHTTP(w, req)
 }
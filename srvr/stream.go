@@ -0,0 +1,246 @@
+package srvr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spaceweasel/mango"
+)
+
+// streamBufferSize is the number of events buffered for each subscriber to a stream
+const streamBufferSize = 256
+
+// streamSendTimeout is how long publishing an event other than an iteration waits for a
+// client with a full buffer to make room for it
+const streamSendTimeout = 10 * time.Second
+
+// streamKeepAlive is how often a comment is sent on an idle stream so that proxies do not
+// close the connection
+const streamKeepAlive = 15 * time.Second
+
+// StreamEvent is a Server-Sent Event published on the stream of a simulation. Event is
+// the name of the event and Data is encoded as JSON in the data field of the event.
+type StreamEvent struct {
+	Event string
+	Data  interface{}
+}
+
+// IterationEvent is published at the end of every iteration of a run. Step is the number
+// of the step being run (starting at 1) and Iteration is the index of the iteration within
+// that step.
+type IterationEvent struct {
+	Step          int   `json:"step"`
+	Iteration     int   `json:"iteration"`
+	Colors        []int `json:"colors"`
+	Conversations int   `json:"conversations"`
+}
+
+// StepEvent is published when a step has been saved to the simulation
+type StepEvent struct {
+	Step       int    `json:"step"`
+	ID         string `json:"id"`
+	Path       string `json:"path"`
+	Iterations int    `json:"iterations"`
+}
+
+// EndEvent is published when a run finishes. Steps is the number of steps saved by the run
+// and Error is set if the run failed or was cancelled
+type EndEvent struct {
+	Steps int    `json:"steps"`
+	Error string `json:"error,omitempty"`
+}
+
+// StreamHub passes the events published by runs on a simulation to the clients streaming
+// that simulation. Iteration events are dropped for a client that is not keeping up with the
+// stream rather than slowing down the run, but step and end events wait for the client to
+// make room for them so that it always learns when a step is saved and when the run ends.
+type StreamHub struct {
+	lock sync.Mutex
+	subs map[string]map[*subscriber]bool
+}
+
+// subscriber is a client streaming a simulation. Done is closed when the client
+// unsubscribes so that an event waiting for room in its buffer is abandoned.
+type subscriber struct {
+	events chan StreamEvent
+	done   chan struct{}
+}
+
+// NewStreamHub returns a new instance of a StreamHub
+func NewStreamHub() *StreamHub {
+	return &StreamHub{
+		subs: map[string]map[*subscriber]bool{},
+	}
+}
+
+// Subscribe returns a channel that receives the events published on the simulation, and
+// a function that must be called to unsubscribe when the events are no longer needed
+func (h *StreamHub) Subscribe(simID string) (<-chan StreamEvent, func()) {
+	s := &subscriber{
+		events: make(chan StreamEvent, streamBufferSize),
+		done:   make(chan struct{}),
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.subs[simID] == nil {
+		h.subs[simID] = map[*subscriber]bool{}
+	}
+	h.subs[simID][s] = true
+	return s.events, func() {
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		delete(h.subs[simID], s)
+		if len(h.subs[simID]) == 0 {
+			delete(h.subs, simID)
+		}
+		close(s.done)
+	}
+}
+
+// Publish sends the event to every client subscribed to the simulation. An iteration event
+// is dropped for a client whose buffer is full, any other event waits for room in the buffer
+// until the client unsubscribes or streamSendTimeout has passed.
+func (h *StreamHub) Publish(simID string, ev StreamEvent) {
+	h.lock.Lock()
+	subs := make([]*subscriber, 0, len(h.subs[simID]))
+	for s := range h.subs[simID] {
+		subs = append(subs, s)
+	}
+	h.lock.Unlock()
+	for _, s := range subs {
+		if ev.Event == "iteration" {
+			select {
+			case s.events <- ev:
+			default:
+			}
+			continue
+		}
+		s.send(ev)
+	}
+}
+
+// send waits for room in the buffer of the subscriber for the event
+func (s *subscriber) send(ev StreamEvent) {
+	timeout := time.NewTimer(streamSendTimeout)
+	defer timeout.Stop()
+	select {
+	case s.events <- ev:
+	case <-s.done:
+	case <-timeout.C:
+	}
+}
+
+// subscribers returns the number of clients subscribed to the simulation
+func (h *StreamHub) subscribers(simID string) int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.subs[simID])
+}
+
+// StreamHandler serves the Server-Sent Event stream of a simulation. It is a plain
+// http.Handler rather than a mango route because the events must be flushed to the
+// client as they are written.
+type StreamHandler struct {
+	FileManager FileManager
+	Hub         *StreamHub
+	// CORS is the policy the API router is configured with. The stream is served outside
+	// the router so the policy is applied by the handler, no CORS headers are set if it is nil.
+	CORS *mango.CORSConfig
+}
+
+// NewStreamHandler returns a new instance of StreamHandler
+func NewStreamHandler(fm FileManager, hub *StreamHub) *StreamHandler {
+	return &StreamHandler{
+		FileManager: fm,
+		Hub:         hub,
+	}
+}
+
+// StreamSimID returns the simulation ID if the path is the stream route for a simulation,
+// /api/simulation/{sim_id}/stream, otherwise it returns false
+func StreamSimID(path string) (string, bool) {
+	elems := strings.Split(strings.Trim(path, "/"), "/")
+	if len(elems) != 4 || elems[0] != "api" || elems[1] != "simulation" || elems[3] != "stream" {
+		return "", false
+	}
+	return elems[2], true
+}
+
+// allowOrigin sets the CORS headers on the response if the origin of the request is
+// permitted by the CORS policy, echoing the origin in the same way as the API router
+func (sh *StreamHandler) allowOrigin(w http.ResponseWriter, req *http.Request) {
+	origin := req.Header.Get("Origin")
+	if sh.CORS == nil || origin == "" {
+		return
+	}
+	for _, o := range sh.CORS.Origins {
+		if o == "*" || o == origin {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+			return
+		}
+	}
+}
+
+// ServeHTTP streams the events published on the simulation until the client disconnects
+func (sh *StreamHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	simID, ok := StreamSimID(req.URL.Path)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	siminfo := NewSimInfo(simID)
+	err := sh.FileManager.Get(siminfo.Filepath()).Read(siminfo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	events, unsubscribe := sh.Hub.Subscribe(simID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	sh.allowOrigin(w, req)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev := <-events:
+			data, err := json.Marshal(ev.Data)
+			if err != nil {
+				continue
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Event, data)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package srvr
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spaceweasel/mango"
+)
+
+type TestEvent struct {
+	Event string
+	Data  string
+}
+
+func CreateStreamServer(tfm *TestFileManager) (*httptest.Server, *StreamHub) {
+	hub := NewStreamHub()
+	sr := &StaticRouter{
+		Router:        CreateRouterWithStreams(tfm, hub),
+		StreamHandler: NewStreamHandler(tfm, hub),
+	}
+	return httptest.NewServer(sr), hub
+}
+
+// ReadEvents reads events from the stream until an end event is received
+func ReadEvents(t *testing.T, resp *http.Response) []TestEvent {
+	events := []TestEvent{}
+	ev := TestEvent{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			ev.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.Data = strings.TrimPrefix(line, "data: ")
+		case line == "":
+			events = append(events, ev)
+			if ev.Event == "end" {
+				return events
+			}
+			ev = TestEvent{}
+		}
+	}
+	t.Fatalf("Stream closed before the end event")
+	return events
+}
+
+func WaitForSubscriber(t *testing.T, hub *StreamHub, simid string) {
+	for i := 0; i < 500; i++ {
+		if hub.subscribers(simid) > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("No subscriber to the stream")
+}
+
+func TestStreamSimID(t *testing.T) {
+	id, ok := StreamSimID("/api/simulation/abc/stream")
+	IsTrue(t, ok, "Stream path not recognised")
+	AreEqual(t, "abc", id, "Wrong simulation ID")
+	_, ok = StreamSimID("/api/simulation/abc/step")
+	IsFalse(t, ok, "Step path recognised as a stream")
+	_, ok = StreamSimID("/api/simulation/abc/step/stream")
+	IsFalse(t, ok, "Step path recognised as a stream")
+}
+
+func TestStreamPublishesIterationsStepsAndEnd(t *testing.T) {
+	tfm, _, _, _, _, simid := CreateTestFileManagerWithSteps(2)
+	ts, hub := CreateStreamServer(tfm)
+	defer ts.Close()
+
+	resp, err := http.Get(fmt.Sprintf("%s/api/simulation/%s/stream", ts.URL, simid))
+	AssertSuccess(t, err)
+	defer resp.Body.Close()
+	AreEqual(t, http.StatusOK, resp.StatusCode, "Not OK")
+	AreEqual(t, "text/event-stream", resp.Header.Get("Content-Type"), "Wrong content type")
+	WaitForSubscriber(t, hub, simid)
+
+	rresp, err := http.Post(fmt.Sprintf("%s/api/simulation/%s/run", ts.URL, simid), "application/json", strings.NewReader(`{"steps":2,"iterations":3}`))
+	AssertSuccess(t, err)
+	rresp.Body.Close()
+	AreEqual(t, http.StatusCreated, rresp.StatusCode, "Not created")
+
+	events := ReadEvents(t, resp)
+	AreEqual(t, 9, len(events), "Wrong number of events")
+	for i, ev := range events {
+		switch i {
+		case 3, 7:
+			AreEqual(t, "step", ev.Event, fmt.Sprintf("Event %d is not a step event", i))
+			se := StepEvent{}
+			AssertSuccess(t, json.Unmarshal([]byte(ev.Data), &se))
+			AreEqual(t, i/4+1, se.Step, "Wrong step number")
+			AreEqual(t, 3, se.Iterations, "Wrong number of iterations in step")
+			AreEqual(t, fmt.Sprintf("/api/simulation/%s/step/%s", simid, se.ID), se.Path, "Wrong step path")
+		case 8:
+			AreEqual(t, "end", ev.Event, "Last event is not an end event")
+			ee := EndEvent{}
+			AssertSuccess(t, json.Unmarshal([]byte(ev.Data), &ee))
+			AreEqual(t, 2, ee.Steps, "Wrong number of steps in end event")
+			AreEqual(t, "", ee.Error, "End event has an error")
+		default:
+			AreEqual(t, "iteration", ev.Event, fmt.Sprintf("Event %d is not an iteration event", i))
+			ie := IterationEvent{}
+			AssertSuccess(t, json.Unmarshal([]byte(ev.Data), &ie))
+			AreEqual(t, i/4+1, ie.Step, "Wrong step number")
+			AreEqual(t, i%4, ie.Iteration, "Wrong iteration")
+			AreEqual(t, 4, len(ie.Colors), "Wrong number of colors")
+		}
+	}
+}
+
+func TestStreamFailsWhenSimulationNotFound(t *testing.T) {
+	tfm, _, _, _, _, _ := CreateTestFileManagerWithSteps(2)
+	tfm.Default = &TestFileUpdater{ReadErr: fmt.Errorf("not found")}
+	ts, _ := CreateStreamServer(tfm)
+	defer ts.Close()
+
+	resp, err := http.Get(fmt.Sprintf("%s/api/simulation/%s/stream", ts.URL, "unknown"))
+	AssertSuccess(t, err)
+	resp.Body.Close()
+	AreEqual(t, http.StatusNotFound, resp.StatusCode, "Not Not found")
+}
+
+func TestStreamAppliesCORSPolicy(t *testing.T) {
+	tfm, _, _, _, _, simid := CreateTestFileManagerWithSteps(2)
+	hub := NewStreamHub()
+	sh := NewStreamHandler(tfm, hub)
+	sh.CORS = &mango.CORSConfig{Origins: []string{"http://allowed.com"}}
+	ts := httptest.NewServer(&StaticRouter{Router: CreateRouterWithStreams(tfm, hub), StreamHandler: sh})
+	defer ts.Close()
+
+	origin := func(o string) string {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/simulation/%s/stream", ts.URL, simid), nil)
+		AssertSuccess(t, err)
+		req.Header.Set("Origin", o)
+		resp, err := http.DefaultClient.Do(req)
+		AssertSuccess(t, err)
+		resp.Body.Close()
+		AreEqual(t, http.StatusOK, resp.StatusCode, "Not OK")
+		return resp.Header.Get("Access-Control-Allow-Origin")
+	}
+	AreEqual(t, "http://allowed.com", origin("http://allowed.com"), "Permitted origin not allowed")
+	AreEqual(t, "", origin("http://other.com"), "Origin not permitted by the policy allowed")
+
+	sh.CORS = nil
+	AreEqual(t, "", origin("http://allowed.com"), "Origin allowed without a policy")
+}
+
+func TestStreamHubDropsEventsForSlowSubscribers(t *testing.T) {
+	hub := NewStreamHub()
+	events, unsubscribe := hub.Subscribe("sim1")
+	for i := 0; i < streamBufferSize+10; i++ {
+		hub.Publish("sim1", StreamEvent{"iteration", IterationEvent{Iteration: i}})
+	}
+	AreEqual(t, streamBufferSize, len(events), "Events not dropped")
+	hub.Publish("sim2", StreamEvent{"iteration", IterationEvent{}})
+	AreEqual(t, streamBufferSize, len(events), "Received an event for another simulation")
+	unsubscribe()
+	AreEqual(t, 0, hub.subscribers("sim1"), "Not unsubscribed")
+}
+
+func TestStreamHubDeliversEndEventToSlowSubscribers(t *testing.T) {
+	hub := NewStreamHub()
+	events, unsubscribe := hub.Subscribe("sim1")
+	defer unsubscribe()
+	for i := 0; i < streamBufferSize+10; i++ {
+		hub.Publish("sim1", StreamEvent{"iteration", IterationEvent{Iteration: i}})
+	}
+	published := make(chan bool)
+	go func() {
+		hub.Publish("sim1", StreamEvent{"step", StepEvent{Step: 1}})
+		hub.Publish("sim1", StreamEvent{"end", EndEvent{Steps: 1}})
+		close(published)
+	}()
+
+	received := []string{}
+	for len(received) < streamBufferSize+2 {
+		received = append(received, (<-events).Event)
+	}
+	<-published
+	AreEqual(t, "step", received[streamBufferSize], "Step event dropped from a full buffer")
+	AreEqual(t, "end", received[streamBufferSize+1], "End event dropped from a full buffer")
+}
+
+func TestStreamHubAbandonsEndEventWhenUnsubscribed(t *testing.T) {
+	hub := NewStreamHub()
+	_, unsubscribe := hub.Subscribe("sim1")
+	for i := 0; i < streamBufferSize; i++ {
+		hub.Publish("sim1", StreamEvent{"iteration", IterationEvent{Iteration: i}})
+	}
+	published := make(chan bool)
+	go func() {
+		hub.Publish("sim1", StreamEvent{"end", EndEvent{}})
+		close(published)
+	}()
+	unsubscribe()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatalf("Publish still waiting for a client that has unsubscribed")
+	}
+}
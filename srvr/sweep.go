@@ -0,0 +1,103 @@
+package srvr
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/codeafix/orgnetsim/sweep"
+	"github.com/google/uuid"
+)
+
+// SweepSpec specifies a parameter sweep to perform on a simulation. If the Experiment has
+// no Hierarchy every run starts from a copy of the network in the latest step of the
+// simulation. If Async is set the sweep is performed in a background job and the job is
+// returned immediately.
+type SweepSpec struct {
+	sweep.Experiment
+	Async bool `json:"async,omitempty"`
+}
+
+// Sweep holds the table of results of a parameter sweep on a simulation, together with
+// the spec the sweep was run with
+type Sweep struct {
+	TimestampHolder
+	ID       string       `json:"id"`
+	ParentID string       `json:"parent"`
+	Spec     SweepSpec    `json:"spec"`
+	Results  *sweep.Table `json:"results"`
+}
+
+// SweepSummary holds a summary of a sweep, excluding the results. This is used for listings
+// of sweeps to reduce payload size.
+type SweepSummary struct {
+	ID       string    `json:"id"`
+	ParentID string    `json:"parent"`
+	Spec     SweepSpec `json:"spec"`
+}
+
+// CreateSweep creates a new Sweep object with a new ID
+func CreateSweep(parentID string) *Sweep {
+	return NewSweep(uuid.New().String(), parentID)
+}
+
+// NewSweep returns a Sweep object for the passed ID
+func NewSweep(id string, parentID string) *Sweep {
+	return &Sweep{
+		ID:       id,
+		ParentID: parentID,
+	}
+}
+
+// NewSweepFromRelPath returns a Sweep object extracting IDs from the relative path in the
+// passed string
+func NewSweepFromRelPath(relPath string) *Sweep {
+	elems := strings.Split(relPath, "/")
+	return NewSweep(elems[len(elems)-1], elems[len(elems)-3])
+}
+
+// CopyValues copies the values from the passed Sweep object to this object.
+// Returns an error if the values could not be copied
+func (s *Sweep) CopyValues(obj Persistable) error {
+	sToCopy, ok := obj.(*Sweep)
+	if !ok {
+		return fmt.Errorf("failed to copy values")
+	}
+	s.Spec = sToCopy.Spec
+	s.Results = sToCopy.Results
+	return nil
+}
+
+// Filepath returns the Filepath used by this item
+func (s *Sweep) Filepath() string {
+	return fmt.Sprintf("sweep_%s.json", s.ID)
+}
+
+// RelPath returns the relative API path for this item
+func (s *Sweep) RelPath() string {
+	return fmt.Sprintf("/api/simulation/%s/experiment/%s", s.ParentID, s.ID)
+}
+
+// Summary returns the sweep without its results
+func (s *Sweep) Summary() interface{} {
+	return &SweepSummary{
+		ID:       s.ID,
+		ParentID: s.ParentID,
+		Spec:     s.Spec,
+	}
+}
+
+// HasResults returns false if the sweep has no results
+func (s *Sweep) HasResults() bool {
+	return s.Results != nil
+}
+
+// WriteCsv writes the table of results of the sweep in csv format
+func (s *Sweep) WriteCsv(w io.Writer) error {
+	return s.Results.WriteCsv(w)
+}
+
+// CsvName returns the name of the csv file the results are downloaded as
+func (s *Sweep) CsvName() string {
+	return "sweep-" + s.ID
+}
@@ -0,0 +1,106 @@
+package srvr
+
+import (
+	"context"
+
+	"github.com/codeafix/orgnetsim/sim"
+	"github.com/codeafix/orgnetsim/sweep"
+	"github.com/spaceweasel/mango"
+)
+
+// SweepHandlerState holds state data for the SweepHandler
+type SweepHandlerState struct {
+	AnalysisHandlerState
+}
+
+// SweepHandler provides methods to run, read and delete parameter sweeps on a simulation
+type SweepHandler interface {
+	mango.Registerer
+	GetList(c *mango.Context)
+	Post(c *mango.Context)
+	Get(c *mango.Context)
+	Delete(c *mango.Context)
+}
+
+// NewSweepHandler returns a new instance of SweepHandler
+func NewSweepHandler(fm FileManager, jm JobManager) SweepHandler {
+	sh := &SweepHandlerState{
+		NewAnalysisHandlerState(fm, jm, "sweep", func(relPath string) Analysis {
+			return NewSweepFromRelPath(relPath)
+		}),
+	}
+	sh.ListHandlerState.EncodeFunc = sh.EncodeList
+	return sh
+}
+
+// Register the routes for this routehandler. Sweeps are served under "experiment" because
+// the router does not backtrack, so a literal segment starting with "s" would hide the
+// step list served by the SimHandler.
+func (sh *SweepHandlerState) Register(r *mango.Router) {
+	r.Get("/api/simulation/{sim_id}/experiment", sh.GetList)
+	r.Post("/api/simulation/{sim_id}/experiment", sh.Post)
+	r.Get("/api/simulation/{sim_id}/experiment/{sweep_id}", sh.Get)
+	r.Delete("/api/simulation/{sim_id}/experiment/{sweep_id}", sh.Delete)
+}
+
+// Post performs a parameter sweep on the simulation and saves the table of results. The
+// runs start from the network in the latest step, or from networks generated from the
+// HierarchySpec in the SweepSpec if one is given. The simulation itself is not changed.
+func (sh *SweepHandlerState) Post(c *mango.Context) {
+	ss := SweepSpec{}
+	siminfo, ok := sh.ReadSpec(c, &ss)
+	if !ok {
+		return
+	}
+	var base sim.RelationshipMgr
+	if ss.Hierarchy == nil {
+		base, ok = sh.LatestNetwork(c, siminfo)
+		if !ok {
+			return
+		}
+	}
+	//Record the seed so that the sweep can be reproduced
+	if ss.Seed == 0 {
+		ss.Seed = sim.NewSeed()
+	}
+	runs := ss.Combinations()
+	if ss.Repeats > 1 {
+		runs *= ss.Repeats
+	}
+	sh.Run(c, siminfo, ss.Async, runs, ss.Run.Iterations, func(ctx context.Context, jp *JobProgress) (Analysis, error) {
+		return sh.runSweep(ctx, base, siminfo, ss, jp)
+	})
+}
+
+// runSweep performs the runs in the SweepSpec and adds the sweep to the simulation. The
+// number of runs completed is reported to the JobProgress if it is not nil. Nothing is saved
+// if the context is cancelled before all the runs complete.
+func (sh *SweepHandlerState) runSweep(ctx context.Context, base sim.RelationshipMgr, siminfo *SimInfo, ss SweepSpec, jp *JobProgress) (*Sweep, error) {
+	results, err := sweep.Run(ctx, &ss.Experiment, base, func(completed, total int) {
+		if jp != nil {
+			jp.RunsCompleted(completed)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	s := CreateSweep(siminfo.ID)
+	s.Spec = ss
+	s.Spec.Async = false
+	s.Results = results
+	err = sh.Save(s, siminfo, jp)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns a sweep, in text/csv format if that is the content type of the request
+func (sh *SweepHandlerState) Get(c *mango.Context) {
+	sh.GetItem(NewSweep(c.RouteParams["sweep_id"], c.RouteParams["sim_id"]), c)
+}
+
+// Delete removes a sweep from the simulation
+func (sh *SweepHandlerState) Delete(c *mango.Context) {
+	sh.DeleteFromSim(NewSweep(c.RouteParams["sweep_id"], c.RouteParams["sim_id"]), c)
+}
@@ -0,0 +1,134 @@
+package srvr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/codeafix/orgnetsim/sweep"
+	"github.com/spaceweasel/mango"
+)
+
+func CreateSweepHandlerBrowser() (*mango.Browser, *TestFileManager, *TestFileUpdater, *TestFileUpdater, string) {
+	tfm, simfu, _, dfu, _, simid := CreateTestFileManagerWithSteps(2)
+	r := CreateRouter(tfm)
+	return mango.NewBrowser(r), tfm, simfu, dfu, simid
+}
+
+func AddTestSweep(tfm *TestFileManager, simfu *TestFileUpdater, simid string) *Sweep {
+	s := CreateSweep(simid)
+	s.Spec = SweepSpec{Experiment: sweep.Experiment{
+		Run:        sweep.RunSettings{Iterations: 2},
+		Parameters: []sweep.Parameter{{Name: "run.iterations", Values: []interface{}{2.0}}},
+		Seed:       3,
+	}}
+	s.Results = &sweep.Table{
+		Parameters: []string{"run.iterations"},
+		Rows: []*sweep.Row{
+			{Run: 0, Repeat: 0, Seed: 7, Params: map[string]interface{}{"run.iterations": 2.0}, Agents: 2, Iterations: 2, FinalShare: []float64{0.5, 0.5}, HalfAdoption: 1, Conversations: 4},
+		},
+	}
+	tfm.Add(s.Filepath(), &TestFileUpdater{Obj: s, Filepath: s.Filepath()})
+	si := simfu.Obj.(*SimInfo)
+	si.Sweeps = append(si.Sweeps, s.RelPath())
+	return s
+}
+
+func TestPostSweepRunsFromLatestStep(t *testing.T) {
+	br, _, simfu, dfu, simid := CreateSweepHandlerBrowser()
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	data := `{"run":{"iterations":5},"parameters":[{"name":"run.iterations","from":2,"to":4,"step":1}],"repeats":2,"seed":4}`
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/experiment", simid), data, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	s := dfu.Obj.(*Sweep)
+	AreEqual(t, simid, s.ParentID, "Wrong parent")
+	AreEqual(t, int64(4), s.Spec.Seed, "Wrong seed")
+	AreEqual(t, 6, len(s.Results.Rows), "Wrong number of rows")
+	AreEqual(t, 3, s.Results.Rows[0].Agents, "Wrong number of agents")
+	AreEqual(t, 4, s.Results.Rows[5].Iterations, "Wrong number of iterations")
+	si := simfu.Obj.(*SimInfo)
+	AreEqual(t, 1, len(si.Sweeps), "Sweep not added to the simulation")
+	AreEqual(t, s.RelPath(), si.Sweeps[0], "Wrong sweep path")
+	AreEqual(t, 3, len(si.Steps), "Steps should not change")
+}
+
+func TestPostSweepGeneratesNetworksFromHierarchy(t *testing.T) {
+	br, _, simfu, dfu, simid := CreateSweepHandlerBrowser()
+	simfu.Obj.(*SimInfo).Steps = []string{}
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	data := `{"hierarchy":{"levels":2,"teamSize":3,"initColors":[0,1],"maxColors":3},"run":{"iterations":5},"parameters":[{"name":"hierarchy.teamSize","values":[2,4]}],"parallel":2}`
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/experiment", simid), data, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusCreated, resp.Code, "Not created")
+	s := dfu.Obj.(*Sweep)
+	NotEqual(t, int64(0), s.Spec.Seed, "Seed not recorded")
+	AreEqual(t, 2, len(s.Results.Rows), "Wrong number of rows")
+	AreEqual(t, 3, s.Results.Rows[0].Agents, "Wrong number of agents for teamSize 2")
+	AreEqual(t, 5, s.Results.Rows[1].Agents, "Wrong number of agents for teamSize 4")
+}
+
+func TestPostSweepFailsWithInvalidSpec(t *testing.T) {
+	br, _, _, _, simid := CreateSweepHandlerBrowser()
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	data := `{"run":{"iterations":5},"parameters":[{"name":"hierarchy.teamSize","values":[2,4]}]}`
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/experiment", simid), data, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusBadRequest, resp.Code, "Not Bad request")
+	AreEqual(t, "parameter 'hierarchy.teamSize' cannot be varied without a hierarchy", strings.TrimSpace(resp.Body.String()), "Incorrect error response")
+}
+
+func TestPostSweepAsyncStartsJob(t *testing.T) {
+	br, _, simfu, _, simid := CreateSweepHandlerBrowser()
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "application/json")
+	data := `{"run":{"iterations":5},"parameters":[{"name":"run.iterations","values":[2,3,4]}],"async":true}`
+	resp, err := br.PostS(fmt.Sprintf("/api/simulation/%s/experiment", simid), data, hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusAccepted, resp.Code, "Not accepted")
+	j := Job{}
+	AssertSuccess(t, json.Unmarshal(resp.Body.Bytes(), &j))
+	AreEqual(t, 3, j.Runs, "Wrong number of runs")
+	j = WaitForJob(t, br, j.ID)
+	AreEqual(t, JobCompleted, j.State, "Job not completed")
+	AreEqual(t, 3, j.RunsCompleted, "Completed runs not reported")
+	AreEqual(t, 1, len(j.StepPaths), "Sweep path not reported")
+	AreEqual(t, j.StepPaths[0], simfu.Obj.(*SimInfo).Sweeps[0], "Wrong sweep path reported")
+}
+
+func TestGetSweepListReturnsSummaries(t *testing.T) {
+	br, tfm, simfu, _, simid := CreateSweepHandlerBrowser()
+	s := AddTestSweep(tfm, simfu, simid)
+
+	resp, err := br.Get(fmt.Sprintf("/api/simulation/%s/experiment", simid), http.Header{})
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not OK")
+	items := []*SweepSummary{}
+	AssertSuccess(t, json.Unmarshal(resp.Body.Bytes(), &items))
+	AreEqual(t, 1, len(items), "Wrong number of sweeps")
+	AreEqual(t, s.ID, items[0].ID, "Wrong sweep")
+	AreEqual(t, "run.iterations", items[0].Spec.Parameters[0].Name, "Wrong spec")
+	IsFalse(t, strings.Contains(resp.Body.String(), "rows"), "Results included in the list")
+}
+
+func TestGetSweepAsCsv(t *testing.T) {
+	br, tfm, simfu, _, simid := CreateSweepHandlerBrowser()
+	s := AddTestSweep(tfm, simfu, simid)
+
+	hdrs := http.Header{}
+	hdrs.Set("Content-Type", "text/csv")
+	resp, err := br.Get(fmt.Sprintf("/api/simulation/%s/experiment/%s", simid, s.ID), hdrs)
+	AssertSuccess(t, err)
+	AreEqual(t, http.StatusOK, resp.Code, "Not OK")
+	AreEqual(t, "Run,Repeat,Seed,run.iterations,Agents,Iterations,Stopped,Grey Share,Blue Share,Half Adoption,Conversations,Broadcast Conversions,Error\n0,0,7,2,2,2,,0.5,0.5,1,4,0,\n", resp.Body.String(), "Wrong csv")
+	IsTrue(t, strings.Contains(resp.Header().Get("Content-Disposition"), "sweep-"+s.ID+".csv"), "Wrong filename")
+}
@@ -0,0 +1,305 @@
+package sweep
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/codeafix/orgnetsim/sim"
+)
+
+// The methods of sensitivity analysis
+const (
+	OneAtATime     = "oat"
+	LatinHypercube = "lhs"
+)
+
+// Factor is a numeric field of the Experiment whose effect on the outcome of the runs is
+// measured by a sensitivity analysis. Name is given in the same way as for a Parameter, for
+// example "hierarchy.traits.influence.sd", and the field is varied between Low and High.
+type Factor struct {
+	Name string  `json:"name"`
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// SensitivitySpec defines a sensitivity analysis of the Factors on the runs of the
+// Experiment. The one-at-a-time method varies each Factor in turn across Levels evenly spaced
+// values (five if not set) while the other Factors stay at their values in the Experiment.
+// The Latin hypercube method varies all the Factors together, drawing Samples points (twenty
+// if not set) so that each Factor is sampled once from each of Samples equal divisions of its
+// range. Every point is run Repeats times. If the Hierarchy or Options have no Traits they are
+// given the DefaultTraits so that the trait distributions can be used as Factors.
+type SensitivitySpec struct {
+	Experiment
+	Method  string   `json:"method"`
+	Factors []Factor `json:"factors"`
+	Levels  int      `json:"levels,omitempty"`
+	Samples int      `json:"samples,omitempty"`
+}
+
+// Measure describes how strongly a Factor drives an outcome. Swing is the change in the
+// outcome across the range of the Factor estimated by a least squares fit, and Correlation is
+// the Spearman rank correlation between the value of the Factor and the outcome.
+type Measure struct {
+	Swing       float64 `json:"swing"`
+	Correlation float64 `json:"correlation"`
+}
+
+// Effect holds the effect of a Factor on the number of iterations before half the Agents
+// held a Color other than Grey, and on the final share of Agents holding a Color other than
+// Grey. Runs that never reached half adoption count as one more than the Iterations set for
+// the Experiment. Baseline is the value of the Factor held while the others were varied in a
+// one-at-a-time analysis.
+type Effect struct {
+	Factor       string  `json:"factor"`
+	Low          float64 `json:"low"`
+	High         float64 `json:"high"`
+	Baseline     float64 `json:"baseline"`
+	HalfAdoption Measure `json:"halfAdoption"`
+	Adoption     Measure `json:"adoption"`
+}
+
+// SensitivityResults holds the Effect of each Factor, along with the Table of the runs
+type SensitivityResults struct {
+	Method  string    `json:"method"`
+	Effects []*Effect `json:"effects"`
+	Table   *Table    `json:"table"`
+}
+
+// Validate returns an error if the sensitivity analysis cannot be run
+func (s *SensitivitySpec) Validate() error {
+	if s.Method != OneAtATime && s.Method != LatinHypercube {
+		return fmt.Errorf("method must be '%s' or '%s'", OneAtATime, LatinHypercube)
+	}
+	if len(s.Parameters) > 0 {
+		return errors.New("a sensitivity analysis varies factors, not parameters")
+	}
+	if len(s.Factors) == 0 {
+		return errors.New("at least one factor is required")
+	}
+	if s.Levels < 0 || s.Levels == 1 || s.Samples < 0 {
+		return errors.New("levels must be at least 2 and samples cannot be negative")
+	}
+	//Check the factors can be set to both ends of their range
+	e := s.Experiment
+	for _, f := range s.Factors {
+		if f.High < f.Low {
+			return fmt.Errorf("factor '%s' has a high value less than its low value", f.Name)
+		}
+		e.Parameters = append(e.Parameters, Parameter{Name: f.Name, Values: []interface{}{f.Low, f.High}})
+	}
+	return e.Validate()
+}
+
+// RunSensitivity performs the runs of a sensitivity analysis and measures the Effect of each
+// Factor. If done is not nil it is called with the number of runs completed each time a run
+// finishes. If the context is cancelled the error from the context is returned.
+func RunSensitivity(ctx context.Context, s *SensitivitySpec, base sim.RelationshipMgr, done func(completed, total int)) (*SensitivityResults, error) {
+	if s.Hierarchy != nil && s.Hierarchy.Traits == nil {
+		h := *s.Hierarchy
+		t := sim.DefaultTraits()
+		h.Traits = &t
+		s.Hierarchy = &h
+	}
+	if s.Options != nil && s.Options.Traits == nil {
+		o := *s.Options
+		t := sim.DefaultTraits()
+		o.Traits = &t
+		s.Options = &o
+	}
+	err := s.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if s.Seed == 0 {
+		s.Seed = sim.NewSeed()
+	}
+	names := make([]string, len(s.Factors))
+	baselines := make([]float64, len(s.Factors))
+	for i, f := range s.Factors {
+		names[i] = f.Name
+		v, err := s.value(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case nil:
+		case float64:
+			baselines[i] = v
+		default:
+			return nil, fmt.Errorf("factor '%s' is not a number", f.Name)
+		}
+	}
+
+	var points []map[string]interface{}
+	if s.Method == OneAtATime {
+		points = s.oneAtATimePoints(baselines)
+	} else {
+		points = s.latinHypercubePoints()
+	}
+	table, err := s.runPoints(ctx, names, points, base, done)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &SensitivityResults{Method: s.Method, Table: table}
+	for i, f := range s.Factors {
+		rows := table.Rows
+		if s.Method == OneAtATime {
+			block := len(table.Rows) / len(s.Factors)
+			rows = rows[i*block : (i+1)*block]
+		}
+		x, halfAdoption, adoption := []float64{}, []float64{}, []float64{}
+		for _, row := range rows {
+			if row.Error != "" {
+				continue
+			}
+			x = append(x, row.Params[f.Name].(float64))
+			h := float64(row.HalfAdoption)
+			if row.HalfAdoption < 0 {
+				h = float64(s.Run.Iterations + 1)
+			}
+			halfAdoption = append(halfAdoption, h)
+			adoption = append(adoption, 1-row.FinalShare[sim.Grey])
+		}
+		results.Effects = append(results.Effects, &Effect{
+			Factor:       f.Name,
+			Low:          f.Low,
+			High:         f.High,
+			Baseline:     baselines[i],
+			HalfAdoption: measure(x, halfAdoption, f.High-f.Low),
+			Adoption:     measure(x, adoption, f.High-f.Low),
+		})
+	}
+	return results, nil
+}
+
+// oneAtATimePoints returns Levels points for each Factor in turn, varying that Factor evenly
+// across its range while the other Factors are held at their baseline values
+func (s *SensitivitySpec) oneAtATimePoints(baselines []float64) []map[string]interface{} {
+	levels := s.Levels
+	if levels == 0 {
+		levels = 5
+	}
+	points := []map[string]interface{}{}
+	for _, f := range s.Factors {
+		for l := 0; l < levels; l++ {
+			point := map[string]interface{}{}
+			for j, other := range s.Factors {
+				point[other.Name] = baselines[j]
+			}
+			point[f.Name] = f.Low + float64(l)*(f.High-f.Low)/float64(levels-1)
+			points = append(points, point)
+		}
+	}
+	return points
+}
+
+// latinHypercubePoints returns Samples points in which the range of each Factor is divided
+// into Samples equal intervals and each interval is sampled once, in a random order
+func (s *SensitivitySpec) latinHypercubePoints() []map[string]interface{} {
+	samples := s.Samples
+	if samples == 0 {
+		samples = 20
+	}
+	r := sim.NewRand(s.Seed)
+	points := make([]map[string]interface{}, samples)
+	for i := range points {
+		points[i] = map[string]interface{}{}
+	}
+	for _, f := range s.Factors {
+		for i, stratum := range r.Perm(samples) {
+			points[i][f.Name] = f.Low + (float64(stratum)+r.Float64())*(f.High-f.Low)/float64(samples)
+		}
+	}
+	return points
+}
+
+// measure returns the Measure of the effect of x on y, where span is the range of x
+func measure(x []float64, y []float64, span float64) Measure {
+	return Measure{
+		Swing:       slope(x, y) * span,
+		Correlation: correlation(ranks(x), ranks(y)),
+	}
+}
+
+// slope returns the slope of the least squares fit of y against x
+func slope(x []float64, y []float64) float64 {
+	if len(x) == 0 {
+		return 0
+	}
+	mx, my := mean(x), mean(y)
+	sxy, sxx := 0.0, 0.0
+	for i := range x {
+		sxy += (x[i] - mx) * (y[i] - my)
+		sxx += (x[i] - mx) * (x[i] - mx)
+	}
+	if sxx == 0 {
+		return 0
+	}
+	return sxy / sxx
+}
+
+// correlation returns the Pearson correlation of x and y, or zero if either is constant
+func correlation(x []float64, y []float64) float64 {
+	if len(x) == 0 {
+		return 0
+	}
+	mx, my := mean(x), mean(y)
+	sxy, sxx, syy := 0.0, 0.0, 0.0
+	for i := range x {
+		sxy += (x[i] - mx) * (y[i] - my)
+		sxx += (x[i] - mx) * (x[i] - mx)
+		syy += (y[i] - my) * (y[i] - my)
+	}
+	if sxx == 0 || syy == 0 {
+		return 0
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// ranks returns the rank of each value, giving tied values the mean of their ranks
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+	ret := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j < len(order) && values[order[j]] == values[order[i]] {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			ret[order[k]] = rank
+		}
+		i = j
+	}
+	return ret
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// WriteCsv writes the Effects in csv format with a row for each Factor
+func (sr *SensitivityResults) WriteCsv(w io.Writer) error {
+	var buffer bytes.Buffer
+	buffer.WriteString("Factor,Low,High,Baseline,Half Adoption Swing,Half Adoption Correlation,Adoption Swing,Adoption Correlation\n")
+	for _, e := range sr.Effects {
+		buffer.WriteString(fmt.Sprintf("%s,%g,%g,%g,%g,%g,%g,%g\n", csvValue(e.Factor), e.Low, e.High, e.Baseline, e.HalfAdoption.Swing, e.HalfAdoption.Correlation, e.Adoption.Swing, e.Adoption.Correlation))
+	}
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
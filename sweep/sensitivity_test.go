@@ -0,0 +1,108 @@
+package sweep
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/codeafix/orgnetsim/sim"
+)
+
+func CreateSensitivitySpec(method string, factors ...Factor) *SensitivitySpec {
+	e := CreateExperiment()
+	e.Hierarchy.InitColors = []sim.Color{sim.Grey, sim.Grey, sim.Grey, sim.Red}
+	return &SensitivitySpec{
+		Experiment: *e,
+		Method:     method,
+		Factors:    factors,
+	}
+}
+
+func TestOneAtATimeVariesEachFactorInTurn(t *testing.T) {
+	s := CreateSensitivitySpec(OneAtATime,
+		Factor{Name: "hierarchy.traits.influence.mean", Low: 0.5, High: 1.5},
+		Factor{Name: "hierarchy.traits.susceptability.sd", Low: 0, High: 0.5},
+	)
+	s.Levels = 3
+	s.Repeats = 2
+	results, err := RunSensitivity(context.Background(), s, nil, nil)
+	AssertSuccess(t, err)
+	AreEqual(t, OneAtATime, results.Method, "Wrong method")
+	AreEqual(t, 12, len(results.Table.Rows), "Wrong number of runs")
+	AreEqual(t, 2, len(results.Effects), "Wrong number of effects")
+	AreEqual(t, 1.0, results.Effects[0].Baseline, "Wrong baseline from the default traits")
+	AreEqual(t, 0.25, results.Effects[1].Baseline, "Wrong baseline from the default traits")
+	//The first factor varies while the second is held at its baseline
+	AreEqual(t, 0.5, results.Table.Rows[0].Params["hierarchy.traits.influence.mean"], "Wrong first value")
+	AreEqual(t, 1.0, results.Table.Rows[2].Params["hierarchy.traits.influence.mean"], "Wrong second value")
+	AreEqual(t, 0.25, results.Table.Rows[2].Params["hierarchy.traits.susceptability.sd"], "Other factor not at its baseline")
+	AreEqual(t, 1.0, results.Table.Rows[6].Params["hierarchy.traits.influence.mean"], "Factor not returned to its baseline")
+	AreEqual(t, 0.0, results.Table.Rows[6].Params["hierarchy.traits.susceptability.sd"], "Wrong first value")
+	IsTrue(t, s.Hierarchy.Traits != nil, "Default traits not recorded in the spec")
+	for _, e := range results.Effects {
+		IsTrue(t, math.Abs(e.Adoption.Correlation) <= 1, "Correlation out of range")
+		IsTrue(t, math.Abs(e.HalfAdoption.Correlation) <= 1, "Correlation out of range")
+	}
+}
+
+func TestInfluenceDrivesAdoption(t *testing.T) {
+	s := CreateSensitivitySpec(LatinHypercube, Factor{Name: "hierarchy.traits.influence.mean", Low: 0, High: 2})
+	s.Hierarchy.Traits = &sim.Traits{
+		Influence:      sim.TraitDistribution{Type: sim.Normal, Mean: 1, SD: 0.1},
+		Susceptability: sim.TraitDistribution{Type: sim.Fixed, Value: 1},
+		Contrariness:   sim.TraitDistribution{Type: sim.Fixed, Value: 0},
+	}
+	s.Samples = 10
+	results, err := RunSensitivity(context.Background(), s, nil, nil)
+	AssertSuccess(t, err)
+	AreEqual(t, 10, len(results.Table.Rows), "Wrong number of runs")
+	values := map[int]bool{}
+	for _, row := range results.Table.Rows {
+		v := row.Params["hierarchy.traits.influence.mean"].(float64)
+		values[int(v/0.2)] = true
+	}
+	AreEqual(t, 10, len(values), "Each interval of the range not sampled exactly once")
+	e := results.Effects[0]
+	IsTrue(t, e.Adoption.Correlation > 0.5, "Influence should drive adoption")
+	IsTrue(t, e.Adoption.Swing > 0, "Adoption should increase with influence")
+	IsTrue(t, e.HalfAdoption.Correlation < -0.5, "Influence should shorten the time to adoption")
+}
+
+func TestSensitivityValidateFails(t *testing.T) {
+	s := CreateSensitivitySpec("grid", Factor{Name: "run.iterations", Low: 1, High: 2})
+	AreEqual(t, "method must be 'oat' or 'lhs'", s.Validate().Error(), "Wrong error")
+	s = CreateSensitivitySpec(OneAtATime)
+	AreEqual(t, "at least one factor is required", s.Validate().Error(), "Wrong error")
+	s = CreateSensitivitySpec(OneAtATime, Factor{Name: "run.iterations", Low: 2, High: 1})
+	AreEqual(t, "factor 'run.iterations' has a high value less than its low value", s.Validate().Error(), "Wrong error")
+	s = CreateSensitivitySpec(OneAtATime, Factor{Name: "hierarchy.traits.influence.mean", Low: 0, High: 1})
+	AreEqual(t, "unknown parameter 'hierarchy.traits.influence.mean'", s.Validate().Error(), "Traits must be set to be validated")
+	_, err := RunSensitivity(context.Background(), CreateSensitivitySpec(OneAtATime, Factor{Name: "hierarchy.traits.influence.type", Low: 0, High: 1}), nil, nil)
+	IsTrue(t, err != nil, "Expected an error for a factor that is not a number")
+}
+
+func TestRanksAverageTies(t *testing.T) {
+	r := ranks([]float64{3, 1, 3, 2})
+	AreEqual(t, 3.5, r[0], "Wrong rank")
+	AreEqual(t, 1.0, r[1], "Wrong rank")
+	AreEqual(t, 3.5, r[2], "Wrong rank")
+	AreEqual(t, 2.0, r[3], "Wrong rank")
+	AreEqual(t, 1.0, correlation(ranks([]float64{1, 2, 3}), ranks([]float64{1, 4, 9})), "Monotonic values not perfectly correlated")
+	AreEqual(t, 0.0, correlation([]float64{1, 2, 3}, []float64{5, 5, 5}), "Constant values should not be correlated")
+}
+
+func TestSensitivityWriteCsv(t *testing.T) {
+	results := &SensitivityResults{
+		Method: OneAtATime,
+		Effects: []*Effect{
+			{Factor: "hierarchy.traits.influence.mean", Low: 0.5, High: 1.5, Baseline: 1, HalfAdoption: Measure{Swing: -10, Correlation: -0.9}, Adoption: Measure{Swing: 0.5, Correlation: 0.8}},
+		},
+	}
+	var buffer bytes.Buffer
+	AssertSuccess(t, results.WriteCsv(&buffer))
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	AreEqual(t, "Factor,Low,High,Baseline,Half Adoption Swing,Half Adoption Correlation,Adoption Swing,Adoption Correlation", lines[0], "Wrong header")
+	AreEqual(t, "hierarchy.traits.influence.mean,0.5,1.5,1,-10,-0.9,0.5,0.8", lines[1], "Wrong row")
+}
@@ -0,0 +1,513 @@
+// Package sweep runs parameter sweep experiments, simulating every combination of a grid of
+// values for the fields of a HierarchySpec, NetworkOptions and run settings, and collects
+// the outcome of every run in a tidy table with one row per run.
+package sweep
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/codeafix/orgnetsim/sim"
+)
+
+// The sections of an Experiment that a Parameter can vary
+const (
+	HierarchySection = "hierarchy"
+	OptionsSection   = "options"
+	RunSection       = "run"
+)
+
+// RunSettings are the settings used to run the simulation for each combination of parameters
+type RunSettings struct {
+	Iterations    int                      `json:"iterations"`
+	Scheduler     sim.SchedulerMode        `json:"scheduler,omitempty"`
+	Selection     sim.PartnerSelection     `json:"selection"`
+	Dynamics      sim.LinkDynamics         `json:"dynamics"`
+	Hierarchy     sim.HierarchyInfluence   `json:"hierarchy"`
+	Interventions sim.InterventionSchedule `json:"interventions,omitempty"`
+	Broadcasts    sim.Broadcasts           `json:"broadcasts,omitempty"`
+	Stop          sim.StopConditions       `json:"stop"`
+}
+
+// Parameter is a field of the Experiment that is varied across the runs. Name is the
+// section of the Experiment followed by the json name of the field, for example
+// "hierarchy.teamSize", "options.linkTeamPeers" or "run.stop.colorShare". The values are
+// either listed in Values, or generated from From to To inclusive in increments of Step.
+type Parameter struct {
+	Name   string        `json:"name"`
+	Values []interface{} `json:"values,omitempty"`
+	From   float64       `json:"from,omitempty"`
+	To     float64       `json:"to,omitempty"`
+	Step   float64       `json:"step,omitempty"`
+}
+
+// Experiment defines a parameter sweep. Every combination of the values of the Parameters
+// is run Repeats times (once if not set). The network for each run is generated from the
+// Hierarchy if there is one, otherwise it is the base network passed to Run. If Options
+// are given the network is then cloned and modified by them, as when a network is parsed.
+// The seed of each repeat is drawn from Seed, and each repeat uses the same seed for every
+// combination so that the combinations are compared on the same random numbers. Parallel
+// is the number of runs performed at the same time, the default is one.
+type Experiment struct {
+	Hierarchy  *sim.HierarchySpec  `json:"hierarchy,omitempty"`
+	Options    *sim.NetworkOptions `json:"options,omitempty"`
+	Run        RunSettings         `json:"run"`
+	Parameters []Parameter         `json:"parameters"`
+	Repeats    int                 `json:"repeats,omitempty"`
+	Seed       int64               `json:"seed,omitempty"`
+	Parallel   int                 `json:"parallel,omitempty"`
+}
+
+// Row is the outcome of a single run. Params holds the value of each Parameter used for the
+// run. Iterations is the number of iterations run, and Stopped is the stop condition that
+// ended the run early, if any. FinalShare is the fraction of Agents holding each Color at
+// the end of the run, and HalfAdoption is the number of iterations it took for half the
+// Agents to hold a Color other than Grey, or -1 if that never happened. BroadcastConversions
+// is the number of changes of Color caused by Broadcasts during the run. If the run could not
+// be performed Error holds the reason.
+type Row struct {
+	Run                  int                    `json:"run"`
+	Repeat               int                    `json:"repeat"`
+	Seed                 int64                  `json:"seed"`
+	Params               map[string]interface{} `json:"params"`
+	Agents               int                    `json:"agents"`
+	Iterations           int                    `json:"iterations"`
+	Stopped              sim.StopCondition      `json:"stopped,omitempty"`
+	FinalShare           []float64              `json:"finalShare"`
+	HalfAdoption         int                    `json:"halfAdoption"`
+	Conversations        int                    `json:"conversations"`
+	BroadcastConversions int                    `json:"broadcastConversions,omitempty"`
+	Error                string                 `json:"error,omitempty"`
+}
+
+// Table holds a Row for every run in the sweep, ordered by combination then repeat
+type Table struct {
+	Parameters []string `json:"parameters"`
+	Rows       []*Row   `json:"rows"`
+}
+
+// Validate returns an error if the Experiment cannot be run
+func (e *Experiment) Validate() error {
+	if e.Repeats < 0 || e.Parallel < 0 {
+		return errors.New("repeats and parallel cannot be negative")
+	}
+	if e.Run.Iterations <= 0 {
+		return errors.New("run.iterations must be greater than zero")
+	}
+	names := map[string]bool{}
+	for _, p := range e.Parameters {
+		if names[p.Name] {
+			return fmt.Errorf("parameter '%s' is listed more than once", p.Name)
+		}
+		names[p.Name] = true
+		section, _, _ := strings.Cut(p.Name, ".")
+		switch section {
+		case HierarchySection:
+			if e.Hierarchy == nil {
+				return fmt.Errorf("parameter '%s' cannot be varied without a hierarchy", p.Name)
+			}
+		case OptionsSection:
+			if e.Options == nil {
+				return fmt.Errorf("parameter '%s' cannot be varied without options", p.Name)
+			}
+		case RunSection:
+		default:
+			return fmt.Errorf("parameter '%s' must start with hierarchy, options or run", p.Name)
+		}
+		values, err := p.values()
+		if err != nil {
+			return err
+		}
+		for _, v := range values {
+			_, err := e.with(p.Name, v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Combinations returns the number of combinations of the values of the Parameters
+func (e *Experiment) Combinations() int {
+	count := 1
+	for _, p := range e.Parameters {
+		values, _ := p.values()
+		count *= len(values)
+	}
+	return count
+}
+
+// Run performs the runs for every combination of parameters and returns a Table of their
+// outcomes. The base network is used if the Experiment has no Hierarchy. If done is not nil
+// it is called with the number of runs completed each time a run finishes. If the context is
+// cancelled no more runs are started and the error from the context is returned.
+func Run(ctx context.Context, e *Experiment, base sim.RelationshipMgr, done func(completed, total int)) (*Table, error) {
+	err := e.Validate()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, p := range e.Parameters {
+		names = append(names, p.Name)
+	}
+	return e.runPoints(ctx, names, e.combinations(), base, done)
+}
+
+// runPoints performs Repeats runs for each of the passed points, which map the names of the
+// parameters to the values used for the runs, and returns a Table of their outcomes
+func (e *Experiment) runPoints(ctx context.Context, names []string, points []map[string]interface{}, base sim.RelationshipMgr, done func(completed, total int)) (*Table, error) {
+	if e.Hierarchy == nil && base == nil {
+		return nil, errors.New("a hierarchy or a base network is required")
+	}
+	var basejson []byte
+	if e.Hierarchy == nil {
+		var err error
+		basejson, err = json.Marshal(base)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if e.Seed == 0 {
+		e.Seed = sim.NewSeed()
+	}
+	repeats := e.Repeats
+	if repeats == 0 {
+		repeats = 1
+	}
+	parallel := e.Parallel
+	if parallel == 0 {
+		parallel = 1
+	}
+	r := sim.NewRand(e.Seed)
+	seeds := make([]int64, repeats)
+	for i := range seeds {
+		seeds[i] = r.Int63()
+	}
+
+	table := &Table{Parameters: names, Rows: make([]*Row, len(points)*repeats)}
+	total := len(table.Rows)
+	var lock sync.Mutex
+	completed := 0
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i := range table.Rows {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			row := &Row{
+				Run:    i,
+				Repeat: i % repeats,
+				Seed:   seeds[i%repeats],
+				Params: points[i/repeats],
+			}
+			row.run(ctx, e, basejson)
+			lock.Lock()
+			defer lock.Unlock()
+			table.Rows[i] = row
+			completed++
+			if done != nil {
+				done(completed, total)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return table, nil
+}
+
+// run performs a single run of the Experiment with the parameters of the Row, and records
+// the outcome in the Row
+func (row *Row) run(ctx context.Context, e *Experiment, basejson []byte) {
+	x := e
+	var err error
+	for _, name := range sortedKeys(row.Params) {
+		x, err = x.with(name, row.Params[name])
+		if err != nil {
+			row.Error = err.Error()
+			return
+		}
+	}
+
+	var n sim.RelationshipMgr
+	if x.Hierarchy != nil {
+		h := *x.Hierarchy
+		h.Seed = row.Seed
+		n, _, err = sim.GenerateHierarchy(h)
+	} else {
+		n, err = sim.NewNetwork(string(basejson))
+	}
+	if err == nil && x.Options != nil {
+		o := *x.Options
+		o.Seed = row.Seed
+		o.SetRand(nil)
+		n, err = o.CloneModify(n)
+	}
+	if err != nil {
+		row.Error = err.Error()
+		return
+	}
+	scheduler, err := sim.NewScheduler(x.Run.Scheduler)
+	if err == nil {
+		err = x.Run.Selection.Validate()
+	}
+	if err == nil {
+		err = x.Run.Dynamics.Validate()
+	}
+	if err == nil {
+		err = x.Run.Hierarchy.Validate()
+	}
+	if err == nil {
+		err = x.Run.Interventions.Validate(n)
+	}
+	if err == nil {
+		err = x.Run.Broadcasts.Validate(n)
+	}
+	if err != nil {
+		row.Error = err.Error()
+		return
+	}
+	runner := sim.NewSeededRunner(n, x.Run.Iterations, row.Seed)
+	runner.SetScheduler(scheduler)
+	runner.SetPartnerSelection(x.Run.Selection)
+	runner.SetLinkDynamics(x.Run.Dynamics)
+	runner.SetHierarchyInfluence(x.Run.Hierarchy)
+	runner.SetInterventions(x.Run.Interventions)
+	runner.SetBroadcasts(x.Run.Broadcasts)
+	runner.SetStopConditions(x.Run.Stop)
+	results, err := runner.RunContext(ctx, nil)
+	if err != nil {
+		row.Error = err.Error()
+		return
+	}
+	row.measure(results, len(n.Agents()), n.MaxColors())
+}
+
+// measure records the outcome metrics of the Results in the Row
+func (row *Row) measure(results sim.Results, agents int, maxColors int) {
+	row.Agents = agents
+	row.Iterations = results.Iterations
+	if results.Stopped != nil {
+		row.Stopped = results.Stopped.Condition
+	}
+	row.FinalShare = make([]float64, maxColors)
+	row.HalfAdoption = -1
+	for i, colors := range results.Colors {
+		if row.HalfAdoption < 0 && 2*(agents-colors[sim.Grey]) >= agents {
+			row.HalfAdoption = i + 1
+		}
+		row.Conversations += results.Conversations[i]
+	}
+	for _, c := range results.BroadcastConversions {
+		row.BroadcastConversions += c
+	}
+	if results.Iterations > 0 && agents > 0 {
+		for c, count := range results.Colors[results.Iterations-1] {
+			row.FinalShare[c] = float64(count) / float64(agents)
+		}
+	}
+}
+
+// with returns a copy of the Experiment with the named field set to the passed value. The
+// section holding the field is converted to a map using its json representation, the value
+// is set, and the map is decoded into a new copy of the section so that the name and the
+// type of the value are checked against the fields of the section.
+func (e *Experiment) with(name string, value interface{}) (*Experiment, error) {
+	m, parent, key, err := e.field(name)
+	if err != nil {
+		return nil, err
+	}
+	parent[key] = value
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	x := *e
+	var target interface{}
+	switch section, _, _ := strings.Cut(name, "."); section {
+	case HierarchySection:
+		x.Hierarchy = &sim.HierarchySpec{}
+		target = x.Hierarchy
+	case OptionsSection:
+		x.Options = &sim.NetworkOptions{}
+		target = x.Options
+	case RunSection:
+		x.Run = RunSettings{}
+		target = &x.Run
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	err = d.Decode(target)
+	if err != nil && strings.Contains(err.Error(), "unknown field") {
+		return nil, fmt.Errorf("unknown parameter '%s'", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %v for parameter '%s': %s", value, name, err.Error())
+	}
+	return &x, nil
+}
+
+// value returns the current value of the named field, or nil if the field is not set
+func (e *Experiment) value(name string) (interface{}, error) {
+	_, parent, key, err := e.field(name)
+	if err != nil {
+		return nil, err
+	}
+	return parent[key], nil
+}
+
+// field converts the section of the Experiment holding the named field to a map using its
+// json representation, and returns the map along with the map holding the field and the key
+// of the field within it
+func (e *Experiment) field(name string) (map[string]interface{}, map[string]interface{}, string, error) {
+	section, field, _ := strings.Cut(name, ".")
+	var source interface{}
+	switch section {
+	case HierarchySection:
+		source = e.Hierarchy
+	case OptionsSection:
+		source = e.Options
+	case RunSection:
+		source = e.Run
+	}
+	b, err := json.Marshal(source)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	m := map[string]interface{}{}
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	keys := strings.Split(field, ".")
+	parent := m
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := parent[key].(map[string]interface{})
+		if !ok {
+			return nil, nil, "", fmt.Errorf("unknown parameter '%s'", name)
+		}
+		parent = child
+	}
+	return m, parent, keys[len(keys)-1], nil
+}
+
+// values returns the list of values of the Parameter
+func (p Parameter) values() ([]interface{}, error) {
+	if len(p.Values) > 0 {
+		return p.Values, nil
+	}
+	if p.Step <= 0 || p.To < p.From {
+		return nil, fmt.Errorf("parameter '%s' needs a list of values or a range with a positive step", p.Name)
+	}
+	values := []interface{}{}
+	steps := int(math.Floor((p.To-p.From)/p.Step + 1e-9))
+	for i := 0; i <= steps; i++ {
+		values = append(values, p.From+float64(i)*p.Step)
+	}
+	return values, nil
+}
+
+// combinations returns every combination of the values of the Parameters, the values of
+// the last Parameter change fastest
+func (e *Experiment) combinations() []map[string]interface{} {
+	combos := []map[string]interface{}{{}}
+	for _, p := range e.Parameters {
+		values, _ := p.values()
+		next := make([]map[string]interface{}, 0, len(combos)*len(values))
+		for _, c := range combos {
+			for _, v := range values {
+				combo := make(map[string]interface{}, len(c)+1)
+				for k, cv := range c {
+					combo[k] = cv
+				}
+				combo[p.Name] = v
+				next = append(next, combo)
+			}
+		}
+		combos = next
+	}
+	return combos
+}
+
+// WriteCsv writes the Table in csv format with a row for each run, and a column for each
+// Parameter followed by the outcome metrics with a column for the final share of each Color
+func (t *Table) WriteCsv(w io.Writer) error {
+	colors := 0
+	for _, row := range t.Rows {
+		if len(row.FinalShare) > colors {
+			colors = len(row.FinalShare)
+		}
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString("Run,Repeat,Seed")
+	for _, p := range t.Parameters {
+		buffer.WriteString("," + p)
+	}
+	buffer.WriteString(",Agents,Iterations,Stopped")
+	for c := 0; c < colors; c++ {
+		buffer.WriteString(fmt.Sprintf(",%s Share", sim.Color(c).String()))
+	}
+	buffer.WriteString(",Half Adoption,Conversations,Broadcast Conversions,Error\n")
+	for _, row := range t.Rows {
+		buffer.WriteString(fmt.Sprintf("%d,%d,%d", row.Run, row.Repeat, row.Seed))
+		for _, p := range t.Parameters {
+			buffer.WriteString("," + csvValue(row.Params[p]))
+		}
+		buffer.WriteString(fmt.Sprintf(",%d,%d,%s", row.Agents, row.Iterations, row.Stopped))
+		for c := 0; c < colors; c++ {
+			share := 0.0
+			if c < len(row.FinalShare) {
+				share = row.FinalShare[c]
+			}
+			buffer.WriteString(fmt.Sprintf(",%g", share))
+		}
+		buffer.WriteString(fmt.Sprintf(",%d,%d,%d,%s\n", row.HalfAdoption, row.Conversations, row.BroadcastConversions, csvValue(row.Error)))
+	}
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+// csvValue formats a parameter value for a csv cell, quoting it if it contains a comma
+func csvValue(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case float64, bool, nil:
+		s = fmt.Sprintf("%v", v)
+	default:
+		b, _ := json.Marshal(v)
+		s = string(b)
+	}
+	if strings.ContainsAny(s, ",\"\n") {
+		s = "\"" + strings.ReplaceAll(s, "\"", "\"\"") + "\""
+	}
+	return s
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
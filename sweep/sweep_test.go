@@ -0,0 +1,247 @@
+package sweep
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/codeafix/orgnetsim/sim"
+)
+
+func IsTrue(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Error(msg)
+	}
+}
+
+func AreEqual(t *testing.T, expected interface{}, actual interface{}, msg string) {
+	if expected != actual {
+		t.Errorf("%s Expected = %v Actual = %v", msg, expected, actual)
+	}
+}
+
+func AssertSuccess(t *testing.T, err error) {
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func CreateExperiment(parameters ...Parameter) *Experiment {
+	return &Experiment{
+		Hierarchy: &sim.HierarchySpec{
+			Levels:        3,
+			TeamSize:      3,
+			TeamLinkLevel: 2,
+			LinkTeamPeers: true,
+			InitColors:    []sim.Color{sim.Grey, sim.Red},
+			MaxColors:     3,
+		},
+		Run:        RunSettings{Iterations: 20},
+		Parameters: parameters,
+		Seed:       9,
+	}
+}
+
+func TestRunProducesRowForEveryCombinationAndRepeat(t *testing.T) {
+	e := CreateExperiment(
+		Parameter{Name: "hierarchy.teamSize", Values: []interface{}{2.0, 3.0}},
+		Parameter{Name: "run.iterations", From: 10, To: 30, Step: 10},
+	)
+	e.Repeats = 2
+	e.Parallel = 2
+	calls := 0
+	table, err := Run(context.Background(), e, nil, func(completed, total int) {
+		calls++
+		AreEqual(t, 12, total, "Wrong total number of runs")
+	})
+	AssertSuccess(t, err)
+	AreEqual(t, 12, calls, "Progress not reported for every run")
+	AreEqual(t, 12, len(table.Rows), "Wrong number of rows")
+	AreEqual(t, "hierarchy.teamSize", table.Parameters[0], "Wrong parameter order")
+	for i, row := range table.Rows {
+		AreEqual(t, i, row.Run, "Wrong run number")
+		AreEqual(t, i%2, row.Repeat, "Wrong repeat")
+		AreEqual(t, table.Rows[i%2].Seed, row.Seed, "Repeats do not share seeds across combinations")
+		AreEqual(t, "", row.Error, "Unexpected error")
+		AreEqual(t, 3, len(row.FinalShare), "Wrong number of colors")
+	}
+	//The last parameter changes fastest
+	AreEqual(t, 2.0, table.Rows[0].Params["hierarchy.teamSize"], "Wrong first teamSize")
+	AreEqual(t, 20.0, table.Rows[2].Params["run.iterations"], "Wrong second iterations")
+	AreEqual(t, 3.0, table.Rows[6].Params["hierarchy.teamSize"], "Wrong second teamSize")
+	AreEqual(t, 7, table.Rows[0].Agents, "Wrong number of agents for teamSize 2")
+	AreEqual(t, 13, table.Rows[6].Agents, "Wrong number of agents for teamSize 3")
+	AreEqual(t, 10, table.Rows[0].Iterations, "Wrong number of iterations")
+	AreEqual(t, 30, table.Rows[11].Iterations, "Wrong number of iterations")
+	sum := 0.0
+	for _, share := range table.Rows[11].FinalShare {
+		sum += share
+	}
+	AreEqual(t, 1.0, sum, "Shares do not add up to one")
+}
+
+func TestRunComparesPartnerSelection(t *testing.T) {
+	e := CreateExperiment(Parameter{Name: "run.selection.mode", Values: []interface{}{"uniform", "strength"}})
+	e.Run.Selection.Baseline = 1
+	table, err := Run(context.Background(), e, nil, nil)
+	AssertSuccess(t, err)
+	AreEqual(t, 2, len(table.Rows), "Wrong number of rows")
+	AreEqual(t, "", table.Rows[0].Error, "Uniform selection failed")
+	AreEqual(t, "", table.Rows[1].Error, "Strength selection failed")
+
+	e = CreateExperiment(Parameter{Name: "run.selection.mode", Values: []interface{}{"loudest"}})
+	table, err = Run(context.Background(), e, nil, nil)
+	AssertSuccess(t, err)
+	AreEqual(t, "unrecognised selection mode 'loudest'", table.Rows[0].Error, "Invalid selection not recorded")
+}
+
+func TestRunComparesLinkDecay(t *testing.T) {
+	e := CreateExperiment(Parameter{Name: "run.dynamics.decay", Values: []interface{}{0.0, 0.1}})
+	e.Run.Dynamics.Threshold = 1
+	table, err := Run(context.Background(), e, nil, nil)
+	AssertSuccess(t, err)
+	AreEqual(t, 2, len(table.Rows), "Wrong number of rows")
+	AreEqual(t, "", table.Rows[0].Error, "Run without decay failed")
+	AreEqual(t, "", table.Rows[1].Error, "Run with decay failed")
+
+	e = CreateExperiment(Parameter{Name: "run.dynamics.closure", Values: []interface{}{1.5}})
+	table, err = Run(context.Background(), e, nil, nil)
+	AssertSuccess(t, err)
+	AreEqual(t, "link closure must be between 0 and 1", table.Rows[0].Error, "Invalid link dynamics not recorded")
+}
+
+func TestRunComparesTopDownAndGrassroots(t *testing.T) {
+	e := CreateExperiment(Parameter{Name: "run.hierarchy.downward", Values: []interface{}{1.0, 3.0}})
+	table, err := Run(context.Background(), e, nil, nil)
+	AssertSuccess(t, err)
+	AreEqual(t, 2, len(table.Rows), "Wrong number of rows")
+	AreEqual(t, "", table.Rows[0].Error, "Run without a downward multiplier failed")
+	AreEqual(t, "", table.Rows[1].Error, "Run with a downward multiplier failed")
+
+	e = CreateExperiment(Parameter{Name: "run.hierarchy.upward", Values: []interface{}{-1.0}})
+	table, err = Run(context.Background(), e, nil, nil)
+	AssertSuccess(t, err)
+	AreEqual(t, "hierarchy influence multipliers cannot be negative", table.Rows[0].Error, "Invalid hierarchy influence not recorded")
+}
+
+func TestRunAppliesInterventions(t *testing.T) {
+	e := CreateExperiment(Parameter{Name: "run.iterations", Values: []interface{}{20.0}})
+	e.Run.Interventions = sim.InterventionSchedule{{Iteration: 5, Type: sim.EvangelistIntervention, Agents: []string{"id_1"}}}
+	table, err := Run(context.Background(), e, nil, nil)
+	AssertSuccess(t, err)
+	AreEqual(t, "", table.Rows[0].Error, "Run with an intervention failed")
+
+	e.Run.Interventions = sim.InterventionSchedule{{Iteration: 5, Type: sim.ColorIntervention, Agents: []string{"nobody"}}}
+	table, err = Run(context.Background(), e, nil, nil)
+	AssertSuccess(t, err)
+	AreEqual(t, "unrecognised Agent Id 'nobody' in intervention", table.Rows[0].Error, "Invalid intervention not recorded")
+}
+
+func TestRunComparesBroadcastInfluence(t *testing.T) {
+	e := CreateExperiment(Parameter{Name: "run.iterations", Values: []interface{}{10.0}})
+	e.Run.Broadcasts = sim.Broadcasts{{Color: sim.Blue, Frequency: 5, Influence: 100}}
+	table, err := Run(context.Background(), e, nil, nil)
+	AssertSuccess(t, err)
+	AreEqual(t, "", table.Rows[0].Error, "Run with a broadcast failed")
+	IsTrue(t, table.Rows[0].BroadcastConversions > 0, "Broadcast conversions not measured")
+
+	e.Run.Broadcasts = sim.Broadcasts{{Color: sim.Blue, Frequency: 5, Influence: -1}}
+	table, err = Run(context.Background(), e, nil, nil)
+	AssertSuccess(t, err)
+	AreEqual(t, "broadcast influence cannot be negative", table.Rows[0].Error, "Invalid broadcast not recorded")
+}
+
+func TestRunIsReproducible(t *testing.T) {
+	p := Parameter{Name: "options.linkTeamPeers", Values: []interface{}{false, true}}
+	e1 := CreateExperiment(p)
+	e1.Options = sim.CreateNetworkOptions(*e1.Hierarchy)
+	e2 := CreateExperiment(p)
+	e2.Options = sim.CreateNetworkOptions(*e2.Hierarchy)
+	t1, err := Run(context.Background(), e1, nil, nil)
+	AssertSuccess(t, err)
+	t2, err := Run(context.Background(), e2, nil, nil)
+	AssertSuccess(t, err)
+	b1, _ := json.Marshal(t1)
+	b2, _ := json.Marshal(t2)
+	AreEqual(t, string(b1), string(b2), "Sweeps with the same seed differ")
+}
+
+func TestRunFromBaseNetwork(t *testing.T) {
+	n, _, err := sim.GenerateHierarchy(sim.HierarchySpec{Levels: 2, TeamSize: 4, InitColors: []sim.Color{sim.Grey, sim.Red}, MaxColors: 3, Seed: 4})
+	AssertSuccess(t, err)
+	e := &Experiment{
+		Run:        RunSettings{Iterations: 50, Stop: sim.StopConditions{ColorShare: 0.5}},
+		Parameters: []Parameter{{Name: "run.stop.colorShare", Values: []interface{}{0.5, 1.0}}},
+		Seed:       2,
+	}
+	before := n.Serialise()
+	table, err := Run(context.Background(), e, n, nil)
+	AssertSuccess(t, err)
+	AreEqual(t, before, n.Serialise(), "Base network changed by the sweep")
+	AreEqual(t, 2, len(table.Rows), "Wrong number of rows")
+	AreEqual(t, 5, table.Rows[0].Agents, "Wrong number of agents")
+	AreEqual(t, sim.ColorShareReached, table.Rows[0].Stopped, "Run not stopped at half adoption")
+	AreEqual(t, table.Rows[0].Iterations, table.Rows[0].HalfAdoption, "Half adoption not reached on the last iteration")
+}
+
+func TestRunRecordsRowErrors(t *testing.T) {
+	e := CreateExperiment(Parameter{Name: "hierarchy.teamLinkLevel", Values: []interface{}{2.0, 3.0}})
+	e.Hierarchy.LinkTeams = true
+	table, err := Run(context.Background(), e, nil, nil)
+	AssertSuccess(t, err)
+	AreEqual(t, "", table.Rows[0].Error, "Unexpected error")
+	AreEqual(t, "teamLinkLevel 3 must be less than levels 3", table.Rows[1].Error, "Error not recorded")
+}
+
+func TestRunStopsWhenCancelled(t *testing.T) {
+	e := CreateExperiment(Parameter{Name: "run.iterations", Values: []interface{}{5.0, 6.0, 7.0}})
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := Run(ctx, e, nil, func(completed, total int) {
+		cancel()
+	})
+	AreEqual(t, context.Canceled, err, "Expected the context error to be returned")
+}
+
+func TestValidateFails(t *testing.T) {
+	tests := []struct {
+		parameter Parameter
+		msg       string
+	}{
+		{Parameter{Name: "hierarchy.size", Values: []interface{}{1.0}}, "unknown parameter 'hierarchy.size'"},
+		{Parameter{Name: "run.stop.foo.bar", Values: []interface{}{1.0}}, "unknown parameter 'run.stop.foo.bar'"},
+		{Parameter{Name: "options.linkTeamPeers", Values: []interface{}{true}}, "parameter 'options.linkTeamPeers' cannot be varied without options"},
+		{Parameter{Name: "network.levels", Values: []interface{}{1.0}}, "parameter 'network.levels' must start with hierarchy, options or run"},
+		{Parameter{Name: "run.iterations", From: 10, To: 5, Step: 1}, "parameter 'run.iterations' needs a list of values or a range with a positive step"},
+		{Parameter{Name: "hierarchy.levels", Values: []interface{}{"three"}}, "invalid value three for parameter 'hierarchy.levels': json: cannot unmarshal string into Go struct field HierarchySpec.levels of type int"},
+	}
+	for _, test := range tests {
+		e := CreateExperiment(test.parameter)
+		err := e.Validate()
+		IsTrue(t, err != nil, "Expected an error for "+test.parameter.Name)
+		if err != nil {
+			AreEqual(t, test.msg, err.Error(), "Wrong error")
+		}
+	}
+	e := CreateExperiment()
+	e.Run.Iterations = 0
+	AreEqual(t, "run.iterations must be greater than zero", e.Validate().Error(), "Wrong error")
+}
+
+func TestWriteCsv(t *testing.T) {
+	table := &Table{
+		Parameters: []string{"hierarchy.teamSize", "hierarchy.initColors"},
+		Rows: []*Row{
+			{Run: 0, Repeat: 0, Seed: 5, Params: map[string]interface{}{"hierarchy.teamSize": 2.0, "hierarchy.initColors": []interface{}{0.0, 1.0}}, Agents: 4, Iterations: 3, FinalShare: []float64{0.25, 0.75}, HalfAdoption: 2, Conversations: 9, BroadcastConversions: 3},
+			{Run: 1, Repeat: 0, Seed: 5, Params: map[string]interface{}{"hierarchy.teamSize": 3.0, "hierarchy.initColors": []interface{}{0.0}}, Stopped: sim.NoChange, Agents: 4, Iterations: 1, FinalShare: []float64{1}, HalfAdoption: -1, Error: "a, b"},
+		},
+	}
+	var buffer bytes.Buffer
+	AssertSuccess(t, table.WriteCsv(&buffer))
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	AreEqual(t, 3, len(lines), "Wrong number of lines")
+	AreEqual(t, "Run,Repeat,Seed,hierarchy.teamSize,hierarchy.initColors,Agents,Iterations,Stopped,Grey Share,Blue Share,Half Adoption,Conversations,Broadcast Conversions,Error", lines[0], "Wrong header")
+	AreEqual(t, "0,0,5,2,\"[0,1]\",4,3,,0.25,0.75,2,9,3,", lines[1], "Wrong first row")
+	AreEqual(t, "1,0,5,3,[0],4,1,noChange,1,0,-1,0,0,\"a, b\"", lines[2], "Wrong second row")
+}
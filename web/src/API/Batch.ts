@@ -0,0 +1,35 @@
+type Summary = {
+    mean: number;
+    median: number;
+    min: number;
+    max: number;
+    percentiles: Array<number>;
+}
+
+type BatchResults = {
+    runs: number;
+    iterations: number;
+    seeds: Array<number>;
+    percentiles: Array<number>;
+    colors: Array<Array<Summary>>;
+    conversations: Array<Summary>;
+}
+
+type BatchSpec = {
+    runs: number;
+    iterations: number;
+    seed?: number;
+    parallel?: number;
+    percentiles?: Array<number>;
+    hierarchy?: object;
+    async?: boolean;
+}
+
+type Batch = {
+    id: string;
+    parent: string;
+    spec: BatchSpec;
+    results?: BatchResults;
+}
+
+export type { Batch, BatchSpec, BatchResults, Summary };
\ No newline at end of file
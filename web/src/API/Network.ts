@@ -9,6 +9,9 @@ type Link = {
     target: string;
     strength?: number;
     length?: number;
+    inactive?: boolean;
+    directed?: boolean;
+    trust?: number;
 }
 
 type AgentState = {
@@ -20,6 +23,15 @@ type AgentState = {
     contrariness: number;
     change: number;
     type: string;
+    ideas?: Array<number>;
+    exclusive?: Array<Array<number>>;
+    previousColors?: Record<string, number>;
+    shortMemory?: Record<string, number>;
+    longMemorySpan?: number;
+    shortMemorySpan?: number;
+    clock?: number;
+    manager?: string;
+    level?: number;
     fx?: number;
     fy?: number;
     x?: number;
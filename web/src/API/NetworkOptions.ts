@@ -1,3 +1,30 @@
+type TraitDistribution = {
+    type: string;
+    mean?: number;
+    sd?: number;
+    min?: number;
+    max?: number;
+    alpha?: number;
+    beta?: number;
+    value?: number;
+}
+
+type Traits = {
+    influence: TraitDistribution;
+    susceptability: TraitDistribution;
+    contrariness: TraitDistribution;
+    threshold?: TraitDistribution;
+    thresholdCount?: boolean;
+    opinion?: TraitDistribution;
+    confidence?: TraitDistribution;
+    convergence?: TraitDistribution;
+    opinionBands?: Array<number>;
+    opinionAveraging?: boolean;
+    exclusiveIdeas?: Array<Array<number>>;
+    longMemorySpan?: number;
+    shortMemorySpan?: number;
+}
+
 type NetworkOptions = {
     linkTeamPeers: boolean;
     linkedTeamList: Array<string>;
@@ -6,5 +33,7 @@ type NetworkOptions = {
     initColors: Array<number>;
     maxColors: number;
     agentsWithMemory: boolean;
+    traits?: Traits;
+    agentType?: string;
 }
-export type { NetworkOptions };
\ No newline at end of file
+export type { NetworkOptions, Traits, TraitDistribution };
\ No newline at end of file
@@ -4,6 +4,8 @@ type ParseOptions = {
     name: number;
     regex: Regex;
     delimiter: string;
+    directed?: boolean;
+    weight?: number;
     payload: string;
 }
 
@@ -1,7 +1,36 @@
+import { Intervention } from './Step';
+
+type Stopped = {
+    condition: string;
+    iteration: number;
+}
+
+type HierarchyInfluence = {
+    upward?: number;
+    downward?: number;
+    peer?: number;
+}
+
+type AppliedIntervention = {
+    iteration: number;
+    intervention: Intervention;
+}
+
 type Results = {
     iterations: number;
     colors: Array<Array<number>>;
     conversations: Array<number>;
+    opinions?: Array<Array<number>>;
+    ideas?: Array<Array<number>>;
+    links?: Array<number>;
+    conversions?: Array<number>;
+    broadcastConversions?: Array<number>;
+    seed?: number;
+    scheduler?: string;
+    selection?: string;
+    hierarchy?: HierarchyInfluence;
+    interventions?: Array<AppliedIntervention>;
+    stopped?: Stopped;
 }
 
 type ResultsCsv = {
@@ -9,4 +38,4 @@ type ResultsCsv = {
     blob: Blob;
 }
 
-export type { Results, ResultsCsv };
\ No newline at end of file
+export type { Results, ResultsCsv, Stopped };
\ No newline at end of file
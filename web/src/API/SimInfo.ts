@@ -5,6 +5,8 @@ type SimInfo = {
     name: string;
     description: string;
     steps: Array<string>;
+    batches?: Array<string>;
+    sweeps?: Array<string>;
     options: NetworkOptions;
 }
 
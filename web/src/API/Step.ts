@@ -1,14 +1,80 @@
 import { Results } from './Results';
 
+type ColorChange = {
+    iteration: number;
+    agent: string;
+    sender: string;
+    oldColor: number;
+    newColor: number;
+    reason: string;
+}
+
 type Step = {
     id: string;
     parent: string;
     results: Results;
+    seed?: number;
+    events?: ColorChange[];
+}
+
+type StopConditions = {
+    colorShare?: number;
+    shareColors?: number[];
+    stableIterations?: number;
+    entropyThreshold?: number;
+    entropyIterations?: number;
+    timeLimit?: number;
+}
+
+type PartnerSelection = {
+    mode?: string;
+    baseline?: number;
+}
+
+type LinkDynamics = {
+    decay?: number;
+    threshold?: number;
+    closure?: number;
+}
+
+type HierarchyInfluence = {
+    upward?: number;
+    downward?: number;
+    peer?: number;
+}
+
+type Intervention = {
+    iteration: number;
+    type: string;
+    agents?: string[];
+    color?: number;
+    susceptability?: number;
+    influence?: number;
+    contrariness?: number;
+    links?: Array<{ source: string; target: string; directed?: boolean; trust?: number }>;
+}
+
+type Broadcast = {
+    name?: string;
+    color: number;
+    reach?: { department?: string; agents?: string[] };
+    frequency: number;
+    influence: number;
 }
 
 type RunSpec = {
     steps: number;
     iterations: number;
+    seed?: number;
+    scheduler?: string;
+    async?: boolean;
+    events?: boolean;
+    selection?: PartnerSelection;
+    dynamics?: LinkDynamics;
+    hierarchy?: HierarchyInfluence;
+    interventions?: Intervention[];
+    broadcasts?: Broadcast[];
+    stop?: StopConditions;
 }
 
-export type { Step, RunSpec };
\ No newline at end of file
+export type { Step, RunSpec, ColorChange, StopConditions, PartnerSelection, LinkDynamics, HierarchyInfluence, Intervention, Broadcast };
\ No newline at end of file
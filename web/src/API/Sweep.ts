@@ -0,0 +1,56 @@
+type SweepParameter = {
+    name: string;
+    values?: Array<any>;
+    from?: number;
+    to?: number;
+    step?: number;
+}
+
+type SweepRow = {
+    run: number;
+    repeat: number;
+    seed: number;
+    params: { [name: string]: any };
+    agents: number;
+    iterations: number;
+    stopped?: string;
+    finalShare: Array<number>;
+    halfAdoption: number;
+    conversations: number;
+    broadcastConversions?: number;
+    error?: string;
+}
+
+type SweepResults = {
+    parameters: Array<string>;
+    rows: Array<SweepRow>;
+}
+
+type SweepSpec = {
+    hierarchy?: object;
+    options?: object;
+    run: {
+        iterations: number;
+        scheduler?: string;
+        selection?: object;
+        dynamics?: object;
+        hierarchy?: object;
+        interventions?: Array<object>;
+        broadcasts?: Array<object>;
+        stop?: object;
+    };
+    parameters: Array<SweepParameter>;
+    repeats?: number;
+    seed?: number;
+    parallel?: number;
+    async?: boolean;
+}
+
+type Sweep = {
+    id: string;
+    parent: string;
+    spec: SweepSpec;
+    results?: SweepResults;
+}
+
+export type { Sweep, SweepSpec, SweepParameter, SweepResults, SweepRow };